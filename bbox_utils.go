@@ -1,5 +1,10 @@
 package shp
 
+import (
+	"fmt"
+	"math"
+)
+
 // readBBox reads a bounding box from an errReader
 func readBBox(er *errReader) Box {
 	var bbox Box
@@ -9,3 +14,69 @@ func readBBox(er *errReader) Box {
 	readLE(er, &bbox.MaxY)
 	return bbox
 }
+
+// ToGeoJSON returns the box in the GeoJSON bbox form [minX, minY, maxX, maxY].
+func (b Box) ToGeoJSON() []float64 {
+	return []float64{b.MinX, b.MinY, b.MaxX, b.MaxY}
+}
+
+// ToWKT returns the box as a closed, counter-clockwise WKT POLYGON envelope.
+func (b Box) ToWKT() string {
+	return fmt.Sprintf("POLYGON ((%s))", formatPointsAsWKT([]Point{
+		{b.MinX, b.MinY},
+		{b.MaxX, b.MinY},
+		{b.MaxX, b.MaxY},
+		{b.MinX, b.MaxY},
+		{b.MinX, b.MinY},
+	}))
+}
+
+// BoxFromGeoJSON creates a Box from a GeoJSON bbox. Both the 2D form
+// [minX, minY, maxX, maxY] and the 3D form [minX, minY, minZ, maxX, maxY, maxZ]
+// are accepted; for any other length an empty Box is returned.
+func BoxFromGeoJSON(bbox []float64) Box {
+	switch len(bbox) {
+	case 4:
+		return Box{bbox[0], bbox[1], bbox[2], bbox[3]}
+	case 6:
+		return Box{bbox[0], bbox[1], bbox[3], bbox[4]}
+	default:
+		return Box{}
+	}
+}
+
+// Difference returns the parts of b that are not covered by other as up to
+// four non-overlapping boxes: a full-width strip below and above other and
+// the remaining pieces to its left and right. Regions without area are
+// omitted, so the result is empty if other covers b and contains b itself if
+// the two boxes do not overlap.
+func (b Box) Difference(other Box) []Box {
+	in := Box{
+		MinX: math.Max(b.MinX, other.MinX),
+		MinY: math.Max(b.MinY, other.MinY),
+		MaxX: math.Min(b.MaxX, other.MaxX),
+		MaxY: math.Min(b.MaxY, other.MaxY),
+	}
+	if in.MinX >= in.MaxX || in.MinY >= in.MaxY {
+		return []Box{b}
+	}
+
+	var boxes []Box
+	add := func(box Box) {
+		if box.MinX < box.MaxX && box.MinY < box.MaxY {
+			boxes = append(boxes, box)
+		}
+	}
+	add(Box{b.MinX, b.MinY, b.MaxX, in.MinY})
+	add(Box{b.MinX, in.MaxY, b.MaxX, b.MaxY})
+	add(Box{b.MinX, in.MinY, in.MinX, in.MaxY})
+	add(Box{in.MaxX, in.MinY, b.MaxX, in.MaxY})
+	return boxes
+}
+
+// AlmostEquals reports whether all four coordinates of b and other differ by
+// at most epsilon, e.g. to compare computed bounding boxes in tests.
+func (b Box) AlmostEquals(other Box, epsilon float64) bool {
+	return math.Abs(b.MinX-other.MinX) <= epsilon && math.Abs(b.MinY-other.MinY) <= epsilon &&
+		math.Abs(b.MaxX-other.MaxX) <= epsilon && math.Abs(b.MaxY-other.MaxY) <= epsilon
+}
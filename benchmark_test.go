@@ -165,6 +165,26 @@ func BenchmarkMemoryUsage(b *testing.B) {
 	}
 }
 
+// BenchmarkAnalyzeHighCardinality 测试唯一值很多的字段的统计性能，
+// 唯一值的查重基于 map，耗时应随记录数线性增长
+func BenchmarkAnalyzeHighCardinality(b *testing.B) {
+	filename := b.TempDir() + "/cardinality.shp"
+	if err := setupTestShapefile(filename, 20000); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stats, err := StatisticsUtils{}.AnalyzeShapefile(filename, WithMaxUniqueValues(0))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if stats.AttributeStats["ID"].UniqueValues != 20000 {
+			b.Fatalf("got %d unique IDs", stats.AttributeStats["ID"].UniqueValues)
+		}
+	}
+}
+
 // setupTestShapefile 创建测试用的 shapefile（通用函数）
 func setupTestShapefile(filename string, pointCount int) error {
 	writer, err := Create(filename, POINT)
@@ -0,0 +1,91 @@
+package shp
+
+import (
+	"fmt"
+	"math"
+)
+
+// Size and precision of the numeric field added by AddComputedField.
+const (
+	computedFieldSize      = 19
+	computedFieldPrecision = 6
+)
+
+// AddComputedField copies the shapefile input to output and appends a numeric
+// field fieldName to the attribute table, whose value for every record is
+// compute applied to the record's shape. The geometries and existing
+// attributes are copied unchanged, as are the .prj and .cpg files if present.
+func AddComputedField(input, output, fieldName string, compute func(Shape) float64) error {
+	if len(fieldName) == 0 || len(fieldName) > 10 {
+		return NewShapeError(ErrInvalidField,
+			fmt.Sprintf("invalid field name %q: must be 1 to 10 characters", fieldName), nil)
+	}
+
+	field := FloatField(fieldName, computedFieldSize, computedFieldPrecision)
+	return transformShapefile(input, output, []Field{field}, func(shape Shape) (Shape, []interface{}) {
+		return shape, []interface{}{compute(shape)}
+	})
+}
+
+// AddAreaField is AddComputedField with the area of every shape. Holes are
+// subtracted from the area of polygons; other shapes have an area of 0.
+func AddAreaField(input, output, fieldName string) error {
+	return AddComputedField(input, output, fieldName, ShapeArea)
+}
+
+// AddLengthField is AddComputedField with the length of every shape: the
+// length of all parts of lines and the perimeter, including holes, of
+// polygons. Points have a length of 0.
+func AddLengthField(input, output, fieldName string) error {
+	return AddComputedField(input, output, fieldName, ShapeLength)
+}
+
+// ShapeArea returns the area of a polygon shape with its holes subtracted, and
+// 0 for all other shapes.
+func ShapeArea(shape Shape) float64 {
+	rings, polygon := shapeParts(shape)
+	if !polygon {
+		return 0
+	}
+	// exterior rings and holes have opposite orientations, so their signed
+	// areas cancel out
+	area := 0.0
+	for _, ring := range rings {
+		area += signedArea(ring)
+	}
+	return math.Abs(area)
+}
+
+// ShapeLength returns the total length of all parts of a line or polygon
+// shape, and 0 for all other shapes.
+func ShapeLength(shape Shape) float64 {
+	parts, _ := shapeParts(shape)
+	length := 0.0
+	for _, part := range parts {
+		for i := 1; i < len(part); i++ {
+			length += GeometryUtils{}.Distance(part[i-1], part[i])
+		}
+	}
+	return length
+}
+
+// shapeParts returns the parts of a line or polygon shape and whether it is a
+// polygon.
+func shapeParts(shape Shape) ([][]Point, bool) {
+	switch s := shape.(type) {
+	case *PolyLine:
+		return splitParts(s.Parts, s.Points), false
+	case *PolyLineZ:
+		return splitParts(s.Parts, s.Points), false
+	case *PolyLineM:
+		return splitParts(s.Parts, s.Points), false
+	case *Polygon:
+		return splitParts(s.Parts, s.Points), true
+	case *PolygonZ:
+		return splitParts(s.Parts, s.Points), true
+	case *PolygonM:
+		return splitParts(s.Parts, s.Points), true
+	default:
+		return nil, false
+	}
+}
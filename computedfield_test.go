@@ -0,0 +1,64 @@
+package shp
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAddComputedField(t *testing.T) {
+	dir := t.TempDir()
+	input := dir + "/in.shp"
+	w, err := Create(input, POLYGON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 5)}); err != nil {
+		t.Fatal(err)
+	}
+	square := []Point{{0, 0}, {0, 4}, {4, 4}, {4, 0}, {0, 0}}
+	hole := []Point{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}}
+	w.Write(NewPolygon([][]Point{square, hole}))
+	_ = w.WriteAttribute(0, 0, "a")
+	w.Write(NewPolygon([][]Point{square}))
+	_ = w.WriteAttribute(1, 0, "b")
+	w.Close()
+	if err := os.WriteFile(dir+"/in.prj", []byte("GEOGCS[]"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	output := dir + "/out.shp"
+	if err := AddAreaField(input, output, "AREA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddLengthField(output, dir+"/out2.shp", "area"); err == nil {
+		t.Error("adding a field with an existing name succeeded")
+	}
+	if err := AddLengthField(output, dir+"/out2.shp", "LENGTH"); err != nil {
+		t.Fatal(err)
+	}
+	if prj, _ := os.ReadFile(dir + "/out2.prj"); string(prj) != "GEOGCS[]" {
+		t.Errorf("got .prj %q", prj)
+	}
+
+	r, err := Open(dir + "/out2.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if fields := r.Fields(); len(fields) != 3 || fields[1].String() != "AREA" || fields[2].String() != "LENGTH" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+	want := [][]interface{}{{"a", 15.0, 20.0}, {"b", 16.0, 16.0}}
+	for r.Next() {
+		n, _ := r.Shape()
+		for i, v := range want[n] {
+			var got interface{} = r.ReadAttribute(n, i)
+			if i > 0 {
+				got = r.ReadAttributeTyped(n, i)
+			}
+			if got != v {
+				t.Errorf("record %d field %d: got %#v, want %#v", n, i, got, v)
+			}
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package shp
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -54,6 +56,116 @@ func ConvertShapefileToGeoJSONStream(shapefilePath, geojsonPath string, skipCorr
 	return converter.ShapefileToGeoJSONStream(shapefilePath, f)
 }
 
+// ConvertShapefileToGeoJSONWithReport 以流式方式将 Shapefile 转为 GeoJSON（紧凑格式），
+// 转换时使用 DefaultValidator 校验每个 shape。未通过校验的记录不会写出，
+// 而是与原因一起记录在返回的问题列表中。
+func ConvertShapefileToGeoJSONWithReport(shapefilePath, geojsonPath string, opts ...ReaderOption) ([]ValidationIssue, error) {
+	converter := GeoJSONConverter{}
+	f, err := os.Create(geojsonPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return converter.ShapefileToGeoJSONStreamWithReport(shapefilePath, f, nil, opts...)
+}
+
+// ConvertShapefileToGeoJSONL 将 Shapefile 转换为 GeoJSONL 文件（每行一个 Feature）.
+func ConvertShapefileToGeoJSONL(shapefilePath, outputPath string) error {
+	return ConvertShapefileToGeoJSONLFrom(shapefilePath, outputPath, 0)
+}
+
+// ConvertShapefileToGeoJSONLFrom 从第 startIndex 条记录起将 Shapefile 转换为 GeoJSONL，
+// 用于续传被中断的转换：startIndex 为 0 时新建（覆盖）输出文件，否则追加到输出文件末尾，
+// 并先截掉中断时可能残留的不完整的最后一行。
+// 注意无法转换的记录会被跳过，因此只有所有记录都能转换时已写行数才等于下一条记录的索引.
+func ConvertShapefileToGeoJSONLFrom(shapefilePath, outputPath string, startIndex int) error {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if startIndex > 0 {
+		flag = os.O_RDWR | os.O_CREATE
+	}
+	f, err := os.OpenFile(outputPath, flag, 0o666)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if startIndex > 0 {
+		if err := truncatePartialLine(f); err != nil {
+			return err
+		}
+	}
+	if err := (GeoJSONConverter{}).ShapefileToGeoJSONL(shapefilePath, f, startIndex); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// AppendShapefileToGeoJSONL 将 Shapefile 追加到 GeoJSONL 文件，以 keyField 字段的值作为 Feature id，
+// 跳过 id 已存在于输出文件中的 Feature，使重复导入同一数据不会产生重复的 Feature。
+// 输出文件不存在时会被创建，中断时残留的不完整最后一行会先被截掉。返回追加的 Feature 数.
+func AppendShapefileToGeoJSONL(shapefilePath, outputPath, keyField string, opts ...ReaderOption) (int, error) {
+	if keyField == "" {
+		return 0, NewShapeError(ErrInvalidField, "key field must not be empty", nil)
+	}
+	f, err := os.OpenFile(outputPath, os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	if err := truncatePartialLine(f); err != nil {
+		return 0, err
+	}
+	end, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	converter := GeoJSONConverter{}
+	seen, err := converter.GeoJSONLIDs(io.NewSectionReader(f, 0, end))
+	if err != nil {
+		return 0, err
+	}
+
+	opts = append(opts[:len(opts):len(opts)], WithIDField(keyField))
+	written, err := converter.ShapefileToGeoJSONLUnique(shapefilePath, f, seen, opts...)
+	if err != nil {
+		return written, err
+	}
+	return written, f.Close()
+}
+
+// truncatePartialLine 截掉文件末尾不以换行结束的部分，并将写入位置移到文件末尾
+func truncatePartialLine(f *os.File) error {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 4096)
+	end := size
+	for end > 0 {
+		n := int64(len(buf))
+		if n > end {
+			n = end
+		}
+		if _, err := f.ReadAt(buf[:n], end-n); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = end - n + int64(i) + 1
+			break
+		}
+		end -= n
+	}
+	if end == size {
+		return nil
+	}
+	if err := f.Truncate(end); err != nil {
+		return err
+	}
+	_, err = f.Seek(end, io.SeekStart)
+	return err
+}
+
 // ConvertShapefileToGeoJSONString 将 Shapefile 转换为 GeoJSON 字符串.
 func ConvertShapefileToGeoJSONString(shapefilePath string) (string, error) {
 	converter := GeoJSONConverter{}
@@ -0,0 +1,269 @@
+package shp
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCSVStringLength 字符型字段的最大长度，更长的值被截断
+const maxCSVStringLength = 254
+
+// CSVToShapefile 将带有坐标列的 CSV 文件转换为 POINT 类型的 Shapefile。
+// CSV 第一行为列名，xCol、yCol 为 X（经度）与 Y（纬度）所在的列，按列名匹配且不区分大小写；
+// 其余各列按顺序成为 DBF 字段，字段名超过 10 字节时被截断。
+//
+// 文件被读取两遍而不整体载入内存：第一遍推断字段类型与长度，第二遍写出记录。
+// 所有非空值均为整数的列为数值字段（N），均为有限小数的列为带小数位的浮点字段（F），
+// 其他列为字符型字段（C），超过 254 字节的值被截断。空值写为空属性，
+// 坐标均为空的行写为 Null 记录；坐标无法解析时返回错误。writer 选项传给 Create
+func CSVToShapefile(csvPath, output string, xCol, yCol string, opts ...WriterOption) error {
+	columns, xIndex, yIndex, err := inferCSVColumns(csvPath, xCol, yCol)
+	if err != nil {
+		return err
+	}
+
+	fields := make([]Field, 0, len(columns))
+	names := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		field := col.field()
+		name := strings.ToUpper(field.String())
+		if names[name] {
+			return NewShapeError(ErrInvalidField,
+				fmt.Sprintf("column %q gives the duplicate field name %q", col.name, field.String()), nil)
+		}
+		names[name] = true
+		fields = append(fields, field)
+	}
+
+	f, err := os.Open(csvPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	r := newCSVReader(f)
+	if _, err := r.Read(); err != nil {
+		return err
+	}
+
+	w, err := Create(output, POINT, opts...)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := w.SetFields(fields); err != nil {
+		return err
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := r.FieldPos(xIndex)
+		shape, err := csvPoint(record[xIndex], record[yIndex])
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		row := int(w.Write(shape))
+		for i, col := range columns {
+			value, ok := col.value(record[col.index])
+			if !ok {
+				continue
+			}
+			if err := w.WriteAttribute(row, i, value); err != nil {
+				return fmt.Errorf("line %d: column %q: %v", line, col.name, err)
+			}
+		}
+	}
+}
+
+// csvColumn 记录 CSV 属性列的名称、位置及第一遍扫描推断出的类型信息
+type csvColumn struct {
+	name     string
+	index    int
+	nonEmpty bool
+	isInt    bool
+	isFloat  bool
+	maxLen   int // 字符串的最大字节数
+	intLen   int // 数值整数部分（含符号）的最大长度
+	decimals int // 数值的最大小数位数
+	// fieldType 由 field 确定的 DBF 字段类型
+	fieldType byte
+}
+
+// inferCSVColumns 读取整个 CSV 文件，返回除坐标列外各列的类型信息及坐标列的位置
+func inferCSVColumns(csvPath, xCol, yCol string) ([]*csvColumn, int, int, error) {
+	f, err := os.Open(csvPath)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer func() { _ = f.Close() }()
+	r := newCSVReader(f)
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, 0, 0, NewShapeError(ErrInvalidFormat, "CSV file has no header", nil)
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	// 去除 UTF-8 BOM
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	xIndex, yIndex := -1, -1
+	var columns []*csvColumn
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		switch {
+		case xIndex < 0 && strings.EqualFold(name, xCol):
+			xIndex = i
+		case yIndex < 0 && strings.EqualFold(name, yCol):
+			yIndex = i
+		default:
+			columns = append(columns, &csvColumn{name: name, index: i, isInt: true, isFloat: true})
+		}
+	}
+	for _, c := range []struct {
+		name  string
+		index int
+	}{{xCol, xIndex}, {yCol, yIndex}} {
+		if c.index < 0 {
+			return nil, 0, 0, NewShapeError(ErrInvalidField, fmt.Sprintf("CSV has no column %q", c.name), nil)
+		}
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return columns, xIndex, yIndex, nil
+		}
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		for _, col := range columns {
+			col.observe(record[col.index])
+		}
+	}
+}
+
+// newCSVReader 返回逐条读取 r 的 CSV 读取器，每条记录的列数必须与首行相同
+func newCSVReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	return cr
+}
+
+// observe 根据列中的一个值更新类型推断
+func (c *csvColumn) observe(value string) {
+	if len(value) > c.maxLen {
+		c.maxLen = len(value)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	c.nonEmpty = true
+	if c.isInt {
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			c.observeNumber(strconv.FormatInt(v, 10))
+			return
+		}
+		c.isInt = false
+	}
+	if c.isFloat {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			c.isFloat = false
+			return
+		}
+		c.observeNumber(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+}
+
+// observeNumber 根据数值的十进制表示更新整数部分长度与小数位数
+func (c *csvColumn) observeNumber(s string) {
+	intPart, fraction := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		intPart, fraction = s[:dot], s[dot+1:]
+	}
+	if len(intPart) > c.intLen {
+		c.intLen = len(intPart)
+	}
+	if len(fraction) > c.decimals {
+		c.decimals = len(fraction)
+	}
+}
+
+// field 返回按推断结果创建的 DBF 字段。数值字段放不下最大值时退回为字符型字段
+func (c *csvColumn) field() Field {
+	name := c.name
+	if len(name) > 10 {
+		name = name[:10] // DBF 字段名最长 10 字节
+	}
+	var field Field
+	switch {
+	case c.nonEmpty && c.isInt && c.intLen <= 20:
+		field = NumberField(name, uint8(c.intLen))
+	case c.nonEmpty && c.isFloat && c.intLen+1+c.decimals <= 20:
+		field = FloatField(name, uint8(c.intLen+1+c.decimals), uint8(c.decimals))
+	default:
+		length := c.maxLen
+		if length < 1 {
+			length = 1
+		}
+		if length > maxCSVStringLength {
+			length = maxCSVStringLength
+		}
+		field = StringField(name, uint8(length))
+	}
+	c.fieldType = field.Fieldtype
+	return field
+}
+
+// value 将 CSV 中的值转换为写入 field 所返回字段的值，空值返回 false
+func (c *csvColumn) value(s string) (interface{}, bool) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return nil, false
+	}
+	switch c.fieldType {
+	case 'N':
+		v, _ := strconv.ParseInt(trimmed, 10, 64)
+		return int(v), true
+	case 'F':
+		v, _ := strconv.ParseFloat(trimmed, 64)
+		return v, true
+	}
+	for len(s) > maxCSVStringLength {
+		// 按字符边界截断
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return s, true
+}
+
+// csvPoint 解析坐标列的值，两者均为空时返回 Null
+func csvPoint(xValue, yValue string) (Shape, error) {
+	xValue, yValue = strings.TrimSpace(xValue), strings.TrimSpace(yValue)
+	if xValue == "" && yValue == "" {
+		return &Null{}, nil
+	}
+	x, err := strconv.ParseFloat(xValue, 64)
+	if err != nil || math.IsInf(x, 0) || math.IsNaN(x) {
+		return nil, fmt.Errorf("invalid X value %q", xValue)
+	}
+	y, err := strconv.ParseFloat(yValue, 64)
+	if err != nil || math.IsInf(y, 0) || math.IsNaN(y) {
+		return nil, fmt.Errorf("invalid Y value %q", yValue)
+	}
+	return &Point{X: x, Y: y}, nil
+}
@@ -0,0 +1,86 @@
+package shp
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCSVToShapefile(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := dir + "/cities.csv"
+	long := strings.Repeat("x", 300)
+	content := "\ufeffname,lat,Lon,population,elevation,code,\"description text\"\n" +
+		"Berlin,52.52,13.405,3645000,34.5,007,\"capital, largest city\"\n" +
+		"Unknown,,,,,,\n" +
+		"Potsdam,52.39,13.06,-182000,32,A1," + long + "\n"
+	if err := os.WriteFile(csvPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := dir + "/cities.shp"
+	if err := CSVToShapefile(csvPath, output, "lon", "LAT"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.GeometryType != POINT {
+		t.Errorf("got shape type %s, want POINT", r.GeometryType)
+	}
+	wantFields := []struct {
+		name      string
+		fieldType byte
+		size      uint8
+		precision uint8
+	}{
+		{"name", 'C', 7, 0},
+		{"population", 'N', 7, 0},
+		{"elevation", 'F', 4, 1},
+		{"code", 'C', 3, 0},
+		{"descriptio", 'C', 254, 0},
+	}
+	fields := r.Fields()
+	if len(fields) != len(wantFields) {
+		t.Fatalf("got %d fields, want %d", len(fields), len(wantFields))
+	}
+	for i, want := range wantFields {
+		f := fields[i]
+		if f.String() != want.name || f.Fieldtype != want.fieldType || f.Size != want.size || f.Precision != want.precision {
+			t.Errorf("field %d: got %s %c(%d,%d), want %s %c(%d,%d)", i,
+				f.String(), f.Fieldtype, f.Size, f.Precision, want.name, want.fieldType, want.size, want.precision)
+		}
+	}
+
+	var got []string
+	for r.Next() {
+		n, shape := r.Shape()
+		row := []string{FormatUtils{}.ToWKT(shape)}
+		for i := range fields {
+			row = append(row, strings.TrimSpace(r.ReadAttribute(n, i)))
+		}
+		got = append(got, strings.Join(row, "|"))
+	}
+	want := []string{
+		"POINT (13.405000 52.520000)|Berlin|3645000|34.5|007|capital, largest city",
+		"GEOMETRYCOLLECTION EMPTY|Unknown||||",
+		"POINT (13.060000 52.390000)|Potsdam|-182000|32.0|A1|" + long[:254],
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got records\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if err := CSVToShapefile(csvPath, output, "x", "lat"); err == nil {
+		t.Error("converted without the X column")
+	}
+	bad := dir + "/bad.csv"
+	if err := os.WriteFile(bad, []byte("lat,lon\n1,2\nnorth,3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CSVToShapefile(bad, dir+"/bad.shp", "lon", "lat"); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("got error %v for an invalid coordinate on line 3", err)
+	}
+}
@@ -0,0 +1,171 @@
+package shp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Dataset is a set of shapefiles in one directory that are read together,
+// e.g. the layers of a map. Every layer is named after the base name of its
+// shapefile without the extension.
+type Dataset struct {
+	layers map[string]*Reader
+	paths  map[string]string
+	names  []string
+	// spatial indexes loaded by Query, nil for layers without an index
+	indexes map[string]*SpatialIndex
+}
+
+// OpenDir opens every shapefile in the directory dir, which is not searched
+// recursively. The options are applied to every Reader. If any of the
+// shapefiles cannot be opened, the ones opened so far are closed and the
+// error is returned.
+func OpenDir(dir string, opts ...ReaderOption) (*Dataset, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, NewShapeError(ErrIO, "failed to read directory", err)
+	}
+
+	d := &Dataset{
+		layers:  make(map[string]*Reader),
+		paths:   make(map[string]string),
+		indexes: make(map[string]*SpatialIndex),
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !strings.EqualFold(ext, ".shp") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		r, err := Open(path, opts...)
+		if err != nil {
+			_ = d.Close()
+			return nil, err
+		}
+		name := strings.TrimSuffix(entry.Name(), ext)
+		d.layers[name] = r
+		d.paths[name] = path
+		d.names = append(d.names, name)
+	}
+	sort.Strings(d.names)
+	return d, nil
+}
+
+// Names returns the names of all layers in alphabetical order.
+func (d *Dataset) Names() []string {
+	return d.names
+}
+
+// Layer returns the Reader of the layer with the given name and whether it
+// exists.
+func (d *Dataset) Layer(name string) (*Reader, bool) {
+	r, ok := d.layers[name]
+	return r, ok
+}
+
+// Query returns the features of all layers, in the order of Names, whose
+// bounding box intersects bbox and for which where returns true. where is
+// called with a function that returns the value of the named attribute of the
+// record, or an empty string if there is no such field; a nil where accepts
+// every record. The features are built like the features of the GeoJSON
+// conversion, with the options the layers were opened with; text attributes
+// in an encoding that cannot be decoded are an error.
+//
+// Layers with an up-to-date spatial index written by WriteIndex only read
+// the records the index returns for bbox; other layers are scanned.
+func (d *Dataset) Query(bbox Box, where func(attr func(name string) string) bool) ([]*Feature, error) {
+	var features []*Feature
+	for _, name := range d.names {
+		found, err := d.QueryLayer(name, bbox, where)
+		if err != nil {
+			return nil, err
+		}
+		features = append(features, found...)
+	}
+	return features, nil
+}
+
+// QueryLayer is Query for the single layer name.
+func (d *Dataset) QueryLayer(name string, bbox Box, where func(attr func(name string) string) bool) ([]*Feature, error) {
+	r, ok := d.layers[name]
+	if !ok {
+		return nil, NewShapeError(ErrInvalidFormat, fmt.Sprintf("unknown layer %q", name), nil)
+	}
+
+	var rows []int
+	if idx := d.spatialIndex(name); idx != nil {
+		rows = idx.Query(bbox)
+	} else {
+		if err := r.loadOffsets(); err != nil {
+			return nil, err
+		}
+		rows = make([]int, len(r.offsets))
+		for i := range rows {
+			rows[i] = i
+		}
+	}
+
+	converter := GeoJSONConverter{}
+	fields := r.Fields()
+	var features []*Feature
+	for _, row := range rows {
+		shape, err := r.ReadShapeAt(row)
+		if err != nil {
+			return nil, err
+		}
+		if _, null := shape.(*Null); null || !shape.BBox().Intersects(bbox) {
+			continue
+		}
+		if where != nil {
+			var decodeErr error
+			keep := where(func(field string) string {
+				value, err := r.ReadAttributeByName(row, field)
+				if err != nil {
+					return ""
+				}
+				value, err = decodeDbfString(value, r.Encoding())
+				if err != nil && decodeErr == nil {
+					decodeErr = &attributeError{field: field, err: err}
+				}
+				return value
+			})
+			if decodeErr != nil {
+				return nil, decodeErr
+			}
+			if !keep {
+				continue
+			}
+		}
+		parts, err := converter.readFeatures(r, row, shape, fields)
+		if err != nil {
+			return nil, err
+		}
+		features = append(features, parts...)
+	}
+	return features, nil
+}
+
+// spatialIndex returns the spatial index of the layer name, or nil if it has
+// none.
+func (d *Dataset) spatialIndex(name string) *SpatialIndex {
+	idx, loaded := d.indexes[name]
+	if !loaded {
+		idx, _ = loadSpatialIndex(d.paths[name])
+		d.indexes[name] = idx
+	}
+	return idx
+}
+
+// Close closes all layers and returns the first error encountered.
+func (d *Dataset) Close() error {
+	var first error
+	for _, r := range d.layers {
+		if err := r.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
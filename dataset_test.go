@@ -0,0 +1,102 @@
+package shp
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestOpenDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, layer := range []struct {
+		name string
+		t    ShapeType
+	}{{"roads", POLYLINE}, {"cities", POINT}} {
+		w, err := Create(dir+"/"+layer.name+".shp", layer.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+	}
+	if err := os.WriteFile(dir+"/README.txt", []byte("not a layer"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := OpenDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if names := d.Names(); !reflect.DeepEqual(names, []string{"cities", "roads"}) {
+		t.Errorf("got layers %v", names)
+	}
+	if r, ok := d.Layer("roads"); !ok || r.GeometryType != POLYLINE {
+		t.Errorf("layer roads not opened correctly")
+	}
+	if _, ok := d.Layer("README"); ok {
+		t.Errorf("non-shapefile opened as layer")
+	}
+
+	if _, err := OpenDir(dir + "/missing"); err == nil {
+		t.Error("expected error for missing directory")
+	}
+}
+
+func TestDatasetQuery(t *testing.T) {
+	dir := t.TempDir()
+	for _, layer := range []string{"cities", "towns"} {
+		w, err := Create(dir+"/"+layer+".shp", POINT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.SetFields([]Field{StringField("NAME", 10), NumberField("POP", 8)}); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 4; i++ {
+			w.Write(&Point{float64(i), float64(i)})
+			if err := w.WriteAttribute(i, 0, layer[:1]+string(rune('a'+i))); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteAttribute(i, 1, i*1000); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.Close()
+	}
+	// only one of the layers has a spatial index
+	if err := WriteIndex(dir + "/towns.shp"); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := OpenDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	features, err := d.Query(Box{MinX: 0.5, MinY: 0.5, MaxX: 3, MaxY: 3}, func(attr func(string) string) bool {
+		return attr("POP") != "3000"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []interface{}
+	for _, f := range features {
+		names = append(names, f.Properties["NAME"])
+	}
+	if want := []interface{}{"cb", "cc", "tb", "tc"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got features %v, want %v", names, want)
+	}
+	if pop := features[0].Properties["POP"]; pop != int64(1000) {
+		t.Errorf("got POP %#v, want 1000", pop)
+	}
+
+	all, err := d.QueryLayer("towns", Box{MinX: -1, MinY: -1, MaxX: 10, MaxY: 10}, nil)
+	if err != nil || len(all) != 4 {
+		t.Errorf("got %d features, %v; want 4", len(all), err)
+	}
+	if _, err := d.QueryLayer("villages", Box{}, nil); err == nil {
+		t.Error("expected error for unknown layer")
+	}
+}
@@ -2,8 +2,13 @@ package shp
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // DBF format constants
@@ -11,16 +16,115 @@ const (
 	dbfOffsetNumRecords   = 4  // offset of number of records from file start
 	dbfOffsetHeaderLen    = 8  // offset of header length field
 	dbfOffsetRecordLen    = 10 // offset of record length field
+	dbfOffsetPadding      = 12 // offset of the padding after the record length
 	dbfHeaderPaddingLen   = 20 // bytes of padding after header/record length
+	dbfOffsetWorkAreaID   = 20 // offset of the dBASE work area ID
+	dbfOffsetCodePage     = 29 // offset of the language driver ID (code page mark)
 	dbfFieldDescriptorLen = 32 // length of each field descriptor
 	dbfHeaderFieldsBase   = 33 // header length includes 33 bytes after fields
 	dbfRowDeletionFlagSz  = 1  // deletion flag size per row
 
+	dbfFieldFlagsOffset   = 0    // offset of the field flags byte within Field.Padding
+	dbfFieldFlagSystem    = 0x01 // column is a hidden system column
+	dbfFieldTypeNullFlag  = '0'  // type of the _NullFlags bookkeeping column
+	dbfFieldTypeInteger   = 'I'  // binary little-endian int32 column
+	dbfFieldTypeDouble    = 'O'  // binary little-endian float64 column
+	dbfFieldTypeTimestamp = '@'  // binary Julian day and milliseconds column
+	dbfFieldTypeDateTime  = 'T'  // binary Julian day and milliseconds column
+
 	dbfDeletionFlagNotDeleted = 0x20
 	dbfDeletionFlagDeleted    = 0x2a
 	dbfFieldTerminator        = 0x0d
+	dbfEndOfFile              = 0x1a // marker after the last record
 )
 
+// dbfCodePageNames maps language driver IDs to the code page names written to
+// the .cpg file.
+var dbfCodePageNames = map[byte]string{
+	0x01: "437",
+	0x02: "850",
+	0x03: "1252",
+	0x4d: "936",
+	0x4e: "949",
+	0x4f: "950",
+	0x57: "1252",
+	0x64: "852",
+	0x65: "866",
+	0x7a: "936",
+}
+
+// dbfCodePageFromEncoding returns the language driver ID for a string encoding
+// supported by the Writer, or 0 if there is none. There is no ID for UTF-8,
+// which is declared by the .cpg file alone.
+func dbfCodePageFromEncoding(encoding string) byte {
+	switch strings.ToUpper(strings.ReplaceAll(encoding, "_", "-")) {
+	case "ISO-8859-1", "LATIN1", "LATIN-1", "88591":
+		return 0x57
+	default:
+		return 0
+	}
+}
+
+// cp1252High maps the bytes 0x80-0x9f of Windows-1252 to runes; the other
+// bytes are identical to ISO-8859-1. Undefined bytes map to U+FFFD.
+var cp1252High = [32]rune{
+	'€', '\ufffd', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\ufffd', 'Ž', '\ufffd',
+	'\ufffd', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\ufffd', 'ž', 'Ÿ',
+}
+
+// decodeDbfString converts s, stored in a DBF with the given encoding as
+// declared by the .cpg file or the language driver ID, to UTF-8. UTF-8,
+// ISO-8859-1 and Windows-1252 are supported. Without an encoding, s is kept
+// if it is valid UTF-8 and decoded as Windows-1252 otherwise. Non-ASCII text
+// in any other encoding, such as GBK (936) or Big5 (950), cannot be decoded
+// and an ErrUnsupportedType error is returned rather than mangling it; use
+// WithAttributeEncoding to override a wrong declaration, or ReadAttribute to
+// get the raw bytes.
+func decodeDbfString(s string, encoding string) (string, error) {
+	isASCII := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			isASCII = false
+			break
+		}
+	}
+	if isASCII {
+		return s, nil
+	}
+
+	switch strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(encoding), "_", "-")) {
+	case "":
+		if utf8.ValidString(s) {
+			return s, nil
+		}
+		return decodeSingleByte(s, true), nil
+	case "UTF-8", "UTF8", "65001":
+		return strings.ToValidUTF8(s, "\ufffd"), nil
+	case "ISO-8859-1", "LATIN1", "LATIN-1", "88591":
+		return decodeSingleByte(s, false), nil
+	case "1252", "CP1252", "WINDOWS-1252", "ANSI 1252":
+		return decodeSingleByte(s, true), nil
+	default:
+		return "", NewShapeError(ErrUnsupportedType, fmt.Sprintf("unsupported DBF encoding %q", encoding), nil)
+	}
+}
+
+// decodeSingleByte decodes s as ISO-8859-1, or as Windows-1252 if cp1252 is
+// set.
+func decodeSingleByte(s string, cp1252 bool) string {
+	var b strings.Builder
+	b.Grow(len(s) * 2)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if cp1252 && c >= 0x80 && c < 0xa0 {
+			b.WriteRune(cp1252High[c-0x80])
+			continue
+		}
+		b.WriteRune(rune(c))
+	}
+	return b.String()
+}
+
 // calcNumFields calculates number of DBF fields from header length.
 func calcNumFields(headerLength int16) int {
 	return int(math.Floor(float64(headerLength-int16(dbfHeaderFieldsBase)) / float64(dbfFieldDescriptorLen)))
@@ -45,8 +149,204 @@ func dbfFieldStartByte(fields []Field, n int) int {
 	return start
 }
 
+// blankDbfRecord fills record, a DBF record of fields, with the values of a
+// record without any values: a blank deletion flag and blank text values, and
+// zero bytes for binary fields, where spaces would be a valid value.
+func blankDbfRecord(record []byte, fields []Field) {
+	for i := range record {
+		record[i] = ' '
+	}
+	start := dbfRowDeletionFlagSz
+	for _, f := range fields {
+		end := start + int(f.Size)
+		if isBinaryFieldType(f.Fieldtype) && end <= len(record) {
+			for i := start; i < end; i++ {
+				record[i] = 0
+			}
+		}
+		start = end
+	}
+}
+
 // dbfFieldOffset returns the absolute file offset for (row, n) in a DBF file.
 func dbfFieldOffset(headerLength, recordLength int16, row int, fields []Field, n int) int64 {
 	base := int64(dbfRowDeletionFlagSz) + int64(headerLength) + (int64(row) * int64(recordLength))
 	return base + int64(dbfFieldStartByte(fields, n)-dbfRowDeletionFlagSz) // adjust because base already includes deletion flag
 }
+
+// visibleDbfFields filters out system columns from fields. It returns the
+// remaining fields and, for each of them, the index into the original slice.
+func visibleDbfFields(fields []Field) ([]Field, []int) {
+	visible := make([]Field, 0, len(fields))
+	index := make([]int, 0, len(fields))
+	for i, f := range fields {
+		if f.IsSystem() {
+			continue
+		}
+		visible = append(visible, f)
+		index = append(index, i)
+	}
+	return visible, index
+}
+
+// normalizeNumeric rewrites a numeric DBF value written with locale specific
+// formatting into the form understood by strconv: a leading '+' is dropped,
+// spaces, apostrophes and underscores used for digit grouping are removed and
+// the decimal separator becomes '.'. When both ',' and '.' occur, the one that
+// comes last is the decimal separator. A single ',' is treated as a thousands
+// separator only if it is followed by exactly three digits, otherwise it is a
+// decimal comma.
+func normalizeNumeric(value string) string {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "+")
+	value = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\'', '_', '\u00a0':
+			return -1
+		}
+		return r
+	}, value)
+
+	lastComma := strings.LastIndexByte(value, ',')
+	lastDot := strings.LastIndexByte(value, '.')
+	switch {
+	case lastComma < 0:
+		return value
+	case lastDot > lastComma:
+		return strings.ReplaceAll(value, ",", "")
+	case lastDot >= 0:
+		return strings.Replace(strings.ReplaceAll(value, ".", ""), ",", ".", 1)
+	case strings.Count(value, ",") > 1 || len(value)-lastComma-1 == 3:
+		return strings.ReplaceAll(value, ",", "")
+	default:
+		return strings.Replace(value, ",", ".", 1)
+	}
+}
+
+// dbfTimestampLayout is the text form of timestamp and datetime values.
+const dbfTimestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// julianDayUnixEpoch is the Julian day number of 1970-01-01.
+const julianDayUnixEpoch = 2440588
+
+// isBinaryFieldType reports whether values of the DBF field type t are stored
+// in binary instead of as text.
+func isBinaryFieldType(t byte) bool {
+	return t == dbfFieldTypeInteger || t == dbfFieldTypeDouble || isTimestampFieldType(t)
+}
+
+// isTimestampFieldType reports whether t is the timestamp or datetime type,
+// whose values are stored as a little-endian int32 Julian day number followed
+// by a little-endian int32 of milliseconds since midnight.
+func isTimestampFieldType(t byte) bool {
+	return t == dbfFieldTypeTimestamp || t == dbfFieldTypeDateTime
+}
+
+// binaryFieldValue decodes the value of a binary integer or double field as
+// decimal text, and of a timestamp or datetime field as UTC time in the
+// format of dbfTimestampLayout. Integers and doubles have no empty value: the
+// zero bytes written for records without a value read as 0, and spaces are
+// decoded like any other bytes. Timestamps of zeros or spaces only are empty.
+// ok is false for fields that are not binary.
+func binaryFieldValue(f Field, raw []byte) (value string, ok bool) {
+	if !isBinaryFieldType(f.Fieldtype) {
+		return "", false
+	}
+	if isTimestampFieldType(f.Fieldtype) && len(strings.Trim(string(raw), " ")) == 0 {
+		return "", true
+	}
+	switch {
+	case isTimestampFieldType(f.Fieldtype) && len(raw) >= 8:
+		day := int32(binary.LittleEndian.Uint32(raw))
+		ms := int32(binary.LittleEndian.Uint32(raw[4:]))
+		if day == 0 && ms == 0 {
+			return "", true
+		}
+		t := time.Unix(int64(day-julianDayUnixEpoch)*86400, int64(ms)*int64(time.Millisecond)).UTC()
+		return t.Format(dbfTimestampLayout), true
+	case f.Fieldtype == dbfFieldTypeInteger && len(raw) >= 4:
+		return strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(raw))), 10), true
+	case f.Fieldtype == dbfFieldTypeDouble && len(raw) >= 8:
+		return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(raw)), 'g', -1, 64), true
+	}
+	return "", true
+}
+
+// encodeBinaryField encodes value for the binary integer, double, timestamp
+// or datetime field f. Integers must fit into 32 bits and floats must be
+// integral for integer fields; strings are parsed. It returns nil for empty
+// strings, which leave the value unset.
+func encodeBinaryField(f Field, value interface{}) ([]byte, error) {
+	if isTimestampFieldType(f.Fieldtype) {
+		return encodeTimestampField(f, value)
+	}
+	var v float64
+	switch x := value.(type) {
+	case int:
+		v = float64(x)
+	case float64:
+		v = x
+	case string:
+		x = strings.TrimSpace(x)
+		if x == "" {
+			return nil, nil
+		}
+		var err error
+		if v, err = strconv.ParseFloat(x, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %q", x)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported value type: %T", value)
+	}
+
+	buf := make([]byte, f.Size)
+	if f.Fieldtype == dbfFieldTypeDouble {
+		if len(buf) < 8 {
+			return nil, fmt.Errorf("double field needs 8 bytes, has %d", f.Size)
+		}
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+		return buf, nil
+	}
+	if v != math.Trunc(v) || v < math.MinInt32 || v > math.MaxInt32 {
+		return nil, fmt.Errorf("%v does not fit into a 32-bit integer field", value)
+	}
+	if len(buf) < 4 {
+		return nil, fmt.Errorf("integer field needs 4 bytes, has %d", f.Size)
+	}
+	binary.LittleEndian.PutUint32(buf, uint32(int32(v)))
+	return buf, nil
+}
+
+// encodeTimestampField encodes value, a time.Time or a string in the format
+// of dbfTimestampLayout or RFC 3339, for the timestamp or datetime field f.
+// Times are stored in UTC with millisecond precision.
+func encodeTimestampField(f Field, value interface{}) ([]byte, error) {
+	var t time.Time
+	switch x := value.(type) {
+	case time.Time:
+		t = x
+	case string:
+		x = strings.TrimSpace(x)
+		if x == "" {
+			return nil, nil
+		}
+		var err error
+		if t, err = time.Parse(time.RFC3339Nano, x); err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", x)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported value type: %T", value)
+	}
+	if f.Size < 8 {
+		return nil, fmt.Errorf("timestamp field needs 8 bytes, has %d", f.Size)
+	}
+
+	ms := t.UTC().UnixMilli()
+	days := ms / 86400000
+	if ms%86400000 < 0 {
+		days-- // floor division for times before 1970
+	}
+	buf := make([]byte, f.Size)
+	binary.LittleEndian.PutUint32(buf, uint32(int32(days+julianDayUnixEpoch)))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(int32(ms-days*86400000)))
+	return buf, nil
+}
@@ -0,0 +1,54 @@
+package shp
+
+// PointDensityGrid 将点按规则网格分箱并统计每个格子中的点数，用于热力图、分级统计图等的预处理。
+// bbox 被均分为 cols 列、rows 行，返回 counts[row][col]，第 0 行对应 MinY 一侧，
+// 第 0 列对应 MinX 一侧。落在格子之间边界上的点计入坐标较大的格子，落在 MaxX、MaxY
+// 边界上的点计入最后一列、最后一行；bbox 之外或坐标为 NaN 的点不计入。
+// cols 或 rows 不为正数时返回 nil
+func (GeometryUtils) PointDensityGrid(points []Point, bbox Box, cols, rows int) [][]int {
+	if cols <= 0 || rows <= 0 {
+		return nil
+	}
+	// 一次分配整个网格，按行切分
+	cells := make([]int, cols*rows)
+	counts := make([][]int, rows)
+	for i := range counts {
+		counts[i] = cells[i*cols : (i+1)*cols]
+	}
+
+	width, height := bbox.MaxX-bbox.MinX, bbox.MaxY-bbox.MinY
+	for _, p := range points {
+		// 比较对 NaN 均为 false，NaN 坐标的点在这里被排除
+		if !(p.X >= bbox.MinX && p.X <= bbox.MaxX && p.Y >= bbox.MinY && p.Y <= bbox.MaxY) {
+			continue
+		}
+		counts[gridCell(p.Y-bbox.MinY, height, rows)][gridCell(p.X-bbox.MinX, width, cols)]++
+	}
+	return counts
+}
+
+// PointDensityGridFromFile 读取点 Shapefile（Point、PointZ 或 PointM）并按 PointDensityGrid
+// 统计网格中的点数，空记录不计入。bbox 为零值时使用文件头中的范围。
+// 包含其他几何类型时返回 ErrUnsupportedType 类型的错误
+func (g GeometryUtils) PointDensityGridFromFile(filename string, bbox Box, cols, rows int) ([][]int, error) {
+	points, extent, err := readPointFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if bbox == (Box{}) {
+		bbox = extent
+	}
+	return g.PointDensityGrid(points, bbox, cols, rows), nil
+}
+
+// gridCell 返回距起点 offset 的坐标在长度为 size、均分为 n 格的范围内所在格子的下标
+func gridCell(offset, size float64, n int) int {
+	if size <= 0 {
+		return 0
+	}
+	i := int(offset / size * float64(n))
+	if i >= n {
+		i = n - 1
+	}
+	return i
+}
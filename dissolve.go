@@ -0,0 +1,188 @@
+package shp
+
+import (
+	"fmt"
+	"math"
+)
+
+// DissolveByField writes to output one record for every distinct value of the
+// field fieldName of the shapefile input, e.g. to aggregate census blocks into
+// counties. The geometry of a record combines the geometries of all input
+// records with that value: the parts of lines and polygons are concatenated
+// and points are collected into a multipoint, so a point shapefile becomes a
+// multipoint shapefile. Parts are not merged geometrically; polygons sharing a
+// boundary keep it. Null shapes are left out of the geometry; a group with
+// only Null shapes is written as a Null shape.
+//
+// The output has fieldName as its only field. Records appear in the order in
+// which their value was first seen; values are compared after trimming
+// blanks, and records with an empty value form one group of their own. All
+// geometries are held in memory until the output is written. MultiPatch
+// shapefiles are an error of type ErrUnsupportedType. The .prj and .cpg files
+// are copied if present.
+func DissolveByField(input, output string, fieldName string) error {
+	r, err := Open(input)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	field, err := r.FieldIndex(fieldName)
+	if err != nil {
+		return err
+	}
+	outType, err := dissolvedShapeType(r.GeometryType)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	groups := make(map[string][]Shape)
+	for r.Next() {
+		n, shape := r.Shape()
+		key := trimAttribute(r.ReadAttribute(n, field))
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+			groups[key] = nil
+		}
+		if _, isNull := shape.(*Null); !isNull {
+			groups[key] = append(groups[key], shape)
+		}
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	w, err := Create(output, outType)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := w.SetFields([]Field{r.Fields()[field]}); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		var shape Shape = &Null{}
+		if len(groups[key]) > 0 {
+			shape = dissolveShapes(outType, groups[key])
+		}
+		row, err := w.WriteChecked(shape)
+		if err != nil {
+			return fmt.Errorf("%s %q: %v", fieldName, key, err)
+		}
+		if key == "" {
+			continue
+		}
+		if err := w.WriteAttribute(int(row), 0, key); err != nil {
+			return fmt.Errorf("%s %q: %v", fieldName, key, err)
+		}
+	}
+
+	return copySidecars(shapefileBase(input), shapefileBase(output), ".prj", ".cpg")
+}
+
+// dissolvedShapeType returns the shape type that combines several shapes of
+// type t.
+func dissolvedShapeType(t ShapeType) (ShapeType, error) {
+	switch t {
+	case POINT:
+		return MULTIPOINT, nil
+	case POINTZ:
+		return MULTIPOINTZ, nil
+	case POINTM:
+		return MULTIPOINTM, nil
+	case POLYLINE, POLYGON, MULTIPOINT, POLYLINEZ, POLYGONZ, MULTIPOINTZ, POLYLINEM, POLYGONM, MULTIPOINTM:
+		return t, nil
+	default:
+		return t, NewShapeError(ErrUnsupportedType, fmt.Sprintf("cannot dissolve %s shapes", t), nil)
+	}
+}
+
+// dissolveShapes returns a shape of type t, as returned by dissolvedShapeType,
+// with the points and parts of all shapes. Missing Z and M values are filled
+// with NaN (no data).
+func dissolveShapes(t ShapeType, shapes []Shape) Shape {
+	var (
+		parts  []int32
+		points []Point
+		zs, ms []float64
+	)
+	add := func(partStarts []int32, pts []Point, z, m []float64) {
+		for _, start := range partStarts {
+			parts = append(parts, start+int32(len(points)))
+		}
+		points = append(points, pts...)
+		zs = append(zs, padValues(z, len(pts))...)
+		ms = append(ms, padValues(m, len(pts))...)
+	}
+	for _, shape := range shapes {
+		switch s := shape.(type) {
+		case *Point:
+			add(nil, []Point{*s}, nil, nil)
+		case *PointZ:
+			add(nil, []Point{{X: s.X, Y: s.Y}}, []float64{s.Z}, []float64{s.M})
+		case *PointM:
+			add(nil, []Point{{X: s.X, Y: s.Y}}, nil, []float64{s.M})
+		case *MultiPoint:
+			add(nil, s.Points, nil, nil)
+		case *MultiPointZ:
+			add(nil, s.Points, s.ZArray, s.MArray)
+		case *MultiPointM:
+			add(nil, s.Points, nil, s.MArray)
+		case *PolyLine:
+			add(s.Parts, s.Points, nil, nil)
+		case *Polygon:
+			add(s.Parts, s.Points, nil, nil)
+		case *PolyLineZ:
+			add(s.Parts, s.Points, s.ZArray, s.MArray)
+		case *PolygonZ:
+			add(s.Parts, s.Points, s.ZArray, s.MArray)
+		case *PolyLineM:
+			add(s.Parts, s.Points, nil, s.MArray)
+		case *PolygonM:
+			add(s.Parts, s.Points, nil, s.MArray)
+		}
+	}
+
+	box := BBoxFromPoints(points)
+	numParts, numPoints := int32(len(parts)), int32(len(points))
+	switch t {
+	case MULTIPOINT:
+		return &MultiPoint{Box: box, NumPoints: numPoints, Points: points}
+	case MULTIPOINTZ:
+		return &MultiPointZ{Box: box, NumPoints: numPoints, Points: points,
+			ZRange: valueRange(zs), ZArray: zs, MRange: valueRange(ms), MArray: ms}
+	case MULTIPOINTM:
+		return &MultiPointM{Box: box, NumPoints: numPoints, Points: points, MRange: valueRange(ms), MArray: ms}
+	case POLYLINE:
+		return &PolyLine{Box: box, NumParts: numParts, NumPoints: numPoints, Parts: parts, Points: points}
+	case POLYGON:
+		return &Polygon{Box: box, NumParts: numParts, NumPoints: numPoints, Parts: parts, Points: points}
+	case POLYLINEZ:
+		return &PolyLineZ{Box: box, NumParts: numParts, NumPoints: numPoints, Parts: parts, Points: points,
+			ZRange: valueRange(zs), ZArray: zs, MRange: valueRange(ms), MArray: ms}
+	case POLYGONZ:
+		return &PolygonZ{Box: box, NumParts: numParts, NumPoints: numPoints, Parts: parts, Points: points,
+			ZRange: valueRange(zs), ZArray: zs, MRange: valueRange(ms), MArray: ms}
+	case POLYLINEM:
+		return &PolyLineM{Box: box, NumParts: numParts, NumPoints: numPoints, Parts: parts, Points: points,
+			MRange: valueRange(ms), MArray: ms}
+	default:
+		return &PolygonM{Box: box, NumParts: numParts, NumPoints: numPoints, Parts: parts, Points: points,
+			MRange: valueRange(ms), MArray: ms}
+	}
+}
+
+// padValues returns the first n of values, followed by NaN if values has
+// fewer than n.
+func padValues(values []float64, n int) []float64 {
+	if len(values) >= n {
+		return values[:n]
+	}
+	padded := make([]float64, n)
+	copy(padded, values)
+	for i := len(values); i < n; i++ {
+		padded[i] = math.NaN()
+	}
+	return padded
+}
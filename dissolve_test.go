@@ -0,0 +1,122 @@
+package shp
+
+import (
+	"testing"
+)
+
+func TestDissolveByField(t *testing.T) {
+	dir := t.TempDir()
+	points := dir + "/blocks.shp"
+	w, err := Create(points, POINTZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("COUNTY", 8), NumberField("POP", 6)}); err != nil {
+		t.Fatal(err)
+	}
+	for i, county := range []string{"north", "south", "north", "", "north"} {
+		w.Write(&PointZ{X: float64(i), Y: float64(i), Z: float64(i * 10)})
+		if county != "" {
+			_ = w.WriteAttribute(i, 0, county)
+		}
+		_ = w.WriteAttribute(i, 1, i)
+	}
+	w.Close()
+
+	out := dir + "/counties.shp"
+	if err := DissolveByField(points, out, "county"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.GeometryType != MULTIPOINTZ {
+		t.Errorf("got shape type %s, want MULTIPOINTZ", r.GeometryType)
+	}
+	if fields := r.Fields(); len(fields) != 1 || fields[0].String() != "COUNTY" {
+		t.Errorf("got fields %v, want COUNTY only", fields)
+	}
+	want := []struct {
+		county string
+		xs     []float64
+	}{{"north", []float64{0, 2, 4}}, {"south", []float64{1}}, {"", []float64{3}}}
+	n := 0
+	for r.Next() {
+		row, shape := r.Shape()
+		if row >= len(want) {
+			t.Fatalf("unexpected record %d", row)
+		}
+		mp := shape.(*MultiPointZ)
+		if got := r.ReadAttribute(row, 0); got != want[row].county {
+			t.Errorf("record %d: county %q, want %q", row, got, want[row].county)
+		}
+		if len(mp.Points) != len(want[row].xs) {
+			t.Fatalf("record %d: %d points, want %d", row, len(mp.Points), len(want[row].xs))
+		}
+		for i, x := range want[row].xs {
+			if mp.Points[i].X != x || mp.ZArray[i] != x*10 {
+				t.Errorf("record %d point %d: got %v z %v", row, i, mp.Points[i], mp.ZArray[i])
+			}
+		}
+		n++
+	}
+	if n != len(want) {
+		t.Errorf("got %d records, want %d", n, len(want))
+	}
+
+	// polygon parts are concatenated
+	polygons := dir + "/parcels.shp"
+	w, err = Create(polygons, POLYGON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("OWNER", 8)}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		x := float64(i * 2)
+		w.Write(NewPolygon([][]Point{{{x, 0}, {x, 1}, {x + 1, 1}, {x + 1, 0}, {x, 0}}}))
+		_ = w.WriteAttribute(i, 0, "smith")
+	}
+	// a group without geometry
+	w.Write(&Null{})
+	_ = w.WriteAttribute(2, 0, "jones")
+	w.Close()
+	if err := DissolveByField(polygons, dir+"/owners.shp", "OWNER"); err != nil {
+		t.Fatal(err)
+	}
+	r2, err := Open(dir + "/owners.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close()
+	if !r2.Next() {
+		t.Fatal("no dissolved polygon")
+	}
+	_, shape := r2.Shape()
+	if pg := shape.(*Polygon); pg.NumParts != 2 || pg.NumPoints != 10 || pg.Parts[1] != 5 ||
+		pg.Box != (Box{MinX: 0, MinY: 0, MaxX: 3, MaxY: 1}) {
+		t.Errorf("got %+v", pg)
+	}
+	if !r2.Next() {
+		t.Fatal("no dissolved record for the group without geometry")
+	}
+	if _, shape := r2.Shape(); shapeTypeOf(shape) != NULL {
+		t.Errorf("got %T for a group of Null shapes, want *Null", shape)
+	}
+	if got := r2.ReadAttribute(1, 0); got != "jones" {
+		t.Errorf("got owner %q, want jones", got)
+	}
+	if r2.Next() {
+		t.Error("more than two dissolved records")
+	}
+	if want := (Box{MinX: 0, MinY: 0, MaxX: 3, MaxY: 1}); r2.BBox() != want {
+		t.Errorf("got header bbox %v, want %v", r2.BBox(), want)
+	}
+
+	if err := DissolveByField(polygons, dir+"/bad.shp", "MISSING"); err == nil {
+		t.Error("dissolved by unknown field without error")
+	}
+}
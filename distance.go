@@ -0,0 +1,124 @@
+package shp
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultMaxDistanceMatrixPoints 距离矩阵默认允许的最大点数，对应约 200MB 的矩阵
+const DefaultMaxDistanceMatrixPoints = 5000
+
+// earthRadius 地球平均半径（米），用于 Haversine 距离
+const earthRadius = 6371008.8
+
+// DistanceOption 定义距离矩阵选项
+type DistanceOption func(*distanceConfig)
+
+type distanceConfig struct {
+	haversine bool
+	maxPoints int
+}
+
+// WithHaversine 设置按 Haversine 公式计算球面距离：X 为经度、Y 为纬度（度），
+// 距离单位为米。默认计算平面欧氏距离，单位与坐标相同
+func WithHaversine(enabled bool) DistanceOption {
+	return func(config *distanceConfig) {
+		config.haversine = enabled
+	}
+}
+
+// WithMaxPoints 设置距离矩阵允许的最大点数，超过时返回错误而不分配 O(n²) 的内存。
+// 默认为 DefaultMaxDistanceMatrixPoints，非正数表示不限制
+func WithMaxPoints(n int) DistanceOption {
+	return func(config *distanceConfig) {
+		config.maxPoints = n
+	}
+}
+
+// DistanceMatrix 计算点两两之间的距离，返回对称矩阵，matrix[i][j] 为第 i 个点与
+// 第 j 个点的距离。坐标为 NaN 的点（如 DistanceMatrixFromFile 中的空记录）
+// 与其他点的距离为 NaN。点数超过上限时返回 ErrInvalidFormat 类型的错误
+func (GeometryUtils) DistanceMatrix(points []Point, opts ...DistanceOption) ([][]float64, error) {
+	config := distanceConfig{maxPoints: DefaultMaxDistanceMatrixPoints}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.maxPoints > 0 && len(points) > config.maxPoints {
+		return nil, NewShapeError(ErrInvalidFormat,
+			fmt.Sprintf("%d points exceed the distance matrix limit of %d", len(points), config.maxPoints), nil)
+	}
+
+	distance := GeometryUtils{}.Distance
+	if config.haversine {
+		distance = haversineDistance
+	}
+	// 一次分配整个矩阵，按行切分
+	n := len(points)
+	values := make([]float64, n*n)
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = values[i*n : (i+1)*n]
+	}
+	for i := 0; i < n; i++ {
+		if math.IsNaN(points[i].X) || math.IsNaN(points[i].Y) {
+			matrix[i][i] = math.NaN()
+		}
+		for j := i + 1; j < n; j++ {
+			d := distance(points[i], points[j])
+			matrix[i][j], matrix[j][i] = d, d
+		}
+	}
+	return matrix, nil
+}
+
+// DistanceMatrixFromFile 读取点 Shapefile（Point、PointZ 或 PointM）并计算所有记录
+// 两两之间的距离，矩阵的行列与记录下标一一对应，空记录的距离为 NaN。
+// 包含其他几何类型时返回 ErrUnsupportedType 类型的错误
+func (g GeometryUtils) DistanceMatrixFromFile(filename string, opts ...DistanceOption) ([][]float64, error) {
+	points, _, err := readPointFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return g.DistanceMatrix(points, opts...)
+}
+
+// readPointFile 读取点 Shapefile 的所有记录及文件的范围，空记录的坐标为 NaN。
+// 包含其他几何类型时返回 ErrUnsupportedType 类型的错误
+func readPointFile(filename string) ([]Point, Box, error) {
+	r, err := Open(filename, WithGeometryOnly(true))
+	if err != nil {
+		return nil, Box{}, err
+	}
+	defer func() { _ = r.Close() }()
+
+	var points []Point
+	for r.Next() {
+		n, shape := r.Shape()
+		switch s := shape.(type) {
+		case *Point:
+			points = append(points, *s)
+		case *PointZ:
+			points = append(points, Point{s.X, s.Y})
+		case *PointM:
+			points = append(points, Point{s.X, s.Y})
+		case *Null:
+			points = append(points, Point{math.NaN(), math.NaN()})
+		default:
+			return nil, Box{}, NewShapeError(ErrUnsupportedType,
+				fmt.Sprintf("record %d is a %s, not a point", n, shapeTypeOf(shape)), nil)
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, Box{}, err
+	}
+	return points, r.BBox(), nil
+}
+
+// haversineDistance 计算经纬度（度）表示的两点之间的球面距离（米）
+func haversineDistance(p1, p2 Point) float64 {
+	lat1, lat2 := p1.Y*math.Pi/180, p2.Y*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (p2.X - p1.X) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
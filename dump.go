@@ -0,0 +1,153 @@
+package shp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// RawFieldDescriptors returns the unparsed 32-byte field descriptors of the
+// DBF file, read directly from the file so that they are available even if
+// the DBF cannot be opened. The descriptors are read up to the header
+// terminator or the end of the file, whatever the header length claims; a
+// trailing descriptor shorter than 32 bytes is returned as is.
+func (r *Reader) RawFieldDescriptors() ([][]byte, error) {
+	f, err := os.Open(r.filename + ".dbf")
+	if err != nil {
+		return nil, NewShapeError(ErrIO, "failed to open DBF", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.Seek(dbfFieldDescriptorLen, io.SeekStart); err != nil {
+		return nil, NewShapeError(ErrIO, "failed to seek DBF", err)
+	}
+	var descriptors [][]byte
+	for {
+		desc := make([]byte, dbfFieldDescriptorLen)
+		n, err := io.ReadFull(f, desc)
+		if n == 0 || desc[0] == dbfFieldTerminator {
+			break
+		}
+		descriptors = append(descriptors, desc[:n])
+		if err != nil {
+			break
+		}
+	}
+	return descriptors, nil
+}
+
+// DumpHeader formats the headers of the SHP and DBF files as hex dumps
+// followed by their decoded values, one field descriptor at a time. It
+// contains no record data, so it can be attached to bug reports about files
+// that don't open. Problems reading the headers are reported inline.
+func (r *Reader) DumpHeader() string {
+	var b strings.Builder
+	r.dumpShpHeader(&b)
+	b.WriteString("\n")
+	r.dumpDbfHeader(&b)
+	return b.String()
+}
+
+func (r *Reader) dumpShpHeader(b *strings.Builder) {
+	header := make([]byte, shpHeaderLen)
+	cur, err := r.shp.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = r.shp.Seek(0, io.SeekStart)
+	}
+	var n int
+	if err == nil {
+		n, err = io.ReadFull(r.shp, header)
+		_, _ = r.shp.Seek(cur, io.SeekStart)
+	}
+	fmt.Fprintf(b, "SHP header (%d bytes):\n%s", n, hex.Dump(header[:n]))
+	if err != nil {
+		fmt.Fprintf(b, "  error: %v\n", err)
+		return
+	}
+
+	be := binary.BigEndian
+	le := binary.LittleEndian
+	float := func(off int) float64 { return math.Float64frombits(le.Uint64(header[off:])) }
+	fmt.Fprintf(b, "  file code:   %d\n", int32(be.Uint32(header[0:])))
+	fmt.Fprintf(b, "  file length: %d bytes", int64(int32(be.Uint32(header[shpOffsetToFileLength:])))*2)
+	if f, ok := r.shp.(*os.File); ok {
+		if stat, err := f.Stat(); err == nil {
+			fmt.Fprintf(b, " (actual %d bytes)", stat.Size())
+		}
+	}
+	fmt.Fprintf(b, "\n  version:     %d\n", int32(le.Uint32(header[28:])))
+	fmt.Fprintf(b, "  shape type:  %s\n", ShapeType(int32(le.Uint32(header[shpOffsetToGeomType:]))))
+	fmt.Fprintf(b, "  bbox:        %g %g %g %g\n", float(36), float(44), float(52), float(60))
+	fmt.Fprintf(b, "  Z range:     %g %g\n", float(68), float(76))
+	fmt.Fprintf(b, "  M range:     %g %g\n", float(84), float(92))
+}
+
+func (r *Reader) dumpDbfHeader(b *strings.Builder) {
+	data, err := readFilePrefix(r.filename+".dbf", dbfFieldDescriptorLen)
+	fmt.Fprintf(b, "DBF header (%d bytes):\n%s", len(data), hex.Dump(data))
+	if err != nil {
+		fmt.Fprintf(b, "  error: %v\n", err)
+		return
+	}
+	if len(data) < dbfFieldDescriptorLen {
+		b.WriteString("  error: header is truncated\n")
+		return
+	}
+
+	le := binary.LittleEndian
+	fmt.Fprintf(b, "  version:         0x%02x\n", data[0])
+	fmt.Fprintf(b, "  last update:     %d-%02d-%02d\n", 1900+int(data[1]), data[2], data[3])
+	fmt.Fprintf(b, "  records:         %d\n", int32(le.Uint32(data[dbfOffsetNumRecords:])))
+	headerLength := int16(le.Uint16(data[dbfOffsetHeaderLen:]))
+	fmt.Fprintf(b, "  header length:   %d (%d fields)\n", headerLength, calcNumFields(headerLength))
+	fmt.Fprintf(b, "  record length:   %d\n", int16(le.Uint16(data[dbfOffsetRecordLen:])))
+	codePage := data[dbfOffsetCodePage]
+	fmt.Fprintf(b, "  language driver: 0x%02x", codePage)
+	if name, ok := dbfCodePageNames[codePage]; ok {
+		fmt.Fprintf(b, " (%s)", name)
+	}
+	b.WriteString("\n")
+
+	descriptors, err := r.RawFieldDescriptors()
+	if err != nil {
+		fmt.Fprintf(b, "  error: %v\n", err)
+		return
+	}
+	recordLength := dbfRowDeletionFlagSz
+	for i, desc := range descriptors {
+		fmt.Fprintf(b, "\nField descriptor %d:\n%s", i, hex.Dump(desc))
+		if len(desc) < dbfFieldDescriptorLen {
+			b.WriteString("  error: descriptor is truncated\n")
+			continue
+		}
+		name := desc[:11]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		fmt.Fprintf(b, "  name %q, type %q, size %d, decimals %d, flags 0x%02x\n",
+			name, desc[11], desc[16], desc[17], desc[18+dbfFieldFlagsOffset])
+		recordLength += int(desc[16])
+	}
+	fmt.Fprintf(b, "\n%d field descriptors, %d bytes per record including the deletion flag\n",
+		len(descriptors), recordLength)
+}
+
+// readFilePrefix returns up to the first n bytes of the file filename.
+func readFilePrefix(filename string, n int) ([]byte, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	data := make([]byte, n)
+	read, err := io.ReadFull(f, data)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+	return data[:read], err
+}
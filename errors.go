@@ -59,6 +59,85 @@ func NewShapeError(errType ErrType, message string, cause error) *ShapeError {
 	}
 }
 
+// HeaderLengthError 表示文件头记录的文件长度大于实际文件大小，通常说明文件被截断.
+// 它作为 ErrCorruptedFile 类型 ShapeError 的 Cause 返回，可通过 errors.As 获取.
+type HeaderLengthError struct {
+	Reported int64 // 文件头记录的长度（字节）
+	Actual   int64 // 实际文件大小（字节）
+}
+
+// Error 实现 error 接口
+func (e *HeaderLengthError) Error() string {
+	return fmt.Sprintf("header reports file length %d but actual file size is %d", e.Reported, e.Actual)
+}
+
+// RecordCountMismatchError 表示 SHP 文件的记录数与 DBF 文件的记录数不一致.
+// 它作为 ErrCorruptedFile 类型 ShapeError 的 Cause 返回，可通过 errors.As 获取.
+type RecordCountMismatchError struct {
+	ShapeRecords     int // SHP 文件中的记录数
+	AttributeRecords int // DBF 文件中的记录数
+}
+
+// Error 实现 error 接口
+func (e *RecordCountMismatchError) Error() string {
+	return fmt.Sprintf("shapefile has %d records but DBF has %d", e.ShapeRecords, e.AttributeRecords)
+}
+
+// RowOutOfRangeError 表示读取的属性行号超出了 DBF 的记录范围.
+// 它作为 ErrInvalidField 类型 ShapeError 的 Cause 返回，可通过 errors.As 获取.
+type RowOutOfRangeError struct {
+	Row        int // 请求的行号
+	NumRecords int // DBF 中的记录数
+}
+
+// Error 实现 error 接口
+func (e *RowOutOfRangeError) Error() string {
+	return fmt.Sprintf("row %d out of range [0, %d)", e.Row, e.NumRecords)
+}
+
+// RecordNumberError 表示记录头中的记录号与记录位置不符。记录号应从 1 开始连续递增，
+// 否则说明文件损坏或被不当编辑，按记录号对应的属性行可能错位.
+type RecordNumberError struct {
+	Number    int32 // 记录头中的记录号
+	Expected  int32 // 按记录位置应有的记录号
+	Duplicate bool  // 该记录号是否已被之前的记录使用
+}
+
+// Error 实现 error 接口
+func (e *RecordNumberError) Error() string {
+	if e.Duplicate {
+		return fmt.Sprintf("duplicate record number %d, expected %d", e.Number, e.Expected)
+	}
+	return fmt.Sprintf("record number %d, expected %d", e.Number, e.Expected)
+}
+
+// RecordOrderError 表示 SHX 索引中某一项的偏移量与 SHP 文件中同一位置记录的偏移量不符，
+// 即索引顺序与物理顺序不一致。偏移量为 -1 表示该位置在对应文件中不存在.
+type RecordOrderError struct {
+	IndexOffset int64 // SHX 索引中的偏移量
+	FileOffset  int64 // SHP 文件中按物理顺序同一位置记录的偏移量
+}
+
+// Error 实现 error 接口
+func (e *RecordOrderError) Error() string {
+	return fmt.Sprintf("index offset %d, file offset %d", e.IndexOffset, e.FileOffset)
+}
+
+// CoordinateBoundsError 表示形状的某个顶点超出了 WithCoordinateBounds 设置的坐标范围，
+// 常见于同一文件中混用投影坐标与经纬度坐标.
+// 它作为 ErrInvalidFormat 类型 ShapeError 的 Cause 返回，可通过 errors.As 获取.
+type CoordinateBoundsError struct {
+	Vertex int   // 顶点在形状所有点中的下标（从 0 开始）
+	Point  Point // 超出范围的顶点
+	Bounds Box   // 允许的坐标范围
+}
+
+// Error 实现 error 接口
+func (e *CoordinateBoundsError) Error() string {
+	return fmt.Sprintf("vertex %d (%g, %g) outside bounds [%g, %g, %g, %g]", e.Vertex, e.Point.X, e.Point.Y,
+		e.Bounds.MinX, e.Bounds.MinY, e.Bounds.MaxX, e.Bounds.MaxY)
+}
+
 // 预定义的错误变量
 var (
 	ErrInvalidFileExtension = NewShapeError(ErrInvalidFormat, "invalid file extension", nil)
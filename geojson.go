@@ -1,11 +1,17 @@
 package shp
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -24,6 +30,7 @@ type GeoJSON struct {
 // Feature represents a GeoJSON Feature
 type Feature struct {
 	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
 	Geometry   *Geometry              `json:"geometry"`
 	Properties map[string]interface{} `json:"properties"`
 }
@@ -38,8 +45,15 @@ type Geometry struct {
 // GeoJSONConverter provides methods to convert between Shapefile and GeoJSON
 type GeoJSONConverter struct{}
 
-// ShapeToGeoJSON converts a single shape to GeoJSON geometry
+// ShapeToGeoJSON converts a single shape to GeoJSON geometry. Polygons whose
+// rings can't be told apart by their winding are grouped by LargestAsOuter.
 func (c GeoJSONConverter) ShapeToGeoJSON(shape Shape) (*Geometry, error) {
+	return c.shapeToGeoJSON(shape, LargestAsOuter)
+}
+
+// shapeToGeoJSON converts shape to GeoJSON geometry, grouping the rings of
+// ambiguous polygons according to policy.
+func (c GeoJSONConverter) shapeToGeoJSON(shape Shape, policy AmbiguousRingPolicy) (*Geometry, error) {
 	switch s := shape.(type) {
 	case *Point:
 		return c.pointToGeoJSON(s)
@@ -60,11 +74,11 @@ func (c GeoJSONConverter) ShapeToGeoJSON(shape Shape) (*Geometry, error) {
 	case *PolyLineM:
 		return c.polyLineToGeoJSON(s.Parts, s.Points, nil, s.MArray)
 	case *Polygon:
-		return c.polygonToGeoJSON(s.Parts, s.Points, nil, nil)
+		return c.polygonToGeoJSON(s.Parts, s.Points, nil, nil, policy)
 	case *PolygonZ:
-		return c.polygonToGeoJSON(s.Parts, s.Points, s.ZArray, nil)
+		return c.polygonToGeoJSON(s.Parts, s.Points, s.ZArray, nil, policy)
 	case *PolygonM:
-		return c.polygonToGeoJSON(s.Parts, s.Points, nil, s.MArray)
+		return c.polygonToGeoJSON(s.Parts, s.Points, nil, s.MArray, policy)
 	case *MultiPatch:
 		return c.multiPatchToGeoJSON(s)
 	default:
@@ -80,14 +94,23 @@ func (c GeoJSONConverter) pointToGeoJSON(s *Point) (*Geometry, error) {
 	}, nil
 }
 
-// pointZToGeoJSON converts PointZ to GeoJSON
+// pointZToGeoJSON converts PointZ to GeoJSON. An unknown (NaN) Z is left out.
 func (c GeoJSONConverter) pointZToGeoJSON(s *PointZ) (*Geometry, error) {
 	return &Geometry{
 		Type:        "Point",
-		Coordinates: []float64{s.X, s.Y, s.Z},
+		Coordinates: appendZ([]float64{s.X, s.Y}, s.Z),
 	}, nil
 }
 
+// appendZ appends z to the position coord unless it is NaN, which stands for
+// an unknown Z value and cannot be represented in JSON.
+func appendZ(coord []float64, z float64) []float64 {
+	if math.IsNaN(z) {
+		return coord
+	}
+	return append(coord, z)
+}
+
 // pointMToGeoJSON converts PointM to GeoJSON
 func (c GeoJSONConverter) pointMToGeoJSON(s *PointM) (*Geometry, error) {
 	return &Geometry{
@@ -116,7 +139,7 @@ func (c GeoJSONConverter) multiPointZToGeoJSON(s *MultiPointZ) (*Geometry, error
 		if i < len(s.ZArray) {
 			z = s.ZArray[i]
 		}
-		coords[i] = []float64{p.X, p.Y, z}
+		coords[i] = appendZ([]float64{p.X, p.Y}, z)
 	}
 	return &Geometry{
 		Type:        "MultiPoint",
@@ -189,11 +212,17 @@ func (c GeoJSONConverter) polyLineToGeoJSON(parts []int32, points []Point, zArra
 	}, nil
 }
 
-// polygonToGeoJSON converts polygon data to GeoJSON Polygon or MultiPolygon
-func (c GeoJSONConverter) polygonToGeoJSON(parts []int32, points []Point, zArray, mArray []float64) (*Geometry, error) {
+// polygonToGeoJSON converts polygon data to a GeoJSON Polygon, or to a
+// MultiPolygon if it has several outer rings. The rings are grouped by
+// groupRings.
+func (c GeoJSONConverter) polygonToGeoJSON(parts []int32, points []Point, zArray, mArray []float64, policy AmbiguousRingPolicy) (*Geometry, error) {
 	if len(parts) == 0 {
 		return nil, fmt.Errorf("no parts in polygon")
 	}
+	groups, err := groupRings(splitParts(parts, points), policy)
+	if err != nil {
+		return nil, err
+	}
 
 	rings := make([]interface{}, 0, len(parts))
 	for i, part := range parts {
@@ -217,11 +246,126 @@ func (c GeoJSONConverter) polygonToGeoJSON(parts []int32, points []Point, zArray
 		rings = append(rings, coords)
 	}
 
-	// For simplicity, treat all as single Polygon with multiple rings
-	return &Geometry{
-		Type:        "Polygon",
-		Coordinates: rings,
-	}, nil
+	polygons := make([]interface{}, len(groups))
+	for i, group := range groups {
+		polygon := make([]interface{}, len(group))
+		for j, ring := range group {
+			polygon[j] = rings[ring]
+		}
+		polygons[i] = polygon
+	}
+	if len(polygons) == 1 {
+		return &Geometry{Type: "Polygon", Coordinates: polygons[0]}, nil
+	}
+	return &Geometry{Type: "MultiPolygon", Coordinates: polygons}, nil
+}
+
+// groupRings groups the rings of a polygon into polygons, returning for every
+// polygon the indices of its outer ring followed by those of its holes.
+// Clockwise rings are outer rings and every counter-clockwise ring is a hole of
+// the smallest outer ring containing it, or else of the outer ring before it.
+// If all rings have the same winding, they are all outer rings unless one lies
+// inside another; then policy decides.
+func groupRings(rings [][]Point, policy AmbiguousRingPolicy) ([][]int, error) {
+	clockwise := make([]bool, len(rings))
+	sameWinding := true
+	for i, ring := range rings {
+		clockwise[i] = isClockwise(ring)
+		sameWinding = sameWinding && clockwise[i] == clockwise[0]
+	}
+	if !sameWinding {
+		return groupRingsByWinding(rings, clockwise), nil
+	}
+
+	nested := false
+	for i := range rings {
+		for j := range rings {
+			if i != j && ringContainsRing(rings[j], rings[i]) {
+				nested = true
+			}
+		}
+	}
+	if !nested || policy == TreatAllAsOuter {
+		groups := make([][]int, len(rings))
+		for i := range rings {
+			groups[i] = []int{i}
+		}
+		return groups, nil
+	}
+	if policy == AmbiguousRingsError {
+		return nil, NewShapeError(ErrInvalidFormat,
+			fmt.Sprintf("all %d rings of the polygon have the same winding and some are nested", len(rings)), nil)
+	}
+	return groupRingsByArea(rings), nil
+}
+
+// groupRingsByWinding groups rings, some of them clockwise, as described by
+// groupRings.
+func groupRingsByWinding(rings [][]Point, clockwise []bool) [][]int {
+	geom := GeometryUtils{}
+	var groups [][]int
+	group := make([]int, len(rings)) // the group of every outer ring
+	for i := range rings {
+		if clockwise[i] {
+			group[i] = len(groups)
+			groups = append(groups, []int{i})
+		}
+	}
+	previous := -1
+	for i, ring := range rings {
+		if clockwise[i] {
+			previous = i
+			continue
+		}
+		parent := -1
+		for j := range rings {
+			if clockwise[j] && ringContainsRing(rings[j], ring) &&
+				(parent < 0 || geom.Area(rings[j]) < geom.Area(rings[parent])) {
+				parent = j
+			}
+		}
+		if parent < 0 {
+			parent = previous
+		}
+		if parent < 0 {
+			// a counter-clockwise ring before any outer ring is an outer ring
+			groups = append(groups, []int{i})
+			continue
+		}
+		groups[group[parent]] = append(groups[group[parent]], i)
+	}
+	return groups
+}
+
+// groupRingsByArea groups rings for LargestAsOuter: the largest ring is an
+// outer ring and the rings inside it are its holes, and so on for the rest.
+// The polygons are ordered by the position of their outer rings.
+func groupRingsByArea(rings [][]Point) [][]int {
+	geom := GeometryUtils{}
+	order := make([]int, len(rings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return geom.Area(rings[order[a]]) > geom.Area(rings[order[b]]) })
+
+	var groups [][]int
+	assigned := make([]bool, len(rings))
+	for _, outer := range order {
+		if assigned[outer] {
+			continue
+		}
+		assigned[outer] = true
+		group := []int{outer}
+		for i := range rings {
+			if !assigned[i] && ringContainsRing(rings[outer], rings[i]) {
+				assigned[i] = true
+				group = append(group, i)
+			}
+		}
+		groups = append(groups, group)
+	}
+	sort.SliceStable(groups, func(a, b int) bool { return groups[a][0] < groups[b][0] })
+	return groups
 }
 
 // pointsToCoordinates converts points to coordinate arrays
@@ -230,16 +374,27 @@ func (c GeoJSONConverter) pointsToCoordinates(points []Point, zArray, _ []float6
 	for i, p := range points {
 		coord := []float64{p.X, p.Y}
 		if zArray != nil && i < len(zArray) {
-			coord = append(coord, zArray[i])
+			coord = appendZ(coord, zArray[i])
 		}
 		coords[i] = coord
 	}
 	return coords
 }
 
-// FeatureToGeoJSON converts a shape with attributes to a GeoJSON Feature
+// FeatureToGeoJSON converts a shape with attributes to a GeoJSON Feature. A
+// Null shape gives a feature with a null geometry, so records without a
+// geometry keep their attributes.
 func (c GeoJSONConverter) FeatureToGeoJSON(shape Shape, properties map[string]interface{}) (*Feature, error) {
-	geometry, err := c.ShapeToGeoJSON(shape)
+	return c.featureToGeoJSON(shape, properties, LargestAsOuter)
+}
+
+// featureToGeoJSON is FeatureToGeoJSON with the AmbiguousRingPolicy for
+// polygons.
+func (c GeoJSONConverter) featureToGeoJSON(shape Shape, properties map[string]interface{}, policy AmbiguousRingPolicy) (*Feature, error) {
+	if _, isNull := shape.(*Null); isNull {
+		return &Feature{Type: "Feature", Properties: properties}, nil
+	}
+	geometry, err := c.shapeToGeoJSON(shape, policy)
 	if err != nil {
 		return nil, err
 	}
@@ -251,7 +406,234 @@ func (c GeoJSONConverter) FeatureToGeoJSON(shape Shape, properties map[string]in
 	}, nil
 }
 
-// ShapefileToGeoJSON converts an entire shapefile to a GeoJSON FeatureCollection
+// readFeature builds the GeoJSON Feature for the shape at row n of reader,
+// converting attribute values to numbers and booleans where possible. If the
+// reader was configured with WithIDField, the value of that field becomes the
+// feature id and is left out of the properties. Empty values are rendered
+// according to the configured NullAttributePolicy.
+func (c GeoJSONConverter) readFeature(reader *Reader, n int, shape Shape, fields []Field) (*Feature, error) {
+	idField := ""
+	nullPolicy := NullAsNull
+	ringPolicy := LargestAsOuter
+	if reader.config != nil {
+		idField = reader.config.IDField
+		nullPolicy = reader.config.NullAttributePolicy
+		ringPolicy = reader.config.AmbiguousRingPolicy
+	}
+
+	var id interface{}
+	properties := make(map[string]interface{}, len(fields))
+	for i, field := range fields {
+		// GeoJSON is UTF-8, whatever the encoding of the DBF
+		attr, err := decodeDbfString(reader.ReadAttribute(n, i), reader.Encoding())
+		if err != nil {
+			return nil, &attributeError{field: field.String(), err: err}
+		}
+		value := parseAttributeValue(attr)
+		if idField != "" && strings.EqualFold(field.String(), idField) {
+			id = value
+			continue
+		}
+		if attr == "" {
+			switch nullPolicy {
+			case NullOmit:
+				continue
+			case NullAsEmptyString:
+				value = ""
+			}
+		}
+		properties[field.String()] = value
+	}
+
+	feature, err := c.featureToGeoJSON(shape, properties, ringPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if reader.config != nil && reader.config.RFC7946Winding {
+		rewindRFC7946(feature.Geometry)
+	}
+	if reader.config != nil && reader.config.ElevationPolicy != ElevationInCoordinates {
+		applyElevationPolicy(feature, shape, reader.config.ElevationPolicy)
+	}
+	feature.ID = id
+	return feature, nil
+}
+
+// attributeError is returned by readFeature for an attribute that cannot be
+// converted, such as text in an unsupported encoding. Unlike an invalid
+// geometry it is not skipped by the conversions, since every record of the
+// file would be affected.
+type attributeError struct {
+	field string
+	err   error
+}
+
+func (e *attributeError) Error() string { return fmt.Sprintf("field %s: %v", e.field, e.err) }
+
+func (e *attributeError) Unwrap() error { return e.err }
+
+// isAttributeError reports whether err is an attributeError.
+func isAttributeError(err error) bool {
+	var attrErr *attributeError
+	return errors.As(err, &attrErr)
+}
+
+// readFeatures returns the features for the shape at row n of reader: the
+// feature built by readFeature, or with ExplodeMultipart one feature per part
+// of it.
+func (c GeoJSONConverter) readFeatures(reader *Reader, n int, shape Shape, fields []Field) ([]*Feature, error) {
+	feature, err := c.readFeature(reader, n, shape, fields)
+	if err != nil {
+		return nil, err
+	}
+	if reader.config == nil || !reader.config.ExplodeMultipart {
+		return []*Feature{feature}, nil
+	}
+	return explodeFeature(feature), nil
+}
+
+// explodeFeature splits feature into one feature per point of a MultiPoint,
+// line of a MultiLineString or polygon of a MultiPolygon. Every feature gets
+// the id and a copy of the properties of feature. Other features are returned
+// unchanged.
+func explodeFeature(feature *Feature) []*Feature {
+	if feature.Geometry == nil {
+		return []*Feature{feature}
+	}
+	var geometries []*Geometry
+	switch feature.Geometry.Type {
+	case "MultiPoint":
+		coords, _ := feature.Geometry.Coordinates.([][]float64)
+		for _, coord := range coords {
+			geometries = append(geometries, &Geometry{Type: "Point", Coordinates: coord})
+		}
+	case "MultiLineString":
+		lines, _ := feature.Geometry.Coordinates.([]interface{})
+		for _, line := range lines {
+			geometries = append(geometries, &Geometry{Type: "LineString", Coordinates: line})
+		}
+	case "MultiPolygon":
+		polygons, _ := feature.Geometry.Coordinates.([]interface{})
+		for _, polygon := range polygons {
+			geometries = append(geometries, &Geometry{Type: "Polygon", Coordinates: polygon})
+		}
+	}
+	if len(geometries) <= 1 {
+		return []*Feature{feature}
+	}
+
+	features := make([]*Feature, len(geometries))
+	for i, geometry := range geometries {
+		properties := make(map[string]interface{}, len(feature.Properties))
+		for name, value := range feature.Properties {
+			properties[name] = value
+		}
+		features[i] = &Feature{Type: "Feature", ID: feature.ID, Geometry: geometry, Properties: properties}
+	}
+	return features
+}
+
+// applyElevationPolicy copies the Z and M values of a PointZ or PointM shape
+// into the "elevation" and "measure" properties of feature, unless an
+// attribute of that name exists, and with ElevationPropertyOnly reduces the
+// coordinates to X and Y.
+func applyElevationPolicy(feature *Feature, shape Shape, policy ElevationPolicy) {
+	values := make(map[string]interface{}, 2)
+	switch s := shape.(type) {
+	case *PointZ:
+		values["elevation"], values["measure"] = s.Z, s.M
+		if math.IsNaN(s.Z) {
+			values["elevation"] = nil
+		}
+		if policy == ElevationPropertyOnly {
+			feature.Geometry.Coordinates = []float64{s.X, s.Y}
+		}
+	case *PointM:
+		values["measure"] = s.M
+	default:
+		return
+	}
+	for name, v := range values {
+		if _, exists := feature.Properties[name]; !exists {
+			feature.Properties[name] = v
+		}
+	}
+}
+
+// rewindRFC7946 orients the rings of the polygons in geom in place according
+// to the right-hand rule of RFC 7946: the first ring of every polygon, its
+// exterior, counter-clockwise and all following rings, its holes, clockwise.
+func rewindRFC7946(geom *Geometry) {
+	if geom == nil {
+		return
+	}
+	switch geom.Type {
+	case "Polygon":
+		rewindPolygonRings(geom.Coordinates)
+	case "MultiPolygon":
+		if polygons, ok := geom.Coordinates.([]interface{}); ok {
+			for _, polygon := range polygons {
+				rewindPolygonRings(polygon)
+			}
+		}
+	case "GeometryCollection":
+		for _, g := range geom.Geometries {
+			rewindRFC7946(g)
+		}
+	}
+}
+
+// rewindPolygonRings orients the rings of the polygon coordinates rings,
+// which must be a []interface{} of [][]float64 rings as built by
+// polygonToGeoJSON.
+func rewindPolygonRings(rings interface{}) {
+	list, ok := rings.([]interface{})
+	if !ok {
+		return
+	}
+	for i, r := range list {
+		ring, ok := r.([][]float64)
+		if !ok {
+			continue
+		}
+		area := 0.0
+		for j := range ring {
+			k := (j + 1) % len(ring)
+			area += ring[j][0]*ring[k][1] - ring[k][0]*ring[j][1]
+		}
+		// positive area is counter-clockwise
+		if (i == 0) != (area > 0) {
+			for a, b := 0, len(ring)-1; a < b; a, b = a+1, b-1 {
+				ring[a], ring[b] = ring[b], ring[a]
+			}
+		}
+	}
+}
+
+// parseAttributeValue converts a DBF attribute string into an int64, float64
+// or bool if it can be parsed as such. Empty values become nil.
+func parseAttributeValue(attr string) interface{} {
+	if attr == "" {
+		return nil
+	}
+	if iVal, err := strconv.ParseInt(attr, 10, 64); err == nil {
+		return iVal
+	}
+	if fVal, err := strconv.ParseFloat(attr, 64); err == nil {
+		return fVal
+	}
+	if attr == boolTrue || attr == boolFalse {
+		return attr == boolTrue
+	}
+	return attr
+}
+
+// ShapefileToGeoJSON converts an entire shapefile to a GeoJSON FeatureCollection.
+// The features are in record order and encoding/json writes the properties
+// sorted by key, so the marshaled output is byte for byte the same for the
+// same input. Records with invalid geometries are skipped, but text attributes
+// in an encoding that cannot be decoded to UTF-8, such as GBK, are an
+// ErrUnsupportedType error.
 func (c GeoJSONConverter) ShapefileToGeoJSON(filename string) (*GeoJSON, error) {
 	reader, err := Open(filename)
 	if err != nil {
@@ -270,31 +652,14 @@ func (c GeoJSONConverter) ShapefileToGeoJSON(filename string) (*GeoJSON, error)
 	for reader.Next() {
 		n, shape := reader.Shape()
 
-		// Get attributes
-		properties := make(map[string]interface{}, len(fields))
-		for i, field := range fields {
-			attr := reader.ReadAttribute(n, i)
-			if attr == "" {
-				properties[field.String()] = nil
-				continue
-			}
-			if iVal, err := strconv.ParseInt(attr, 10, 64); err == nil {
-				properties[field.String()] = iVal
-			} else if fVal, err := strconv.ParseFloat(attr, 64); err == nil {
-				properties[field.String()] = fVal
-			} else if attr == boolTrue || attr == boolFalse {
-				properties[field.String()] = (attr == boolTrue)
-			} else {
-				properties[field.String()] = attr
-			}
-		}
-
-		feature, err := c.FeatureToGeoJSON(shape, properties)
-		if err != nil {
+		parts, err := c.readFeatures(reader, n, shape, fields)
+		if isAttributeError(err) {
+			return nil, err
+		} else if err != nil {
 			continue // Skip invalid geometries
 		}
 
-		features = append(features, feature)
+		features = append(features, parts...)
 	}
 
 	if err := reader.Err(); err != nil {
@@ -325,31 +690,14 @@ func (c GeoJSONConverter) ShapefileToGeoJSONWithOptions(filename string, opts ..
 	for reader.Next() {
 		n, shape := reader.Shape()
 
-		// Get attributes
-		properties := make(map[string]interface{}, len(fields))
-		for i, field := range fields {
-			attr := reader.ReadAttribute(n, i)
-			if attr == "" {
-				properties[field.String()] = nil
-				continue
-			}
-			if iVal, err := strconv.ParseInt(attr, 10, 64); err == nil {
-				properties[field.String()] = iVal
-			} else if fVal, err := strconv.ParseFloat(attr, 64); err == nil {
-				properties[field.String()] = fVal
-			} else if attr == boolTrue || attr == boolFalse {
-				properties[field.String()] = (attr == boolTrue)
-			} else {
-				properties[field.String()] = attr
-			}
-		}
-
-		feature, err := c.FeatureToGeoJSON(shape, properties)
-		if err != nil {
+		parts, err := c.readFeatures(reader, n, shape, fields)
+		if isAttributeError(err) {
+			return nil, err
+		} else if err != nil {
 			continue // Skip invalid geometries
 		}
 
-		features = append(features, feature)
+		features = append(features, parts...)
 	}
 
 	// 注意：这里我们不检查reader.Err()，因为在容错模式下可能会有一些可恢复的错误
@@ -365,52 +713,552 @@ func (c GeoJSONConverter) ShapefileToGeoJSONWithOptions(filename string, opts ..
 }
 
 // GeoJSONToShapefile converts a GeoJSON FeatureCollection to a shapefile
-func (c GeoJSONConverter) GeoJSONToShapefile(geoJSON *GeoJSON, filename string) error {
+// Writer options are passed on to Create. The shape type is that of the first
+// feature with a geometry; features with a null geometry are written as Null
+// shapes with their attributes. Features with invalid geometries are skipped.
+// A feature whose record would exceed the length limits of the format is an
+// error naming its index; the features before it are kept.
+func (c GeoJSONConverter) GeoJSONToShapefile(geoJSON *GeoJSON, filename string, opts ...WriterOption) error {
 	if geoJSON.Type != "FeatureCollection" || len(geoJSON.Features) == 0 {
 		return fmt.Errorf("invalid GeoJSON: must be a FeatureCollection with features")
 	}
 
-	// Determine the shape type from the first feature
-	firstGeom := geoJSON.Features[0].Geometry
-	shapeType, err := c.determineShapeType(firstGeom)
+	var first *Geometry
+	for _, feature := range geoJSON.Features {
+		if feature != nil && feature.Geometry != nil {
+			first = feature.Geometry
+			break
+		}
+	}
+	fw, err := c.newFeatureWriter(first, mergePropertyFields(geoJSON.Features), filename, opts...)
 	if err != nil {
 		return err
 	}
+	defer fw.close()
 
-	// Create the shapefile writer
-	writer, err := Create(filename, shapeType)
+	for i, feature := range geoJSON.Features {
+		if feature == nil {
+			continue
+		}
+		if err := fw.write(feature); err != nil {
+			return fmt.Errorf("feature %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// GeoJSONStreamToShapefile converts a GeoJSON FeatureCollection read from r to
+// a shapefile. The features array is decoded one feature at a time, so the
+// whole document never has to fit into memory. Writer options are passed on
+// to Create. Features with a null geometry before the first feature with a
+// geometry are held back until the shape type is known. Invalid geometries
+// and records too long for the format are handled as by GeoJSONToShapefile.
+//
+//nolint:gocyclo
+func (c GeoJSONConverter) GeoJSONStreamToShapefile(r io.Reader, shapefilePath string, opts ...WriterOption) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return fmt.Errorf("invalid GeoJSON: %v", err)
+	}
+
+	var fw *featureWriter
+	defer func() {
+		if fw != nil {
+			fw.close()
+		}
+	}()
+	var pending []*Feature
+	start := func(first *Geometry) error {
+		var err error
+		if fw, err = c.newFeatureWriter(first, mergePropertyFields(pending), shapefilePath, opts...); err != nil {
+			return err
+		}
+		for i, f := range pending {
+			if err := fw.write(f); err != nil {
+				return fmt.Errorf("feature %d: %w", i, err)
+			}
+		}
+		pending = nil
+		return nil
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("invalid GeoJSON: %v", err)
+		}
+		switch tok {
+		case "type":
+			var typ string
+			if err := dec.Decode(&typ); err != nil {
+				return fmt.Errorf("invalid GeoJSON type: %v", err)
+			}
+			if typ != "FeatureCollection" {
+				return fmt.Errorf("invalid GeoJSON: must be a FeatureCollection with features")
+			}
+		case "features":
+			if err := expectDelim(dec, '['); err != nil {
+				return fmt.Errorf("invalid GeoJSON features: %v", err)
+			}
+			for index := 0; dec.More(); index++ {
+				var feature Feature
+				if err := dec.Decode(&feature); err != nil {
+					return fmt.Errorf("invalid GeoJSON feature: %v", err)
+				}
+				if fw == nil {
+					pending = append(pending, &feature)
+					if feature.Geometry != nil {
+						if err := start(feature.Geometry); err != nil {
+							return err
+						}
+					}
+					continue
+				}
+				if err := fw.write(&feature); err != nil {
+					return fmt.Errorf("feature %d: %w", index, err)
+				}
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return fmt.Errorf("invalid GeoJSON features: %v", err)
+			}
+		default:
+			// skip members we don't need, e.g. bbox or crs
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("invalid GeoJSON: %v", err)
+			}
+		}
+	}
+
+	if fw == nil && len(pending) > 0 {
+		// no feature has a geometry
+		return start(nil)
+	}
+	if fw == nil {
+		return fmt.Errorf("invalid GeoJSON: must be a FeatureCollection with features")
+	}
+	return nil
+}
+
+// expectDelim reads the next token from dec and checks that it is delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
 	if err != nil {
 		return err
 	}
-	defer writer.Close()
+	if tok != delim {
+		return fmt.Errorf("expected %v but got %v", delim, tok)
+	}
+	return nil
+}
 
-	// Set up fields based on properties of the first feature
-	fields := c.createFieldsFromProperties(geoJSON.Features[0].Properties)
-	if err := writer.SetFields(fields); err != nil {
-		return err
+// featureWriter writes GeoJSON features to a shapefile whose shape type was
+// derived from the first feature and whose fields are derived from the first
+// properties it is given.
+type featureWriter struct {
+	c         GeoJSONConverter
+	writer    *Writer
+	shapeType ShapeType
+	fields    []Field
+	fieldsSet bool
+}
+
+// newFeatureWriter creates the shapefile filename with the shape type of the
+// geometry first, or NULL if it is nil, and fields. If fields is nil, the fields
+// are set from the properties of the first feature written that has any, so
+// that features without properties at the start of the input don't drop the
+// attributes of all others.
+func (c GeoJSONConverter) newFeatureWriter(first *Geometry, fields []Field, filename string, opts ...WriterOption) (*featureWriter, error) {
+	shapeType := NULL
+	if first != nil {
+		var err error
+		if shapeType, err = c.determineShapeType(first); err != nil {
+			return nil, err
+		}
 	}
 
-	// Write features
-	for _, feature := range geoJSON.Features {
-		shape, err := c.GeoJSONToShape(feature.Geometry, shapeType)
+	// Create the shapefile writer
+	writer, err := Create(filename, shapeType, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	fw := &featureWriter{c: c, writer: writer, shapeType: shapeType}
+	if fields != nil {
+		if err := fw.setFields(fields); err != nil {
+			writer.Close()
+			return nil, err
+		}
+	}
+	return fw, nil
+}
+
+// setFields sets the fields of the shapefile. The records written before get
+// empty attributes.
+func (fw *featureWriter) setFields(fields []Field) error {
+	fw.fields = fields
+	fw.fieldsSet = true
+	return fw.writer.SetFields(fw.fields)
+}
+
+// close creates the DBF file if no feature had properties and closes the
+// shapefile.
+func (fw *featureWriter) close() {
+	if !fw.fieldsSet {
+		_ = fw.setFields(nil)
+	}
+	fw.writer.Close()
+}
+
+// write writes a single feature. Features with invalid geometries are skipped;
+// a null geometry is written as a Null shape. It returns the error of
+// WriteChecked if the record doesn't fit the format.
+func (fw *featureWriter) write(feature *Feature) error {
+	if !fw.fieldsSet && feature.Properties != nil {
+		_ = fw.setFields(fw.c.createFieldsFromProperties(feature.Properties))
+	}
+
+	var shape Shape = &Null{}
+	if feature.Geometry != nil {
+		var err error
+		shape, err = fw.c.GeoJSONToShape(feature.Geometry, fw.shapeType)
 		if err != nil {
-			continue // Skip invalid geometries
+			return nil // Skip invalid geometries
+		}
+		if fw.writer.config != nil && fw.writer.config.SwapXY {
+			swapXY(shape)
+		}
+	}
+
+	row, err := fw.writer.WriteChecked(shape)
+	if err != nil {
+		return err
+	}
+
+	// Write attributes
+	for j, field := range fw.fields {
+		fieldName := field.String()
+		if value, exists := feature.Properties[fieldName]; exists {
+			_ = fw.writer.WriteAttribute(int(row), j, attributeValue(field, value))
 		}
+	}
+	return nil
+}
 
-		row := writer.Write(shape)
+// swapXY exchanges the X and Y coordinates of all points of shape in place
+// and updates its bounding box.
+func swapXY(shape Shape) {
+	swap := func(points []Point) {
+		for i := range points {
+			points[i].X, points[i].Y = points[i].Y, points[i].X
+		}
+	}
+	switch s := shape.(type) {
+	case *Point:
+		s.X, s.Y = s.Y, s.X
+	case *MultiPoint:
+		swap(s.Points)
+	case *PolyLine:
+		swap(s.Points)
+	case *Polygon:
+		swap(s.Points)
+	}
+	RecomputeBBox(shape)
+}
 
-		// Write attributes
-		for j, field := range fields {
-			fieldName := field.String()
-			if value, exists := feature.Properties[fieldName]; exists {
-				_ = writer.WriteAttribute(int(row), j, value)
+// WarnIfLikelySwapped checks whether the coordinates of geoJSON look like they
+// were authored as [lat, lon] instead of [lon, lat]: every X lies within the
+// latitude range while some Y lies outside of it. It returns a descriptive
+// error in that case and nil otherwise.
+func (c GeoJSONConverter) WarnIfLikelySwapped(geoJSON *GeoJSON) error {
+	var count int
+	xInLatRange, yOutOfLatRange := true, false
+	visit := func(x, y float64) {
+		count++
+		if math.Abs(x) > 90 {
+			xInLatRange = false
+		}
+		if math.Abs(y) > 90 && math.Abs(y) <= 180 {
+			yOutOfLatRange = true
+		}
+	}
+	if geoJSON.Geometry != nil {
+		walkPositions(geoJSON.Geometry, visit)
+	}
+	for _, f := range geoJSON.Features {
+		if f != nil && f.Geometry != nil {
+			walkPositions(f.Geometry, visit)
+		}
+	}
+	if count > 0 && xInLatRange && yOutOfLatRange {
+		return fmt.Errorf("coordinates are likely in [lat, lon] order: all %d X values are within ±90 while some Y values exceed it", count)
+	}
+	return nil
+}
+
+// walkPositions calls fn for every position of geom, including those of
+// nested geometry collections.
+func walkPositions(geom *Geometry, fn func(x, y float64)) {
+	walkCoordinates(geom.Coordinates, fn)
+	for _, g := range geom.Geometries {
+		if g != nil {
+			walkPositions(g, fn)
+		}
+	}
+}
+
+// walkCoordinates recursively visits the positions of a GeoJSON coordinates
+// value, which may be decoded JSON ([]interface{}) or typed float slices.
+func walkCoordinates(coords interface{}, fn func(x, y float64)) {
+	c := GeoJSONConverter{}
+	switch v := coords.(type) {
+	case []float64:
+		if len(v) >= 2 {
+			fn(v[0], v[1])
+		}
+	case [][]float64:
+		for _, p := range v {
+			walkCoordinates(p, fn)
+		}
+	case []interface{}:
+		if len(v) >= 2 {
+			x, errX := c.toFloat64(v[0])
+			y, errY := c.toFloat64(v[1])
+			if errX == nil && errY == nil {
+				fn(x, y)
+				return
+			}
+		}
+		for _, e := range v {
+			walkCoordinates(e, fn)
+		}
+	}
+}
+
+// ValidateGeoJSON checks the coordinate structure of every geometry in
+// geoJSON before it is converted to a shapefile: the nesting depth expected by
+// each geometry type, the minimum number of vertices of lines and rings, ring
+// closure and that all coordinates are finite numbers. Features with a null
+// geometry are valid. It returns one error per problem found, prefixed with
+// the index of the offending feature, or nil if the input is valid.
+func (c GeoJSONConverter) ValidateGeoJSON(geoJSON *GeoJSON) []error {
+	var errs []error
+	if geoJSON.Geometry != nil {
+		c.validateGeometry(geoJSON.Geometry, func(err error) {
+			errs = append(errs, fmt.Errorf("geometry: %v", err))
+		})
+	}
+	for i, f := range geoJSON.Features {
+		name := fmt.Sprintf("feature %d", i)
+		if f != nil && f.ID != nil {
+			name = fmt.Sprintf("feature %d (id %v)", i, f.ID)
+		}
+		report := func(err error) {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+		}
+		if f == nil {
+			report(fmt.Errorf("missing feature"))
+			continue
+		}
+		// a null geometry is valid GeoJSON and is imported as a Null shape
+		if f.Geometry != nil {
+			c.validateGeometry(f.Geometry, report)
+		}
+	}
+	return errs
+}
+
+// validateGeometry calls report for every structural problem of geom.
+func (c GeoJSONConverter) validateGeometry(geom *Geometry, report func(error)) {
+	if err := c.validateDepth(geom); err != nil {
+		report(err)
+		return
+	}
+	var err error
+	switch geom.Type {
+	case "Point":
+		err = c.validatePosition(geom.Coordinates)
+	case "MultiPoint":
+		err = c.validatePositions(geom.Coordinates, 0, false)
+	case "LineString":
+		err = c.validatePositions(geom.Coordinates, minLinePoints, false)
+	case "MultiLineString":
+		err = c.validateNested(geom.Coordinates, "line", func(v interface{}) error {
+			return c.validatePositions(v, minLinePoints, false)
+		})
+	case "Polygon":
+		err = c.validatePolygon(geom.Coordinates)
+	case "MultiPolygon":
+		err = c.validateNested(geom.Coordinates, "polygon", c.validatePolygon)
+	case "GeometryCollection":
+		for i, g := range geom.Geometries {
+			if g == nil {
+				report(fmt.Errorf("geometry %d: missing geometry", i))
+				continue
+			}
+			c.validateGeometry(g, func(err error) {
+				report(fmt.Errorf("geometry %d: %v", i, err))
+			})
+		}
+		return
+	default:
+		err = fmt.Errorf("unknown geometry type %q", geom.Type)
+	}
+	if err != nil {
+		report(fmt.Errorf("%s: %v", geom.Type, err))
+	}
+}
+
+// coordinateDepths is the nesting depth of the coordinates of each geometry
+// type, counting the position array of a Point as one level.
+var coordinateDepths = map[string]int{
+	"Point":           1,
+	"MultiPoint":      2,
+	"LineString":      2,
+	"MultiLineString": 3,
+	"Polygon":         3,
+	"MultiPolygon":    4,
+}
+
+// validateDepth checks that the coordinates of geom are nested as deep as its
+// type requires, e.g. that a Polygon is not given the coordinates of a
+// LineString. Unknown types and geometry collections are not checked.
+func (c GeoJSONConverter) validateDepth(geom *Geometry) error {
+	want, ok := coordinateDepths[geom.Type]
+	if !ok {
+		return nil
+	}
+	if got, ok := checkDepth(geom.Coordinates, want); !ok {
+		return fmt.Errorf("%s coordinates must be nested %d levels deep but are nested %d", geom.Type, want, got)
+	}
+	return nil
+}
+
+// checkDepth reports whether every element of coords is nested want levels
+// deep. Otherwise it returns the depth of the first element that isn't, which
+// is measured along its first elements. Empty arrays match any depth.
+func checkDepth(coords interface{}, want int) (int, bool) {
+	arr, ok := coordinateArray(coords)
+	if !ok {
+		return 0, want == 0
+	}
+	if want == 0 {
+		depth := 1
+		for len(arr) > 0 {
+			if arr, ok = coordinateArray(arr[0]); !ok {
+				break
 			}
+			depth++
 		}
+		return depth, false
 	}
+	for _, v := range arr {
+		if got, ok := checkDepth(v, want-1); !ok {
+			return got + 1, false
+		}
+	}
+	return want, true
+}
+
+// validatePolygon checks that coords is a list of closed linear rings.
+func (c GeoJSONConverter) validatePolygon(coords interface{}) error {
+	return c.validateNested(coords, "ring", func(v interface{}) error {
+		return c.validatePositions(v, minRingPoints, true)
+	})
+}
 
+// validateNested checks that coords is an array and validates each of its
+// elements with fn. name describes an element in error messages.
+func (c GeoJSONConverter) validateNested(coords interface{}, name string, fn func(interface{}) error) error {
+	arr, ok := coordinateArray(coords)
+	if !ok {
+		return fmt.Errorf("coordinates must be an array of %ss", name)
+	}
+	for i, v := range arr {
+		if err := fn(v); err != nil {
+			return fmt.Errorf("%s %d: %v", name, i, err)
+		}
+	}
 	return nil
 }
 
+// validatePositions checks that coords is an array of at least minPoints
+// positions. If closed is set, the first and last position must be equal.
+func (c GeoJSONConverter) validatePositions(coords interface{}, minPoints int, closed bool) error {
+	arr, ok := coordinateArray(coords)
+	if !ok {
+		return fmt.Errorf("coordinates must be an array of positions")
+	}
+	for i, v := range arr {
+		if err := c.validatePosition(v); err != nil {
+			return fmt.Errorf("position %d: %v", i, err)
+		}
+	}
+	if len(arr) < minPoints {
+		return fmt.Errorf("has %d positions, at least %d are required", len(arr), minPoints)
+	}
+	if closed && len(arr) > 0 {
+		first, _ := coordinateArray(arr[0])
+		last, _ := coordinateArray(arr[len(arr)-1])
+		for i := 0; i < 2; i++ {
+			x, _ := c.toFloat64(first[i])
+			y, _ := c.toFloat64(last[i])
+			if x != y {
+				return fmt.Errorf("ring is not closed")
+			}
+		}
+	}
+	return nil
+}
+
+// validatePosition checks that coords is a position of at least two finite
+// numbers.
+func (c GeoJSONConverter) validatePosition(coords interface{}) error {
+	arr, ok := coordinateArray(coords)
+	if !ok || len(arr) < 2 {
+		return fmt.Errorf("position must be an array of at least 2 numbers")
+	}
+	for _, v := range arr {
+		f, err := c.toFloat64(v)
+		if err != nil {
+			return fmt.Errorf("coordinate is not a number: %v", err)
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("coordinate %v is not finite", f)
+		}
+	}
+	return nil
+}
+
+// coordinateArray returns coords as a generic array. Besides decoded JSON it
+// accepts the typed float slices produced by ShapeToGeoJSON.
+func coordinateArray(coords interface{}) ([]interface{}, bool) {
+	switch v := coords.(type) {
+	case []interface{}:
+		return v, true
+	case []float64:
+		return toInterfaceSlice(len(v), func(i int) interface{} { return v[i] }), true
+	case [][]float64:
+		return toInterfaceSlice(len(v), func(i int) interface{} { return v[i] }), true
+	case [][][]float64:
+		return toInterfaceSlice(len(v), func(i int) interface{} { return v[i] }), true
+	case [][][][]float64:
+		return toInterfaceSlice(len(v), func(i int) interface{} { return v[i] }), true
+	default:
+		return nil, false
+	}
+}
+
+// toInterfaceSlice builds a slice of n elements returned by at.
+func toInterfaceSlice(n int, at func(int) interface{}) []interface{} {
+	s := make([]interface{}, n)
+	for i := range s {
+		s[i] = at(i)
+	}
+	return s
+}
+
 // determineShapeType determines the Shapefile shape type from GeoJSON geometry type
 func (c GeoJSONConverter) determineShapeType(geom *Geometry) (ShapeType, error) {
 	switch geom.Type {
@@ -427,38 +1275,124 @@ func (c GeoJSONConverter) determineShapeType(geom *Geometry) (ShapeType, error)
 	}
 }
 
-// createFieldsFromProperties creates DBF fields from GeoJSON properties
-func (c GeoJSONConverter) createFieldsFromProperties(properties map[string]interface{}) []Field {
-	var fields []Field
+// mergePropertyFields returns the DBF fields for the union of the properties
+// of features, sorted by property name. The field of a property fits all its
+// values: the fields for the values of the features are combined with
+// widenField as by MergeShapefiles, so integers and decimals give a decimal
+// field and conflicting types a character field.
+func mergePropertyFields(features []*Feature) []Field {
+	merged := make(map[string]Field)
+	nullOnly := make(map[string]bool)
+	for _, f := range features {
+		if f == nil {
+			continue
+		}
+		for name, value := range f.Properties {
+			existing, ok := merged[name]
+			if value == nil {
+				if !ok {
+					merged[name] = propertyField(name, nil)
+					nullOnly[name] = true
+				}
+				continue
+			}
+			field := propertyField(name, value)
+			if ok && !nullOnly[name] {
+				field = widenField(existing, field)
+			}
+			merged[name] = field
+			delete(nullOnly, name)
+		}
+	}
+	return sortedPropertyFields(merged)
+}
 
+// createFieldsFromProperties creates DBF fields from GeoJSON properties. The
+// fields are sorted by property name so that the same input always gives the
+// same DBF, whatever the iteration order of the map.
+func (c GeoJSONConverter) createFieldsFromProperties(properties map[string]interface{}) []Field {
+	fields := make(map[string]Field, len(properties))
 	for name, value := range properties {
-		if len(name) > 10 {
-			name = name[:10] // DBF field names are limited to 10 characters
+		fields[name] = propertyField(name, value)
+	}
+	return sortedPropertyFields(fields)
+}
+
+// sortedPropertyFields returns the fields of the properties in fields sorted
+// by property name.
+func sortedPropertyFields(fields map[string]Field) []Field {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sorted []Field
+	for _, name := range names {
+		sorted = append(sorted, fields[name])
+	}
+	return sorted
+}
+
+// propertyField returns the DBF field for the property name with the example
+// value.
+func propertyField(name string, value interface{}) Field {
+	if len(name) > 10 {
+		name = name[:10] // DBF field names are limited to 10 characters
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len(v)
+		if length > 254 {
+			length = 254 // Maximum string field length
 		}
+		return StringField(name, uint8(length))
+	case int, int32, int64:
+		return NumberField(name, 10)
+	case float32, float64:
+		return FloatField(name, 15, 6)
+	case bool:
+		return StringField(name, 1)
+	default:
+		return StringField(name, 50)
+	}
+}
 
+// attributeValue converts the property value to the type WriteAttribute
+// writes to field: booleans become "T" or "F", and numbers in a character
+// field, whose values also include strings, are written as text.
+func attributeValue(field Field, value interface{}) interface{} {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "T"
+		}
+		return "F"
+	case int32:
+		value = int(v)
+	case int64:
+		value = int(v)
+	case float32:
+		value = float64(v)
+	}
+	if field.Fieldtype == 'C' {
 		switch v := value.(type) {
-		case string:
-			length := len(v)
-			if length > 254 {
-				length = 254 // Maximum string field length
-			}
-			fields = append(fields, StringField(name, uint8(length)))
-		case int, int32, int64:
-			fields = append(fields, NumberField(name, 10))
-		case float32, float64:
-			fields = append(fields, FloatField(name, 15, 6))
-		case bool:
-			fields = append(fields, StringField(name, 1))
-		default:
-			fields = append(fields, StringField(name, 50))
+		case int:
+			return strconv.Itoa(v)
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
 		}
 	}
-
-	return fields
+	return value
 }
 
-// GeoJSONToShape converts a GeoJSON geometry to a Shape
+// GeoJSONToShape converts a GeoJSON geometry to a Shape. It is an error if
+// the coordinates aren't nested as deep as the geometry type requires.
 func (c GeoJSONConverter) GeoJSONToShape(geom *Geometry, _ ShapeType) (Shape, error) {
+	if err := c.validateDepth(geom); err != nil {
+		return nil, err
+	}
 	switch geom.Type {
 	case "Point":
 		return c.geoJSONPointToShape(geom)
@@ -470,6 +1404,8 @@ func (c GeoJSONConverter) GeoJSONToShape(geom *Geometry, _ ShapeType) (Shape, er
 		return c.geoJSONMultiLineStringToShape(geom)
 	case "Polygon":
 		return c.geoJSONPolygonToShape(geom)
+	case "MultiPolygon":
+		return c.geoJSONMultiPolygonToShape(geom)
 	default:
 		return nil, fmt.Errorf("unsupported geometry type: %s", geom.Type)
 	}
@@ -539,7 +1475,11 @@ func (c GeoJSONConverter) geoJSONLineStringToShape(geom *Geometry) (Shape, error
 		return nil, err
 	}
 
-	return NewPolyLine([][]Point{points}), nil
+	polyline, err := NewPolyLineChecked([][]Point{points})
+	if err != nil {
+		return nil, err
+	}
+	return polyline, nil
 }
 
 // geoJSONMultiLineStringToShape converts GeoJSON MultiLineString to Shape
@@ -563,7 +1503,11 @@ func (c GeoJSONConverter) geoJSONMultiLineStringToShape(geom *Geometry) (Shape,
 		parts = append(parts, points)
 	}
 
-	return NewPolyLine(parts), nil
+	polyline, err := NewPolyLineChecked(parts)
+	if err != nil {
+		return nil, err
+	}
+	return polyline, nil
 }
 
 // geoJSONPolygonToShape converts GeoJSON Polygon to Shape
@@ -573,6 +1517,48 @@ func (c GeoJSONConverter) geoJSONPolygonToShape(geom *Geometry) (Shape, error) {
 		return nil, fmt.Errorf("invalid Polygon coordinates")
 	}
 
+	parts, err := c.ringsToPoints(coords)
+	if err != nil {
+		return nil, err
+	}
+
+	polygon, err := NewPolygonChecked(parts)
+	if err != nil {
+		return nil, err
+	}
+	return polygon, nil
+}
+
+// geoJSONMultiPolygonToShape converts GeoJSON MultiPolygon to a Polygon with
+// the rings of all its polygons
+func (c GeoJSONConverter) geoJSONMultiPolygonToShape(geom *Geometry) (Shape, error) {
+	coords, ok := geom.Coordinates.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid MultiPolygon coordinates")
+	}
+
+	var parts [][]Point
+	for _, polygonCoords := range coords {
+		polygonCoordArr, ok := polygonCoords.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid MultiPolygon polygon coordinates")
+		}
+		rings, err := c.ringsToPoints(polygonCoordArr)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, rings...)
+	}
+
+	polygon, err := NewPolygonChecked(parts)
+	if err != nil {
+		return nil, err
+	}
+	return polygon, nil
+}
+
+// ringsToPoints converts the ring coordinate arrays of a polygon to points
+func (c GeoJSONConverter) ringsToPoints(coords []interface{}) ([][]Point, error) {
 	var parts [][]Point
 	for _, ringCoords := range coords {
 		ringCoordArr, ok := ringCoords.([]interface{})
@@ -586,15 +1572,7 @@ func (c GeoJSONConverter) geoJSONPolygonToShape(geom *Geometry) (Shape, error) {
 		}
 		parts = append(parts, points)
 	}
-
-	polyline := NewPolyLine(parts)
-	return &Polygon{
-		Box:       polyline.Box,
-		NumParts:  polyline.NumParts,
-		NumPoints: polyline.NumPoints,
-		Parts:     polyline.Parts,
-		Points:    polyline.Points,
-	}, nil
+	return parts, nil
 }
 
 // coordinatesToPoints converts coordinate arrays to Point slice
@@ -643,17 +1621,36 @@ func (c GeoJSONConverter) toFloat64(val interface{}) (float64, error) {
 // SaveGeoJSONToFile saves a GeoJSON object to a file
 func (c GeoJSONConverter) SaveGeoJSONToFile(geoJSON *GeoJSON, filename string, compact ...bool) error {
 	isCompact := len(compact) > 0 && compact[0]
+	return c.SaveGeoJSONToFileWithOptions(geoJSON, filename, isCompact)
+}
+
+// SaveGeoJSONToFileWithOptions is SaveGeoJSONToFile with reader options that
+// control the output. The file is written through a buffer of the size set with
+// WithBuffering, by default that of DefaultReaderConfig; WithBuffering(false, 0)
+// writes it directly.
+func (c GeoJSONConverter) SaveGeoJSONToFileWithOptions(geoJSON *GeoJSON, filename string, compact bool, opts ...ReaderOption) error {
+	config := DefaultReaderConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = file.Close() }()
 
-	enc := json.NewEncoder(file)
-	if !isCompact {
+	out, flush := bufferedOutput(file, config)
+	enc := json.NewEncoder(out)
+	if !compact {
 		enc.SetIndent("", "  ")
 	}
-	return enc.Encode(geoJSON)
+	if err := enc.Encode(geoJSON); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return file.Close()
 }
 
 // LoadGeoJSONFromFile loads a GeoJSON object from a file
@@ -687,56 +1684,191 @@ func writeFile(filename string, r io.Reader) error {
 // 适合超大文件，避免一次性构建全部 features 切片占用内存。
 // 可通过 ReaderOption（如 WithIgnoreCorruptedShapes(true)）控制读取行为。
 func (c GeoJSONConverter) ShapefileToGeoJSONStream(shpPath string, w io.Writer, opts ...ReaderOption) error {
+	return c.shapefileToGeoJSONStream(shpPath, w, nil, opts...)
+}
+
+// ShapefileToGeoJSONL 将 Shapefile 从第 startIndex 条记录（从 0 开始）起以 GeoJSONL 格式写出，
+// 即每行一个紧凑的 Feature，借助 .shx 直接定位到起始记录而无需读取之前的记录。
+// 无法转换的记录会被跳过。
+func (c GeoJSONConverter) ShapefileToGeoJSONL(shpPath string, w io.Writer, startIndex int, opts ...ReaderOption) error {
+	_, err := c.shapefileToGeoJSONL(shpPath, w, startIndex, nil, opts...)
+	return err
+}
+
+// ShapefileToGeoJSONLUnique 与 ShapefileToGeoJSONL 相同（从第一条记录开始），但跳过 id 已在 seen 中的 Feature，
+// 并把写出的 Feature 的 id 加入 seen，返回写出的 Feature 数。id 由 WithIDField 指定的字段得到，
+// seen 的键为 id 的 JSON 编码（见 GeoJSONLIDs）；没有 id 的 Feature 总会写出。
+func (c GeoJSONConverter) ShapefileToGeoJSONLUnique(shpPath string, w io.Writer, seen map[string]bool, opts ...ReaderOption) (int, error) {
+	return c.shapefileToGeoJSONL(shpPath, w, 0, seen, opts...)
+}
+
+// GeoJSONLIDs 读取 GeoJSONL 内容中所有 Feature 的 id，返回以 id 的 JSON 编码为键的集合，
+// 可作为 ShapefileToGeoJSONLUnique 的 seen 参数。空行被忽略，无法解析的行返回错误.
+func (c GeoJSONConverter) GeoJSONLIDs(r io.Reader) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			var feature struct {
+				ID interface{} `json:"id"`
+			}
+			if jerr := json.Unmarshal(data, &feature); jerr != nil {
+				return nil, fmt.Errorf("line %d: %w", line, jerr)
+			}
+			if key, ok := featureKey(feature.ID); ok {
+				ids[key] = true
+			}
+		}
+		if err == io.EOF {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// featureKey 返回 Feature id 的 JSON 编码，使数字 id 无论来自 DBF 还是已解析的 JSON 都得到相同的键
+func featureKey(id interface{}) (string, bool) {
+	if id == nil {
+		return "", false
+	}
+	data, err := json.Marshal(id)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// bufferedOutput 按 WithBuffering 的设置将转换的输出 w 包装为 bufio.Writer，
+// 使逐个 Feature 的小块写入合并为大块写入；关闭缓冲时直接返回 w。flush 将缓冲的数据写入 w
+func bufferedOutput(w io.Writer, config *ReaderConfig) (out io.Writer, flush func() error) {
+	if config == nil || !config.EnableBuffering || config.BufferSize <= 0 {
+		return w, func() error { return nil }
+	}
+	bw := bufio.NewWriterSize(w, config.BufferSize)
+	return bw, bw.Flush
+}
+
+// shapefileToGeoJSONL GeoJSONL 转换的实现，seen 为 nil 时不去重
+func (c GeoJSONConverter) shapefileToGeoJSONL(shpPath string, w io.Writer, startIndex int, seen map[string]bool, opts ...ReaderOption) (written int, err error) {
+	reader, err := OpenWithConfig(shpPath, DefaultReaderConfig(), opts...)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	if err := reader.SeekRecord(startIndex); err != nil {
+		return 0, err
+	}
+	out, flush := bufferedOutput(w, reader.config)
+	defer func() {
+		if flushErr := flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	fields := reader.Fields()
+	enc := json.NewEncoder(out) // Encode 在每个值后写入换行
+	for reader.Next() {
+		n, shape := reader.Shape()
+		features, err := c.readFeatures(reader, n, shape, fields)
+		if isAttributeError(err) {
+			return written, err
+		} else if err != nil {
+			continue
+		}
+		// the features exploded from a record share its id
+		key, hasKey := featureKey(features[0].ID)
+		if seen != nil && hasKey && seen[key] {
+			continue
+		}
+		for _, feature := range features {
+			if err := enc.Encode(feature); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if seen != nil && hasKey {
+			seen[key] = true
+		}
+	}
+	return written, reader.Err()
+}
+
+// ShapefileToGeoJSONStreamWithReport 与 ShapefileToGeoJSONStream 相同，但会用 validator
+// 校验每个 shape，跳过未通过校验或无法转换的记录，并返回这些记录的问题列表。
+// validator 为 nil 时使用 DefaultValidator。
+func (c GeoJSONConverter) ShapefileToGeoJSONStreamWithReport(shpPath string, w io.Writer, validator Validator, opts ...ReaderOption) ([]ValidationIssue, error) {
+	if validator == nil {
+		validator = &DefaultValidator{}
+	}
+	report := &streamReport{validator: validator}
+	err := c.shapefileToGeoJSONStream(shpPath, w, report, opts...)
+	return report.issues, err
+}
+
+// streamReport 收集流式转换过程中的记录问题
+type streamReport struct {
+	validator Validator
+	issues    []ValidationIssue
+}
+
+// shapefileToGeoJSONStream 流式转换的实现，report 为 nil 时不做校验
+//
+//nolint:gocyclo
+func (c GeoJSONConverter) shapefileToGeoJSONStream(shpPath string, w io.Writer, report *streamReport, opts ...ReaderOption) (err error) {
 	reader, err := OpenWithConfig(shpPath, DefaultReaderConfig(), opts...)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = reader.Close() }()
+	out, flush := bufferedOutput(w, reader.config)
+	defer func() {
+		if flushErr := flush(); err == nil {
+			err = flushErr
+		}
+	}()
 
 	fields := reader.Fields()
 
 	// 写入 FeatureCollection 头
-	if _, err := w.Write([]byte(`{"type":"FeatureCollection","features":[`)); err != nil {
+	if _, err := out.Write([]byte(`{"type":"FeatureCollection","features":[`)); err != nil {
 		return err
 	}
 
 	first := true
-	enc := json.NewEncoder(w)
+	enc := json.NewEncoder(out)
 
 	for reader.Next() {
 		n, shape := reader.Shape()
-		props := make(map[string]interface{}, len(fields))
-		for i, field := range fields {
-			attr := reader.ReadAttribute(n, i)
-			if attr == "" {
-				props[field.String()] = nil
+		if report != nil {
+			if err := report.validator.Validate(shape); err != nil {
+				report.issues = append(report.issues, ValidationIssue{Row: n, Err: err})
 				continue
 			}
-			if iVal, err := strconv.ParseInt(attr, 10, 64); err == nil {
-				props[field.String()] = iVal
-			} else if fVal, err := strconv.ParseFloat(attr, 64); err == nil {
-				props[field.String()] = fVal
-			} else if attr == boolTrue || attr == boolFalse {
-				props[field.String()] = (attr == boolTrue)
-			} else {
-				props[field.String()] = attr
-			}
 		}
-
-		feature, err := c.FeatureToGeoJSON(shape, props)
-		if err != nil {
+		features, err := c.readFeatures(reader, n, shape, fields)
+		if isAttributeError(err) {
+			return err
+		} else if err != nil {
+			if report != nil {
+				report.issues = append(report.issues, ValidationIssue{Row: n, Err: err})
+			}
 			continue
 		}
 
-		if !first {
-			if _, err := w.Write([]byte(",")); err != nil {
+		for _, feature := range features {
+			if !first {
+				if _, err := out.Write([]byte(",")); err != nil {
+					return err
+				}
+			}
+			first = false
+			if err := enc.Encode(feature); err != nil {
 				return err
 			}
 		}
-		first = false
-		if err := enc.Encode(feature); err != nil {
-			return err
-		}
 	}
 
 	if err := reader.Err(); err != nil {
@@ -744,6 +1876,6 @@ func (c GeoJSONConverter) ShapefileToGeoJSONStream(shpPath string, w io.Writer,
 	}
 
 	// 结尾
-	_, err = w.Write([]byte("]}"))
+	_, err = out.Write([]byte("]}"))
 	return err
 }
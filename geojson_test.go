@@ -1,10 +1,16 @@
 package shp_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/wangningkai/go-shp"
@@ -242,3 +248,1074 @@ func ExampleBatchConvertShapefilesToGeoJSON() {
 	//     log.Fatal(err)
 	// }
 }
+
+func TestConvertShapefileToGeoJSONWithReport(t *testing.T) {
+	dir := t.TempDir()
+	shpPath := dir + "/report.shp"
+	w, err := shp.Create(shpPath, shp.POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&shp.Point{X: 1, Y: 2})
+	w.Write(&shp.Point{X: math.NaN(), Y: 2})
+	w.Write(&shp.Point{X: 3, Y: 4})
+	w.Close()
+
+	issues, err := shp.ConvertShapefileToGeoJSONWithReport(shpPath, dir+"/report.geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Row != 1 {
+		t.Fatalf("unexpected issues: %v", issues)
+	}
+
+	data, err := os.ReadFile(dir + "/report.geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fc shp.GeoJSON
+	if err := json.Unmarshal(data, &fc); err != nil {
+		t.Fatalf("invalid GeoJSON output: %v", err)
+	}
+	if len(fc.Features) != 2 {
+		t.Errorf("got %d features, want 2", len(fc.Features))
+	}
+}
+
+func TestShapefileToGeoJSONWithIDField(t *testing.T) {
+	shpPath := t.TempDir() + "/ids.shp"
+	w, err := shp.Create(shpPath, shp.POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]shp.Field{shp.NumberField("FID", 3), shp.StringField("NAME", 1)}); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"a", "b"} {
+		row := w.Write(&shp.Point{X: float64(i), Y: float64(i)})
+		_ = w.WriteAttribute(int(row), 0, 100+i)
+		_ = w.WriteAttribute(int(row), 1, name)
+	}
+	w.Close()
+
+	// DBF field names are matched case-insensitively
+	for _, idField := range []string{"FID", "fid"} {
+		fc, err := shp.GeoJSONConverter{}.ShapefileToGeoJSONWithOptions(shpPath, shp.WithIDField(idField))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fc.Features) != 2 {
+			t.Fatalf("got %d features, want 2", len(fc.Features))
+		}
+		for i, f := range fc.Features {
+			if f.ID != int64(100+i) {
+				t.Errorf("%s: feature %d: got id %#v, want %d", idField, i, f.ID, 100+i)
+			}
+			if _, ok := f.Properties["FID"]; ok {
+				t.Errorf("%s: feature %d: id field still present in properties", idField, i)
+			}
+		}
+	}
+}
+
+func TestGeoJSONStreamToShapefile(t *testing.T) {
+	input := `{"type":"FeatureCollection","bbox":[0,0,3,3],"features":[
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"name":"a"}},
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[3,3]},"properties":{"name":"b"}}
+	]}`
+	shpPath := t.TempDir() + "/stream.shp"
+	if err := (shp.GeoJSONConverter{}).GeoJSONStreamToShapefile(strings.NewReader(input), shpPath); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := shp.Open(shpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var names []string
+	for r.Next() {
+		n, _ := r.Shape()
+		names = append(names, r.ReadAttribute(n, 0))
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("got names %v, want [a b]", names)
+	}
+
+	if err := (shp.GeoJSONConverter{}).GeoJSONStreamToShapefile(strings.NewReader(`{"type":"Feature"}`), shpPath); err == nil {
+		t.Error("converted a non-FeatureCollection without error")
+	}
+}
+
+func TestGeoJSONToShapefileLeadingNilProperties(t *testing.T) {
+	input := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[0,0]},"properties":null},
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[1,1]},"properties":{"name":"b"}},
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[2,2]},"properties":{"name":"longer"}}
+	]}`
+	var fc shp.GeoJSON
+	if err := json.Unmarshal([]byte(input), &fc); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	convert := map[string]func(string) error{
+		"in memory": func(path string) error { return (shp.GeoJSONConverter{}).GeoJSONToShapefile(&fc, path) },
+		"streaming": func(path string) error {
+			return (shp.GeoJSONConverter{}).GeoJSONStreamToShapefile(strings.NewReader(input), path)
+		},
+	}
+	for name, fn := range convert {
+		shpPath := dir + "/" + strings.ReplaceAll(name, " ", "_") + ".shp"
+		if err := fn(shpPath); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		r, err := shp.Open(shpPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for r.Next() {
+			n, _ := r.Shape()
+			v, err := r.ReadAttributeByName(n, "name")
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			names = append(names, v)
+		}
+		r.Close()
+		if len(names) != 3 || names[0] != "" || names[1] != "b" {
+			t.Errorf("%s: got names %q, want the attributes of the later features", name, names)
+		}
+		// only the whole collection shows the longest value up front
+		if name == "in memory" && names[2] != "longer" {
+			t.Errorf("%s: got %q for the longest value", name, names[2])
+		}
+	}
+}
+
+func TestGeoJSONToShapefileWidensFields(t *testing.T) {
+	point := func(x float64, properties map[string]interface{}) *shp.Feature {
+		return &shp.Feature{Type: "Feature", Properties: properties,
+			Geometry: &shp.Geometry{Type: "Point", Coordinates: []interface{}{x, 0.0}}}
+	}
+	fc := &shp.GeoJSON{Type: "FeatureCollection", Features: []*shp.Feature{
+		point(0, map[string]interface{}{"count": 1, "code": 3.5, "flag": true}),
+		point(1, map[string]interface{}{"count": 2.25, "code": "A12", "flag": "maybe"}),
+	}}
+	shpPath := t.TempDir() + "/widened.shp"
+	if err := (shp.GeoJSONConverter{}).GeoJSONToShapefile(fc, shpPath); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := shp.Open(shpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	types := map[string]byte{}
+	for _, f := range r.Fields() {
+		types[f.String()] = f.Fieldtype
+	}
+	// integer then decimal gives a decimal field, number or boolean then
+	// string a character field
+	if want := map[string]byte{"count": 'F', "code": 'C', "flag": 'C'}; !reflect.DeepEqual(types, want) {
+		t.Errorf("got field types %q, want %q", types, want)
+	}
+	want := [][3]string{{"1", "3.5", "T"}, {"2.25", "A12", "maybe"}}
+	for row, values := range want {
+		for i, name := range []string{"count", "code", "flag"} {
+			got, err := r.ReadAttributeByName(row, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name == "count" {
+				if f, err := strconv.ParseFloat(got, 64); err == nil {
+					got = strconv.FormatFloat(f, 'f', -1, 64)
+				}
+			}
+			if got != values[i] {
+				t.Errorf("row %d: got %s %q, want %q", row, name, got, values[i])
+			}
+		}
+	}
+}
+
+func TestSwapXYOnImport(t *testing.T) {
+	fc := &shp.GeoJSON{
+		Type: "FeatureCollection",
+		Features: []*shp.Feature{{
+			Type:       "Feature",
+			Geometry:   &shp.Geometry{Type: "Point", Coordinates: []interface{}{37.77, -122.42}},
+			Properties: map[string]interface{}{},
+		}},
+	}
+	c := shp.GeoJSONConverter{}
+	if err := c.WarnIfLikelySwapped(fc); err == nil {
+		t.Error("swapped coordinates not detected")
+	}
+
+	shpPath := t.TempDir() + "/swapped.shp"
+	if err := c.GeoJSONToShapefile(fc, shpPath, shp.WithSwapXY(true)); err != nil {
+		t.Fatal(err)
+	}
+	r, err := shp.Open(shpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if !r.Next() {
+		t.Fatal("no shape written")
+	}
+	_, s := r.Shape()
+	if p := s.(*shp.Point); p.X != -122.42 || p.Y != 37.77 {
+		t.Errorf("got point %v, want swapped coordinates", p)
+	}
+
+	fc.Features[0].Geometry.Coordinates = []interface{}{-122.42, 37.77}
+	if err := c.WarnIfLikelySwapped(fc); err != nil {
+		t.Errorf("correct coordinates flagged: %v", err)
+	}
+}
+
+func TestNullAttributePolicy(t *testing.T) {
+	shpPath := t.TempDir() + "/nulls.shp"
+	w, err := shp.Create(shpPath, shp.POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]shp.Field{shp.StringField("NAME", 5)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&shp.Point{X: 1, Y: 1})
+	w.Close()
+
+	tests := []struct {
+		policy    shp.NullAttributePolicy
+		want      interface{}
+		wantExist bool
+	}{
+		{shp.NullAsNull, nil, true},
+		{shp.NullAsEmptyString, "", true},
+		{shp.NullOmit, nil, false},
+	}
+	for _, test := range tests {
+		fc, err := shp.GeoJSONConverter{}.ShapefileToGeoJSONWithOptions(shpPath, shp.WithNullAttributePolicy(test.policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, exists := fc.Features[0].Properties["NAME"]
+		if exists != test.wantExist || got != test.want {
+			t.Errorf("policy %d: got %#v (present: %v), want %#v (present: %v)",
+				test.policy, got, exists, test.want, test.wantExist)
+		}
+	}
+}
+
+func TestValidateGeoJSON(t *testing.T) {
+	const input = `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2]}, "properties": {}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1]}, "properties": {}},
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0]]}, "properties": {}},
+		{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1]]]}, "properties": {}},
+		{"type": "Feature", "id": "shallow", "geometry": {"type": "Polygon", "coordinates": [[0, 0], [1, 0], [1, 1], [0, 0]]}, "properties": {}},
+		{"type": "Feature", "geometry": {"type": "MultiPoint", "coordinates": [[0, "NaN"]]}, "properties": {}},
+		{"type": "Feature", "geometry": null, "properties": {}},
+		{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}, "properties": {}},
+		null
+	]}`
+	var geoJSON shp.GeoJSON
+	if err := json.Unmarshal([]byte(input), &geoJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := shp.GeoJSONConverter{}.ValidateGeoJSON(&geoJSON)
+	want := []string{
+		"feature 1: Point: position must be an array of at least 2 numbers",
+		"feature 2: LineString: has 1 positions, at least 2 are required",
+		"feature 3: Polygon: ring 0: ring is not closed",
+		"feature 4 (id shallow): Polygon coordinates must be nested 3 levels deep but are nested 2",
+		"feature 5: MultiPoint: position 0: coordinate NaN is not finite",
+		"feature 8: missing feature",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("error %d: got %q, want %q", i, err, want[i])
+		}
+	}
+
+	conv := shp.GeoJSONConverter{}
+	for _, test := range []struct {
+		geom string
+		want string
+	}{
+		{`{"type": "Point", "coordinates": [[1, 2]]}`, "Point coordinates must be nested 1 levels deep but are nested 2"},
+		{`{"type": "LineString", "coordinates": [[0, 0], 1]}`, "LineString coordinates must be nested 2 levels deep but are nested 1"},
+		{`{"type": "Polygon", "coordinates": [[0, 0], [1, 0], [1, 1], [0, 0]]}`, "Polygon coordinates must be nested 3 levels deep but are nested 2"},
+		{`{"type": "MultiPolygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}`, "MultiPolygon coordinates must be nested 4 levels deep but are nested 3"},
+		{`{"type": "MultiLineString", "coordinates": [[[0, 0], [1, 1]], [[[2, 2]]]]}`, "MultiLineString coordinates must be nested 3 levels deep but are nested 4"},
+	} {
+		var geom shp.Geometry
+		if err := json.Unmarshal([]byte(test.geom), &geom); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conv.GeoJSONToShape(&geom, shp.NULL); err == nil || err.Error() != test.want {
+			t.Errorf("%s: got error %v, want %q", test.geom, err, test.want)
+		}
+	}
+
+	geom, err := conv.ShapeToGeoJSON(shp.NewPolygon([][]shp.Point{{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}, {X: 0, Y: 0}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := conv.ValidateGeoJSON(&shp.GeoJSON{Type: "Feature", Geometry: geom}); errs != nil {
+		t.Errorf("unexpected errors for converted shape: %v", errs)
+	}
+}
+
+func TestShapefileToGeoJSONDecodesAttributes(t *testing.T) {
+	dir := t.TempDir()
+	shpPath := dir + "/latin1.shp"
+	w, err := shp.Create(shpPath, shp.POINT, shp.WithStringEncoding("ISO-8859-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]shp.Field{shp.StringField("NAME", 10)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&shp.Point{X: 1, Y: 1})
+	if err := w.WriteAttribute(0, 0, "Zürich"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	name := func(opts ...shp.ReaderOption) interface{} {
+		fc, err := shp.GeoJSONConverter{}.ShapefileToGeoJSONWithOptions(shpPath, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fc.Features[0].Properties["NAME"]
+	}
+
+	// encoding from the .cpg file
+	if got := name(); got != "Zürich" {
+		t.Errorf("with .cpg: got %q, want Zürich", got)
+	}
+	// encoding from the language driver ID of the DBF header
+	if err := os.Remove(dir + "/latin1.cpg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := name(); got != "Zürich" {
+		t.Errorf("without .cpg: got %q, want Zürich", got)
+	}
+	// an explicit encoding overrides the detection; the invalid UTF-8 byte is
+	// replaced rather than passed through
+	if got := name(shp.WithAttributeEncoding("UTF-8")); got != "Z�rich" {
+		t.Errorf("with UTF-8 override: got %q", got)
+	}
+}
+
+func TestShapefileToGeoJSONUnsupportedEncoding(t *testing.T) {
+	dir := t.TempDir()
+	shpPath := dir + "/gbk.shp"
+	w, err := shp.Create(shpPath, shp.POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]shp.Field{shp.StringField("NAME", 10)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&shp.Point{X: 1, Y: 1})
+	if err := w.WriteAttribute(0, 0, "ABCD"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	// store "中文" in GBK and mark the DBF as code page 936
+	dbf, err := os.ReadFile(dir + "/gbk.dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := bytes.Index(dbf, []byte("ABCD"))
+	if i < 0 {
+		t.Fatal("attribute not found in DBF")
+	}
+	copy(dbf[i:], "\xd6\xd0\xce\xc4")
+	dbf[29] = 0x4d
+	if err := os.WriteFile(dir+"/gbk.dbf", dbf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(dir + "/gbk.cpg")
+
+	fc, err := shp.GeoJSONConverter{}.ShapefileToGeoJSON(shpPath)
+	if !errors.Is(err, shp.NewShapeError(shp.ErrUnsupportedType, "", nil)) {
+		t.Fatalf("got %v, %v; want an unsupported encoding error", fc, err)
+	}
+	if !strings.Contains(err.Error(), `"936"`) {
+		t.Errorf("error %q does not name the encoding", err)
+	}
+
+	// the raw bytes stay available
+	r, err := shp.Open(shpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got := r.ReadAttribute(0, 0); got != "\xd6\xd0\xce\xc4" {
+		t.Errorf("ReadAttribute = %q", got)
+	}
+}
+
+func TestConvertShapefileToGeoJSONLFrom(t *testing.T) {
+	dir := t.TempDir()
+	shpPath := dir + "/points.shp"
+	w, err := shp.Create(shpPath, shp.POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]shp.Field{shp.NumberField("ID", 5)}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		w.Write(&shp.Point{X: float64(i), Y: float64(i)})
+		if err := w.WriteAttribute(i, 0, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	ids := func(path string) []string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			var f shp.Feature
+			if err := json.Unmarshal([]byte(line), &f); err != nil {
+				t.Fatalf("invalid line %q: %v", line, err)
+			}
+			ids = append(ids, fmt.Sprint(f.Properties["ID"]))
+		}
+		return ids
+	}
+
+	full := dir + "/full.geojsonl"
+	if err := shp.ConvertShapefileToGeoJSONL(shpPath, full); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"0", "1", "2", "3", "4"}
+	if got := ids(full); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("full conversion: got ids %v, want %v", got, want)
+	}
+
+	// simulate a conversion that crashed while writing the fourth line
+	data, err := os.ReadFile(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	partial := dir + "/partial.geojsonl"
+	if err := os.WriteFile(partial, []byte(strings.Join(lines[:3], "")+lines[3][:10]), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := shp.ConvertShapefileToGeoJSONLFrom(shpPath, partial, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(partial); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("resumed conversion: got ids %v, want %v", got, want)
+	}
+
+	if err := shp.ConvertShapefileToGeoJSONLFrom(shpPath, partial, 6); err == nil {
+		t.Error("expected error for start index beyond the last record")
+	}
+}
+
+func TestAppendShapefileToGeoJSONL(t *testing.T) {
+	dir := t.TempDir()
+	create := func(name string, ids ...int) string {
+		shpPath := dir + "/" + name + ".shp"
+		w, err := shp.Create(shpPath, shp.POINT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.SetFields([]shp.Field{shp.NumberField("ID", 5)}); err != nil {
+			t.Fatal(err)
+		}
+		for i, id := range ids {
+			w.Write(&shp.Point{X: float64(id), Y: float64(id)})
+			if err := w.WriteAttribute(i, 0, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.Close()
+		return shpPath
+	}
+	first := create("first", 0, 1, 2)
+	second := create("second", 2, 3, 3, 4)
+
+	out := dir + "/out.geojsonl"
+	for _, tc := range []struct {
+		shpPath string
+		want    int
+	}{
+		{first, 3},
+		{first, 0}, // re-running the import is a no-op
+		{second, 2},
+	} {
+		n, err := shp.AppendShapefileToGeoJSONL(tc.shpPath, out, "ID")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != tc.want {
+			t.Errorf("%s: appended %d features, want %d", tc.shpPath, n, tc.want)
+		}
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var f shp.Feature
+		if err := json.Unmarshal([]byte(line), &f); err != nil {
+			t.Fatalf("invalid line %q: %v", line, err)
+		}
+		ids = append(ids, fmt.Sprint(f.ID))
+	}
+	if got, want := strings.Join(ids, ","), "0,1,2,3,4"; got != want {
+		t.Errorf("got ids %s, want %s", got, want)
+	}
+}
+
+func TestRFC7946Winding(t *testing.T) {
+	shpPath := t.TempDir() + "/winding.shp"
+	w, err := shp.Create(shpPath, shp.POLYGON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// shapefile winding: clockwise exterior, counter-clockwise hole
+	w.Write(shp.NewPolygon([][]shp.Point{
+		{{X: 0, Y: 0}, {X: 0, Y: 4}, {X: 4, Y: 4}, {X: 4, Y: 0}, {X: 0, Y: 0}},
+		{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 2}, {X: 1, Y: 2}, {X: 1, Y: 1}},
+	}))
+	w.Close()
+
+	signedArea := func(ring [][]float64) float64 {
+		area := 0.0
+		for i := range ring {
+			j := (i + 1) % len(ring)
+			area += ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+		}
+		return area
+	}
+	rings := func(opts ...shp.ReaderOption) []interface{} {
+		fc, err := shp.GeoJSONConverter{}.ShapefileToGeoJSONWithOptions(shpPath, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fc.Features[0].Geometry.Coordinates.([]interface{})
+	}
+
+	def := rings()
+	if signedArea(def[0].([][]float64)) > 0 || signedArea(def[1].([][]float64)) < 0 {
+		t.Errorf("default output should keep the shapefile winding")
+	}
+	rfc := rings(shp.WithRFC7946Winding(true))
+	if signedArea(rfc[0].([][]float64)) < 0 {
+		t.Errorf("exterior ring is not counter-clockwise: %v", rfc[0])
+	}
+	if signedArea(rfc[1].([][]float64)) > 0 {
+		t.Errorf("hole is not clockwise: %v", rfc[1])
+	}
+}
+
+func TestElevationProperty(t *testing.T) {
+	const shpPath = "test_files/pointz.shp"
+	r, err := shp.Open(shpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Next()
+	_, shape := r.Shape()
+	p := shape.(*shp.PointZ)
+	r.Close()
+
+	tests := []struct {
+		policy     shp.ElevationPolicy
+		wantCoords []float64
+		wantProps  bool
+	}{
+		{shp.ElevationInCoordinates, []float64{p.X, p.Y, p.Z}, false},
+		{shp.ElevationAlsoProperty, []float64{p.X, p.Y, p.Z}, true},
+		{shp.ElevationPropertyOnly, []float64{p.X, p.Y}, true},
+	}
+	for _, test := range tests {
+		fc, err := shp.GeoJSONConverter{}.ShapefileToGeoJSONWithOptions(shpPath, shp.WithElevationProperty(test.policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := fc.Features[0]
+		if got := fmt.Sprint(f.Geometry.Coordinates); got != fmt.Sprint(test.wantCoords) {
+			t.Errorf("policy %d: got coordinates %s, want %v", test.policy, got, test.wantCoords)
+		}
+		elevation, hasElevation := f.Properties["elevation"]
+		measure, hasMeasure := f.Properties["measure"]
+		if hasElevation != test.wantProps || hasMeasure != test.wantProps {
+			t.Errorf("policy %d: got properties %v", test.policy, f.Properties)
+		}
+		if test.wantProps && (elevation != p.Z || measure != p.M) {
+			t.Errorf("policy %d: got elevation %v and measure %v, want %v and %v",
+				test.policy, elevation, measure, p.Z, p.M)
+		}
+	}
+}
+
+func TestGeoJSONDeterministicOutput(t *testing.T) {
+	dir := t.TempDir()
+	shpPath := dir + "/stable.shp"
+	w, err := shp.Create(shpPath, shp.POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := []shp.Field{shp.StringField("ZETA", 10), shp.StringField("ALPHA", 10), shp.NumberField("MID", 5)}
+	if err := w.SetFields(fields); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		w.Write(&shp.Point{X: float64(i), Y: float64(-i)})
+		_ = w.WriteAttribute(i, 0, fmt.Sprintf("z%d", i))
+		_ = w.WriteAttribute(i, 1, fmt.Sprintf("a%d", i))
+		_ = w.WriteAttribute(i, 2, i)
+	}
+	w.Close()
+
+	encode := func() []byte {
+		fc, err := shp.GeoJSONConverter{}.ShapefileToGeoJSON(shpPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(fc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	first := encode()
+	for i := 0; i < 10; i++ {
+		if got := encode(); string(got) != string(first) {
+			t.Fatalf("run %d differs:\n%s\n%s", i, got, first)
+		}
+	}
+	if !strings.Contains(string(first), `"properties":{"ALPHA":"a0","MID":0,"ZETA":"z0"}`) {
+		t.Errorf("properties not sorted by key: %s", first)
+	}
+	if !strings.Contains(string(first), `"coordinates":[0,0]`) ||
+		strings.Index(string(first), `"z0"`) > strings.Index(string(first), `"z1"`) {
+		t.Errorf("features not in record order: %s", first)
+	}
+
+	// the DBF fields created from the properties are sorted by name
+	fc, err := shp.GeoJSONConverter{}.ShapefileToGeoJSON(shpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		out := fmt.Sprintf("%s/roundtrip%d.shp", dir, i)
+		if err := (shp.GeoJSONConverter{}).GeoJSONToShapefile(fc, out); err != nil {
+			t.Fatal(err)
+		}
+		r, err := shp.Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, f := range r.Fields() {
+			names = append(names, f.String())
+		}
+		r.Close()
+		if got := strings.Join(names, ","); got != "ALPHA,MID,ZETA" {
+			t.Errorf("fields %s, want ALPHA,MID,ZETA", got)
+		}
+	}
+}
+
+func TestExplodeMultipart(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, shapeType shp.ShapeType, shape shp.Shape) string {
+		path := dir + "/" + name + ".shp"
+		w, err := shp.Create(path, shapeType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.SetFields([]shp.Field{shp.StringField("NAME", 10)}); err != nil {
+			t.Fatal(err)
+		}
+		w.Write(shape)
+		_ = w.WriteAttribute(0, 0, name)
+		w.Close()
+		return path
+	}
+	convert := func(path string, opts ...shp.ReaderOption) []*shp.Feature {
+		fc, err := shp.GeoJSONConverter{}.ShapefileToGeoJSONWithOptions(path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fc.Features
+	}
+
+	// two outer rings, the first with a hole
+	polygon := write("polygon", shp.POLYGON, shp.NewPolygon([][]shp.Point{
+		{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}},
+		{{20, 0}, {20, 5}, {25, 5}, {25, 0}, {20, 0}},
+		{{2, 2}, {4, 2}, {4, 4}, {2, 4}, {2, 2}},
+	}))
+	if got := convert(polygon); len(got) != 1 {
+		t.Fatalf("got %d features without exploding, want 1", len(got))
+	}
+	features := convert(polygon, shp.WithExplodeMultipart(true))
+	if len(features) != 2 {
+		t.Fatalf("got %d polygon features, want 2", len(features))
+	}
+	for i, wantRings := range []int{2, 1} {
+		f := features[i]
+		rings, _ := f.Geometry.Coordinates.([]interface{})
+		if f.Geometry.Type != "Polygon" || len(rings) != wantRings {
+			t.Errorf("feature %d: %s with %d rings, want Polygon with %d", i, f.Geometry.Type, len(rings), wantRings)
+		}
+		if f.Properties["NAME"] != "polygon" {
+			t.Errorf("feature %d: properties %v", i, f.Properties)
+		}
+	}
+	features[0].Properties["NAME"] = "changed"
+	if features[1].Properties["NAME"] != "polygon" {
+		t.Error("exploded features share their properties")
+	}
+
+	// with RFC 7946 winding every exploded exterior is counter-clockwise
+	for i, f := range convert(polygon, shp.WithExplodeMultipart(true), shp.WithRFC7946Winding(true)) {
+		exterior := f.Geometry.Coordinates.([]interface{})[0].([][]float64)
+		area := 0.0
+		for k := 0; k+1 < len(exterior); k++ {
+			area += exterior[k][0]*exterior[k+1][1] - exterior[k+1][0]*exterior[k][1]
+		}
+		if area <= 0 {
+			t.Errorf("feature %d: exterior is not counter-clockwise", i)
+		}
+	}
+
+	line := write("line", shp.POLYLINE, shp.NewPolyLine([][]shp.Point{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}, {{4, 4}, {5, 5}}}))
+	features = convert(line, shp.WithExplodeMultipart(true))
+	if len(features) != 3 {
+		t.Fatalf("got %d line features, want 3", len(features))
+	}
+	for i, f := range features {
+		if f.Geometry.Type != "LineString" || f.Properties["NAME"] != "line" {
+			t.Errorf("feature %d: %s %v", i, f.Geometry.Type, f.Properties)
+		}
+	}
+
+	points := write("points", shp.MULTIPOINT, &shp.MultiPoint{
+		Box:       shp.Box{MinX: 0, MinY: 0, MaxX: 1, MaxY: 1},
+		NumPoints: 2,
+		Points:    []shp.Point{{X: 0, Y: 0}, {X: 1, Y: 1}},
+	})
+	var buf strings.Builder
+	if err := (shp.GeoJSONConverter{}).ShapefileToGeoJSONL(points, &buf, 0, shp.WithExplodeMultipart(true)); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 ||
+		!strings.Contains(lines[1], `"coordinates":[1,1]`) || !strings.Contains(lines[1], `"type":"Point"`) {
+		t.Errorf("unexpected GeoJSONL:\n%s", buf.String())
+	}
+}
+
+// countingWriter counts the calls to Write.
+type countingWriter struct {
+	strings.Builder
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Builder.Write(p)
+}
+
+func TestGeoJSONStreamBuffering(t *testing.T) {
+	shpPath := t.TempDir() + "/many.shp"
+	w, err := shp.Create(shpPath, shp.POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		w.Write(&shp.Point{X: float64(i), Y: float64(i)})
+	}
+	w.Close()
+
+	conv := shp.GeoJSONConverter{}
+	for _, tc := range []struct {
+		name      string
+		geojsonl  bool
+		opts      []shp.ReaderOption
+		maxWrites int
+		minWrites int
+	}{
+		{"stream buffered", false, nil, 1, 1},
+		{"stream unbuffered", false, []shp.ReaderOption{shp.WithBuffering(false, 0)}, 1 << 30, 100},
+		{"geojsonl buffered", true, nil, 1, 1},
+		{"geojsonl small buffer", true, []shp.ReaderOption{shp.WithBuffering(true, 64)}, 1 << 30, 50},
+	} {
+		var out countingWriter
+		if tc.geojsonl {
+			err = conv.ShapefileToGeoJSONL(shpPath, &out, 0, tc.opts...)
+		} else {
+			err = conv.ShapefileToGeoJSONStream(shpPath, &out, tc.opts...)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if out.writes < tc.minWrites || out.writes > tc.maxWrites {
+			t.Errorf("%s: %d writes, want between %d and %d", tc.name, out.writes, tc.minWrites, tc.maxWrites)
+		}
+		if !strings.Contains(out.String(), `"coordinates":[99,99]`) {
+			t.Errorf("%s: output incomplete", tc.name)
+		}
+	}
+
+	// the file writer flushes its buffer
+	fc, err := conv.ShapefileToGeoJSON(shpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	save := map[string]func(string) error{
+		"default": func(out string) error { return conv.SaveGeoJSONToFile(fc, out, true) },
+		"small buffer": func(out string) error {
+			return conv.SaveGeoJSONToFileWithOptions(fc, out, true, shp.WithBuffering(true, 64))
+		},
+		"unbuffered": func(out string) error {
+			return conv.SaveGeoJSONToFileWithOptions(fc, out, false, shp.WithBuffering(false, 0))
+		},
+	}
+	for name, fn := range save {
+		out := t.TempDir() + "/many.geojson"
+		if err := fn(out); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := conv.LoadGeoJSONFromFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(loaded.Features) != 100 {
+			t.Errorf("%s: saved %d features, want 100", name, len(loaded.Features))
+		}
+	}
+}
+
+func TestNullGeometryRecords(t *testing.T) {
+	dir := t.TempDir()
+	shpPath := dir + "/parcels.shp"
+	w, err := shp.Create(shpPath, shp.POLYGON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]shp.Field{shp.StringField("NAME", 8)})
+	square := func(x float64) shp.Shape {
+		return shp.NewPolygon([][]shp.Point{{{X: x, Y: 0}, {X: x, Y: 2}, {X: x + 2, Y: 2}, {X: x + 2, Y: 0}, {X: x, Y: 0}}})
+	}
+	for i, shape := range []shp.Shape{&shp.Null{}, square(0), &shp.Null{}, square(10)} {
+		w.Write(shape)
+		_ = w.WriteAttribute(i, 0, fmt.Sprintf("p%d", i))
+	}
+	w.Close()
+
+	conv := shp.GeoJSONConverter{}
+	fc, err := conv.ShapefileToGeoJSON(shpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Features) != 4 {
+		t.Fatalf("got %d features, want 4", len(fc.Features))
+	}
+	for i, f := range fc.Features {
+		if wantNull := i%2 == 0; (f.Geometry == nil) != wantNull || f.Properties["NAME"] != fmt.Sprintf("p%d", i) {
+			t.Errorf("feature %d: geometry %v, properties %v", i, f.Geometry, f.Properties)
+		}
+	}
+
+	var buf strings.Builder
+	if err := conv.ShapefileToGeoJSONStream(shpPath, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded shp.GeoJSON
+	if err := json.Unmarshal([]byte(buf.String()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	convert := map[string]func(string) error{
+		"in memory": func(path string) error { return conv.GeoJSONToShapefile(&decoded, path) },
+		"streaming": func(path string) error {
+			return conv.GeoJSONStreamToShapefile(strings.NewReader(buf.String()), path)
+		},
+	}
+	for name, fn := range convert {
+		out := dir + "/" + strings.ReplaceAll(name, " ", "_") + ".shp"
+		if err := fn(out); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		r, err := shp.Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.GeometryType != shp.POLYGON {
+			t.Errorf("%s: shape type %s, want POLYGON", name, r.GeometryType)
+		}
+		var types []shp.ShapeType
+		var names []string
+		for r.Next() {
+			n, shape := r.Shape()
+			_, isNull := shape.(*shp.Null)
+			_, isPolygon := shape.(*shp.Polygon)
+			switch {
+			case isNull:
+				types = append(types, shp.NULL)
+			case isPolygon:
+				types = append(types, shp.POLYGON)
+			}
+			names = append(names, strings.TrimSpace(r.ReadAttribute(n, 0)))
+		}
+		r.Close()
+		if fmt.Sprint(types) != fmt.Sprint([]shp.ShapeType{shp.NULL, shp.POLYGON, shp.NULL, shp.POLYGON}) ||
+			strings.Join(names, ",") != "p0,p1,p2,p3" {
+			t.Errorf("%s: got types %v, names %v", name, types, names)
+		}
+	}
+
+	stats, err := shp.StatisticsUtils{}.AnalyzeShapefile(shpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalShapes != 4 || stats.ShapeTypes[shp.POLYGON] != 2 || stats.ShapeTypes[shp.NULL] != 2 {
+		t.Errorf("got shape types %v of %d shapes", stats.ShapeTypes, stats.TotalShapes)
+	}
+	if stats.TotalArea != 8 || stats.AverageArea != 4 || stats.AveragePerimeter != 8 {
+		t.Errorf("got total area %v, average area %v, average perimeter %v; want 8, 4, 8",
+			stats.TotalArea, stats.AverageArea, stats.AveragePerimeter)
+	}
+
+	// a collection without any geometry gives a NULL shapefile
+	nulls := `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":null,"properties":{"name":"x"}}]}`
+	out := dir + "/nulls.shp"
+	if err := conv.GeoJSONStreamToShapefile(strings.NewReader(nulls), out); err != nil {
+		t.Fatal(err)
+	}
+	r, err := shp.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.GeometryType != shp.NULL || !r.Next() {
+		t.Errorf("got shape type %s", r.GeometryType)
+	}
+}
+
+func TestAmbiguousRingPolicy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, rings [][]shp.Point) string {
+		path := dir + "/" + name + ".shp"
+		w, err := shp.Create(path, shp.POLYGON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(shp.NewPolygon(rings))
+		w.Close()
+		return path
+	}
+	// ring sizes of every polygon of the only feature, or nil without a feature
+	polygons := func(path string, opts ...shp.ReaderOption) []int {
+		fc, err := shp.GeoJSONConverter{}.ShapefileToGeoJSONWithOptions(path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fc.Features) == 0 {
+			return nil
+		}
+		geom := fc.Features[0].Geometry
+		coords := geom.Coordinates.([]interface{})
+		if geom.Type == "Polygon" {
+			coords = []interface{}{coords}
+		}
+		var got []int
+		for _, polygon := range coords {
+			got = append(got, len(polygon.([]interface{})))
+		}
+		if (geom.Type == "Polygon") != (len(got) == 1) {
+			t.Errorf("%s with %d polygons", geom.Type, len(got))
+		}
+		return got
+	}
+
+	// all clockwise: a square, a hole inside it and an island
+	ambiguous := write("ambiguous", [][]shp.Point{
+		{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}},
+		{{20, 0}, {20, 5}, {25, 5}, {25, 0}, {20, 0}},
+		{{2, 2}, {2, 4}, {4, 4}, {4, 2}, {2, 2}},
+	})
+	for _, tc := range []struct {
+		name string
+		opts []shp.ReaderOption
+		want []int
+	}{
+		{"default", nil, []int{2, 1}},
+		{"largest as outer", []shp.ReaderOption{shp.WithAmbiguousRingPolicy(shp.LargestAsOuter)}, []int{2, 1}},
+		{"all outer", []shp.ReaderOption{shp.WithAmbiguousRingPolicy(shp.TreatAllAsOuter)}, []int{1, 1, 1}},
+		{"error", []shp.ReaderOption{shp.WithAmbiguousRingPolicy(shp.AmbiguousRingsError)}, nil},
+	} {
+		if got := polygons(ambiguous, tc.opts...); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: got polygons with %v rings, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// islands with the same winding are not ambiguous
+	islands := write("islands", [][]shp.Point{
+		{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}},
+		{{20, 0}, {20, 5}, {25, 5}, {25, 0}, {20, 0}},
+	})
+	if got := polygons(islands, shp.WithAmbiguousRingPolicy(shp.AmbiguousRingsError)); fmt.Sprint(got) != "[1 1]" {
+		t.Errorf("islands: got polygons with %v rings, want [1 1]", got)
+	}
+
+	// a counter-clockwise hole belongs to the outer ring containing it, even
+	// if another outer ring comes in between
+	holes := write("holes", [][]shp.Point{
+		{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}},
+		{{20, 0}, {20, 5}, {25, 5}, {25, 0}, {20, 0}},
+		{{2, 2}, {4, 2}, {4, 4}, {2, 4}, {2, 2}},
+	})
+	if got := polygons(holes, shp.WithAmbiguousRingPolicy(shp.AmbiguousRingsError)); fmt.Sprint(got) != "[2 1]" {
+		t.Errorf("holes: got polygons with %v rings, want [2 1]", got)
+	}
+
+	// MultiPolygons convert back to a single record with all rings
+	var buf strings.Builder
+	if err := (shp.GeoJSONConverter{}).ShapefileToGeoJSONStream(holes, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := dir + "/back.shp"
+	if err := (shp.GeoJSONConverter{}).GeoJSONStreamToShapefile(strings.NewReader(buf.String()), out); err != nil {
+		t.Fatal(err)
+	}
+	r, err := shp.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if !r.Next() {
+		t.Fatal("no record written")
+	}
+	if _, shape := r.Shape(); shape.(*shp.Polygon).NumParts != 3 {
+		t.Errorf("got %d rings, want 3", shape.(*shp.Polygon).NumParts)
+	}
+}
@@ -0,0 +1,146 @@
+package shp
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"math"
+	"sort"
+)
+
+// FeatureHash 计算要素的稳定哈希（SHA-256 的十六进制表示），用于比较数据集的不同版本、
+// 找出新增、删除或修改的要素，以及去重。哈希覆盖几何类型、部件划分、所有坐标（含 Z/M、
+// MultiPatch 的部件类型）和按名称排序的属性，不包括外包矩形等可由坐标推出的值。
+// 坐标按 float64 的二进制位比较：-0 与 0 视为相同，所有 NaN 视为相同。
+// 属性值按原样参与计算，调用方应先去除 DBF 值的填充空格，以免同一值因字段宽度不同而哈希不同。
+// shape 为 nil 时视为 Null
+func (GeometryUtils) FeatureHash(shape Shape, attrs map[string]string) string {
+	h := sha256.New()
+	hashShape(h, shape)
+
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hashInt(h, len(names))
+	for _, name := range names {
+		// 带长度前缀，使 {"a": "bc"} 与 {"ab": "c"} 的哈希不同
+		hashString(h, name)
+		hashString(h, attrs[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FeatureHashesFromFile 读取 Shapefile 并按 FeatureHash 计算每条记录的哈希，
+// 下标与记录下标一致，属性以字段名为键、去除填充空格后参与计算
+func (g GeometryUtils) FeatureHashesFromFile(filename string) ([]string, error) {
+	r, err := Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	fields := r.Fields()
+	var hashes []string
+	for r.Next() {
+		n, shape := r.Shape()
+		attrs := make(map[string]string, len(fields))
+		for i, f := range fields {
+			attrs[f.String()] = trimAttribute(r.ReadAttribute(n, i))
+		}
+		hashes = append(hashes, g.FeatureHash(shape, attrs))
+	}
+	return hashes, r.Err()
+}
+
+// hashShape 将形状的规范表示写入 h
+func hashShape(h hash.Hash, shape Shape) {
+	var (
+		parts     []int32
+		partTypes []int32
+		points    []Point
+		zs, ms    []float64
+	)
+	switch s := shape.(type) {
+	case *Point:
+		points = []Point{*s}
+	case *PointZ:
+		points, zs, ms = []Point{{X: s.X, Y: s.Y}}, []float64{s.Z}, []float64{s.M}
+	case *PointM:
+		points, ms = []Point{{X: s.X, Y: s.Y}}, []float64{s.M}
+	case *MultiPoint:
+		points = s.Points
+	case *MultiPointZ:
+		points, zs, ms = s.Points, s.ZArray, s.MArray
+	case *MultiPointM:
+		points, ms = s.Points, s.MArray
+	case *PolyLine:
+		parts, points = s.Parts, s.Points
+	case *Polygon:
+		parts, points = s.Parts, s.Points
+	case *PolyLineZ:
+		parts, points, zs, ms = s.Parts, s.Points, s.ZArray, s.MArray
+	case *PolygonZ:
+		parts, points, zs, ms = s.Parts, s.Points, s.ZArray, s.MArray
+	case *PolyLineM:
+		parts, points, ms = s.Parts, s.Points, s.MArray
+	case *PolygonM:
+		parts, points, ms = s.Parts, s.Points, s.MArray
+	case *MultiPatch:
+		parts, points, zs, ms = s.Parts, s.Points, s.ZArray, s.MArray
+		partTypes = s.PartTypes
+	}
+
+	shapeType := NULL
+	if shape != nil {
+		shapeType = shapeTypeOf(shape)
+	}
+	hashInt(h, int(shapeType))
+	hashInt(h, len(parts))
+	for _, p := range parts {
+		hashInt(h, int(p))
+	}
+	hashInt(h, len(partTypes))
+	for _, t := range partTypes {
+		hashInt(h, int(t))
+	}
+	hashInt(h, len(points))
+	for _, p := range points {
+		hashFloat(h, p.X)
+		hashFloat(h, p.Y)
+	}
+	for _, values := range [][]float64{zs, ms} {
+		hashInt(h, len(values))
+		for _, v := range values {
+			hashFloat(h, v)
+		}
+	}
+}
+
+// hashInt 将 v 以 8 字节小端序写入 h
+func hashInt(h hash.Hash, v int) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(v))
+	_, _ = h.Write(buf[:])
+}
+
+// hashFloat 将 v 的二进制位写入 h，-0 按 0、所有 NaN 按同一个值写入
+func hashFloat(h hash.Hash, v float64) {
+	switch {
+	case v == 0:
+		v = 0
+	case math.IsNaN(v):
+		v = math.NaN()
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	_, _ = h.Write(buf[:])
+}
+
+// hashString 将 s 的长度和内容写入 h
+func hashString(h hash.Hash, s string) {
+	hashInt(h, len(s))
+	_, _ = h.Write([]byte(s))
+}
@@ -1,6 +1,7 @@
 package shp
 
 import (
+	"encoding/binary"
 	"io"
 )
 
@@ -9,38 +10,64 @@ const (
 	shpHeaderLen          = 100
 	shpOffsetToFileLength = 24
 	shpOffsetToGeomType   = 32
-	shpZMRangesLen        = 32 // Zmin, Zmax, Mmin, Mmax (4*float64)
+	shxRecordLen          = 8 // offset and content length (2*int32)
 )
 
-// readShpHeaderSeeker reads SHP header from a seekable reader.
-// Returns file length in bytes, geometry type and bounding box.
-func readShpHeaderSeeker(rs io.ReadSeeker) (int64, ShapeType, Box, error) {
-	er := &errReader{Reader: rs}
-	filelength, _ := rs.Seek(0, io.SeekEnd)
-	// read type and bbox
-	_, _ = rs.Seek(shpOffsetToGeomType, io.SeekStart)
-	var geom ShapeType
-	readLE(er, &geom)
-	bbox := readBBox(er)
-	_, _ = rs.Seek(shpHeaderLen, io.SeekStart)
-	return filelength, geom, bbox, er.e
+// shpHeader holds the fields of a SHP header that the readers use.
+type shpHeader struct {
+	fileLength   int64 // in bytes
+	geometryType ShapeType
+	bbox         Box
+	zRange       [2]float64 // Zmin, Zmax
+	mRange       [2]float64 // Mmin, Mmax
+}
+
+// readShpHeaderSeeker reads SHP header from a seekable reader and leaves it
+// positioned at the first record.
+func readShpHeaderSeeker(rs io.ReadSeeker) (shpHeader, error) {
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return shpHeader{}, err
+	}
+	h, err := readShpHeaderReader(rs)
+	if err != nil {
+		return h, err
+	}
+	_, err = rs.Seek(shpHeaderLen, io.SeekStart)
+	return h, err
 }
 
 // readShpHeaderReader reads SHP header from a forward-only reader.
-// Returns file length in bytes, geometry type and bounding box.
-func readShpHeaderReader(r io.Reader) (int64, ShapeType, Box, error) {
+func readShpHeaderReader(r io.Reader) (shpHeader, error) {
+	var h shpHeader
 	er := &errReader{Reader: r}
 	// skip to file length (file code + unused)
 	_, _ = io.CopyN(io.Discard, er, shpOffsetToFileLength)
 	var l int32
 	readBE(er, &l)
-	filelength := int64(l) * 2
-	// skip 4 bytes (unused)
+	h.fileLength = int64(l) * 2
+	// skip 4 bytes (version)
 	_, _ = io.CopyN(io.Discard, er, 4)
-	var geom ShapeType
-	readLE(er, &geom)
-	bbox := readBBox(er)
-	// skip Z/M ranges (4 float64)
-	_, _ = io.CopyN(io.Discard, er, shpZMRangesLen)
-	return filelength, geom, bbox, er.e
+	readLE(er, &h.geometryType)
+	h.bbox = readBBox(er)
+	// Z and M ranges (4 float64)
+	readLE(er, &h.zRange)
+	readLE(er, &h.mRange)
+	return h, er.e
+}
+
+// readShxOffsets reads the SHX file from the start and returns the byte
+// offsets of all records in the SHP file.
+func readShxOffsets(rs io.ReadSeeker) ([]int64, error) {
+	if _, err := rs.Seek(shpHeaderLen, io.SeekStart); err != nil {
+		return nil, NewShapeError(ErrIO, "failed to seek in shapefile index", err)
+	}
+	data, err := io.ReadAll(rs)
+	if err != nil {
+		return nil, NewShapeError(ErrIO, "failed to read shapefile index", err)
+	}
+	offsets := make([]int64, len(data)/shxRecordLen)
+	for i := range offsets {
+		offsets[i] = int64(binary.BigEndian.Uint32(data[i*shxRecordLen:])) * 2
+	}
+	return offsets, nil
 }
@@ -0,0 +1,55 @@
+package shp
+
+// LocatePoint returns the index of the first polygon record of the shapefile
+// filename that contains p, for example to find the zip code area a location
+// lies in. If a spatial index sidecar written by WriteIndex is available only
+// the records whose bounding box contains p are read, otherwise the shapefile
+// is scanned and every shape is checked against its bounding box before the
+// exact point-in-polygon test. found is false if no polygon contains p.
+func LocatePoint(filename string, p Point) (index int, found bool, err error) {
+	r, err := Open(filename)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = r.Close() }()
+
+	box := Box{MinX: p.X, MinY: p.Y, MaxX: p.X, MaxY: p.Y}
+	if idx, err := loadSpatialIndex(filename); err == nil {
+		for _, row := range idx.Query(box) {
+			shape, err := r.ReadShapeAt(row)
+			if err != nil {
+				return 0, false, err
+			}
+			if PointInPolygonShape(p, shape) {
+				return row, true, nil
+			}
+		}
+		return 0, false, nil
+	}
+
+	for r.Next() {
+		n, shape := r.Shape()
+		if shape.BBox().Intersects(box) && PointInPolygonShape(p, shape) {
+			return n, true, nil
+		}
+	}
+	return 0, false, r.Err()
+}
+
+// PointInPolygonShape reports whether p lies inside the polygon shape, which
+// may be a Polygon, PolygonZ or PolygonM. Points inside a hole are outside of
+// the polygon. Other shape types never contain a point.
+func PointInPolygonShape(p Point, shape Shape) bool {
+	rings, polygon := shapeParts(shape)
+	if !polygon {
+		return false
+	}
+	// even-odd rule: a point inside a hole is contained by two rings
+	inside := false
+	for _, ring := range rings {
+		if (GeometryUtils{}).IsPointInPolygon(p, ring) {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,239 @@
+package shp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// MakeValid 修复无效的多边形并返回新的多边形：
+//   - 去除重复的连续顶点和毛刺（折返的顶点）
+//   - 在自相交处将环拆分为多个简单环（例如“蝴蝶结”形拆为两个三角形）
+//   - 丢弃面积为零的环
+//   - 按包含关系重新判定外环与内环，外环为顺时针、内环为逆时针，
+//     每个外环之后紧跟其内环
+//
+// 这是一个基础实现：共线重叠的边只在形成毛刺时处理，不做完整的拓扑重建。
+// 若修复后没有剩余面积则返回错误。
+func (GeometryUtils) MakeValid(poly *Polygon) (*Polygon, error) {
+	if poly == nil {
+		return nil, errors.New("polygon is nil")
+	}
+
+	var rings [][]Point
+	for _, part := range splitParts(poly.Parts, poly.Points) {
+		for _, ring := range splitSelfIntersections(cleanRing(part)) {
+			if len(ring) >= 3 && signedArea(ring) != 0 {
+				rings = append(rings, ring)
+			}
+		}
+	}
+	if len(rings) == 0 {
+		return nil, errors.New("polygon has no area")
+	}
+
+	return NewPolygon(orderRings(rings)), nil
+}
+
+// cleanRing 返回去掉闭合点、重复顶点和毛刺后的开放环
+func cleanRing(part []Point) []Point {
+	ring := make([]Point, len(part))
+	copy(ring, part)
+	for changed := true; changed && len(ring) > 0; {
+		changed = false
+		for i := 0; i < len(ring) && len(ring) > 0; i++ {
+			prev := ring[(i+len(ring)-1)%len(ring)]
+			cur := ring[i]
+			next := ring[(i+1)%len(ring)]
+			if cur == prev || isSpike(prev, cur, next) {
+				ring = append(ring[:i], ring[i+1:]...)
+				changed = true
+				i--
+			}
+		}
+	}
+	return ring
+}
+
+// isSpike 判断 b 是否为毛刺：a、b、c 共线且在 b 处折返
+func isSpike(a, b, c Point) bool {
+	cross := (b.X-a.X)*(c.Y-b.Y) - (b.Y-a.Y)*(c.X-b.X)
+	dot := (b.X-a.X)*(c.X-b.X) + (b.Y-a.Y)*(c.Y-b.Y)
+	return cross == 0 && dot < 0
+}
+
+// splitSelfIntersections 在第一个自相交处把开放环拆为两个环并递归处理，
+// 返回不再自相交的开放环
+func splitSelfIntersections(ring []Point) [][]Point {
+	n := len(ring)
+	for i := 0; i < n; i++ {
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				continue // 首尾两条边相邻
+			}
+			p, ok := segmentIntersection(ring[i], ring[(i+1)%n], ring[j], ring[(j+1)%n])
+			if !ok {
+				continue
+			}
+			first := append([]Point{p}, ring[i+1:j+1]...)
+			second := append(append(append([]Point{}, ring[:i+1]...), p), ring[j+1:]...)
+			return append(
+				splitSelfIntersections(cleanRing(first)),
+				splitSelfIntersections(cleanRing(second))...)
+		}
+	}
+	return [][]Point{ring}
+}
+
+// segmentIntersection 返回线段 a1-a2 与 b1-b2 的交点（含端点），平行时返回 false
+func segmentIntersection(a1, a2, b1, b2 Point) (Point, bool) {
+	dx1, dy1 := a2.X-a1.X, a2.Y-a1.Y
+	dx2, dy2 := b2.X-b1.X, b2.Y-b1.Y
+	denom := dx1*dy2 - dy1*dx2
+	if denom == 0 {
+		return Point{}, false
+	}
+	t := ((b1.X-a1.X)*dy2 - (b1.Y-a1.Y)*dx2) / denom
+	u := ((b1.X-a1.X)*dy1 - (b1.Y-a1.Y)*dx1) / denom
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return Point{}, false
+	}
+	switch {
+	case t == 0:
+		return a1, true
+	case t == 1:
+		return a2, true
+	case u == 0:
+		return b1, true
+	case u == 1:
+		return b2, true
+	}
+	return Point{a1.X + t*dx1, a1.Y + t*dy1}, true
+}
+
+// RingInfo 描述多边形中一个环的角色
+type RingInfo struct {
+	// Part 环在多边形中的部件下标
+	Part int
+	// Outer 是否为外环
+	Outer bool
+	// Parent 内环所属外环（直接包含它的最小环）的部件下标，外环为 -1
+	Parent int
+	// Clockwise 环是否为顺时针；Shapefile 要求外环顺时针、内环逆时针
+	Clockwise bool
+	// Area 环的面积（非负）
+	Area float64
+}
+
+// ClassifyRings 按包含关系判定多边形每个环是外环还是内环（洞）：
+// 被偶数个环包含的为外环，被奇数个环包含的为内环，并归属于包含它的最小外环。
+// 判定不依赖环的方向，可与 Clockwise 比较来发现方向错误的环。
+// 部件下标无效时返回错误
+func (GeometryUtils) ClassifyRings(poly *Polygon) ([]RingInfo, error) {
+	if poly == nil {
+		return nil, errors.New("polygon is nil")
+	}
+	rings := make([][]Point, len(poly.Parts))
+	for i, start := range poly.Parts {
+		end := len(poly.Points)
+		if i+1 < len(poly.Parts) {
+			end = int(poly.Parts[i+1])
+		}
+		if start < 0 || int(start) > end || end > len(poly.Points) {
+			return nil, fmt.Errorf("invalid part %d", i)
+		}
+		rings[i] = poly.Points[start:end]
+	}
+	return classifyRings(rings), nil
+}
+
+// classifyRings 按包含关系判定 rings 中每个环的角色，结果与 rings 一一对应
+func classifyRings(rings [][]Point) []RingInfo {
+	geom := GeometryUtils{}
+	infos := make([]RingInfo, len(rings))
+	for i, ring := range rings {
+		infos[i] = RingInfo{Part: i, Parent: -1, Clockwise: isClockwise(ring), Area: geom.Area(ring)}
+	}
+	for i := range rings {
+		parent, containers := -1, 0
+		for j := range rings {
+			if j == i || infos[j].Area <= infos[i].Area || !ringContainsRing(rings[j], rings[i]) {
+				continue
+			}
+			containers++
+			// 最小的包含环即为直接父环
+			if parent < 0 || infos[j].Area < infos[parent].Area {
+				parent = j
+			}
+		}
+		infos[i].Outer = containers%2 == 0
+		if !infos[i].Outer {
+			infos[i].Parent = parent
+		}
+	}
+	return infos
+}
+
+// orderRings 根据包含关系判定外环与内环，返回闭合并按 Shapefile 方向排列的环，
+// 外环按面积从大到小排列，每个外环之后紧跟其内环
+func orderRings(rings [][]Point) [][]Point {
+	infos := classifyRings(rings)
+	order := make([]int, len(rings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return infos[order[a]].Area > infos[order[b]].Area })
+
+	var exteriors []int
+	holes := make(map[int][]int)
+	for _, i := range order {
+		if infos[i].Outer {
+			exteriors = append(exteriors, i)
+			continue
+		}
+		holes[infos[i].Parent] = append(holes[infos[i].Parent], i)
+	}
+
+	var result [][]Point
+	for _, e := range exteriors {
+		result = append(result, orientRing(rings[e], true))
+		for _, h := range holes[e] {
+			result = append(result, orientRing(rings[h], false))
+		}
+	}
+	return result
+}
+
+// ringContainsRing 判断 inner 是否位于 outer 内部（以不在 outer 边界上的首个顶点判断）
+func ringContainsRing(outer, inner []Point) bool {
+	geom := GeometryUtils{}
+	for _, p := range inner {
+		onBoundary := false
+		for _, q := range outer {
+			if p == q {
+				onBoundary = true
+				break
+			}
+		}
+		if !onBoundary {
+			return geom.IsPointInPolygon(p, outer)
+		}
+	}
+	return false
+}
+
+// orientRing 返回闭合的环，clockwise 为 true 时为顺时针，否则为逆时针。
+// ring 可以是开放或已闭合的环，不会被修改
+func orientRing(ring []Point, clockwise bool) []Point {
+	r := make([]Point, len(ring), len(ring)+1)
+	copy(r, ring)
+	if isClockwise(r) != clockwise {
+		for a, b := 0, len(r)-1; a < b; a, b = a+1, b-1 {
+			r[a], r[b] = r[b], r[a]
+		}
+	}
+	if len(r) == 0 || r[0] == r[len(r)-1] {
+		return r
+	}
+	return append(r, r[0])
+}
@@ -0,0 +1,149 @@
+package shp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeShapefilesUnion merges the shapefiles inputs into output, whose
+// attribute table has the union of the fields of all inputs. Fields are
+// matched by name, ignoring case, and keep the position in which they were
+// first seen. Fields that appear with different definitions are widened to
+// fit every input: the larger size and precision are used, numeric and float
+// fields become float fields and any other type conflict becomes a character
+// field. Fields missing from an input are left blank for its records.
+//
+// All inputs must have the same shape type. Records are copied one at a
+// time, so the inputs are never loaded into memory. The .prj and .cpg files
+// of the first input are copied to output if present.
+func MergeShapefilesUnion(inputs []string, output string) error {
+	if len(inputs) == 0 {
+		return fmt.Errorf("no input shapefiles")
+	}
+
+	var (
+		shapeType ShapeType
+		fields    []Field
+		index     = make(map[string]int)
+	)
+	for i, input := range inputs {
+		r, err := Open(input)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			shapeType = r.GeometryType
+		} else if r.GeometryType != shapeType {
+			_ = r.Close()
+			return NewShapeError(ErrUnsupportedType,
+				fmt.Sprintf("%s has shape type %s, expected %s", input, r.GeometryType, shapeType), nil)
+		}
+		for _, f := range r.Fields() {
+			name := strings.ToUpper(f.String())
+			if j, ok := index[name]; ok {
+				fields[j] = widenField(fields[j], f)
+				continue
+			}
+			index[name] = len(fields)
+			fields = append(fields, f)
+		}
+		_ = r.Close()
+	}
+
+	w, err := Create(output, shapeType)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if len(fields) > 0 {
+		if err := w.SetFields(fields); err != nil {
+			return err
+		}
+	}
+
+	for _, input := range inputs {
+		if err := appendShapefile(w, input, index); err != nil {
+			return err
+		}
+	}
+
+	return copySidecars(shapefileBase(inputs[0]), shapefileBase(output), ".prj", ".cpg")
+}
+
+// appendShapefile writes all records of the shapefile input to w, storing
+// every attribute in the field of w that index maps its upper-case name to.
+func appendShapefile(w *Writer, input string, index map[string]int) error {
+	r, err := Open(input)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	fields := r.Fields()
+	targets := make([]int, len(fields))
+	for i, f := range fields {
+		targets[i] = index[strings.ToUpper(f.String())]
+	}
+
+	for r.Next() {
+		n, shape := r.Shape()
+		written, err := w.WriteChecked(shape)
+		if err != nil {
+			return fmt.Errorf("%s record %d: %v", input, n, err)
+		}
+		row := int(written)
+		for i, target := range targets {
+			value := r.ReadAttribute(n, i)
+			if value == "" {
+				continue
+			}
+			if err := w.WriteAttribute(row, target, value); err != nil {
+				return fmt.Errorf("%s record %d: %v", input, n, err)
+			}
+		}
+	}
+	return r.Err()
+}
+
+// widenField returns a field definition that can hold the values of both a
+// and b under the name of a.
+func widenField(a, b Field) Field {
+	if a.Fieldtype == b.Fieldtype && a.Size >= b.Size && a.Precision >= b.Precision {
+		return a
+	}
+
+	w := a
+	switch {
+	case isNumericFieldType(a.Fieldtype) && isNumericFieldType(b.Fieldtype):
+		if a.Fieldtype != b.Fieldtype {
+			w.Fieldtype = 'F'
+		}
+		// keep room for the integer digits of both fields
+		digits := maxUint8(a.Size-a.Precision, b.Size-b.Precision)
+		w.Precision = maxUint8(a.Precision, b.Precision)
+		w.Size = maxUint8(a.Size, b.Size)
+		if int(digits)+int(w.Precision) <= 255 {
+			w.Size = maxUint8(w.Size, digits+w.Precision)
+		}
+	case a.Fieldtype == b.Fieldtype:
+		w.Size = maxUint8(a.Size, b.Size)
+	default:
+		w.Fieldtype = 'C'
+		w.Size = maxUint8(a.Size, b.Size)
+		w.Precision = 0
+	}
+	return w
+}
+
+// isNumericFieldType reports whether values of the DBF field type t are
+// numbers stored as text.
+func isNumericFieldType(t byte) bool {
+	return t == 'N' || t == 'F'
+}
+
+func maxUint8(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,88 @@
+package shp
+
+import (
+	"testing"
+)
+
+func TestMergeShapefilesUnion(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, fields []Field, values [][]interface{}) string {
+		filename := dir + "/" + name + ".shp"
+		w, err := Create(filename, POINT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Close()
+		if err := w.SetFields(fields); err != nil {
+			t.Fatal(err)
+		}
+		for i, row := range values {
+			w.Write(&Point{float64(i), float64(i)})
+			for j, v := range row {
+				if err := w.WriteAttribute(i, j, v); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		return filename
+	}
+	a := write("a", []Field{StringField("NAME", 5), NumberField("POP", 5)},
+		[][]interface{}{{"north", 12345}})
+	b := write("b", []Field{NumberField("AREA", 4), StringField("name", 12), FloatField("POP", 8, 2)},
+		[][]interface{}{{42, "south-east", 1.5}, {7, "west", 2.25}})
+
+	output := dir + "/merged.shp"
+	if err := MergeShapefilesUnion([]string{a, b}, output); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	fields := r.Fields()
+	want := []Field{StringField("NAME", 12), FloatField("POP", 8, 2), NumberField("AREA", 4)}
+	if len(fields) != len(want) {
+		t.Fatalf("got %d fields, want %d", len(fields), len(want))
+	}
+	for i, f := range fields {
+		if f.String() != want[i].String() || f.Fieldtype != want[i].Fieldtype ||
+			f.Size != want[i].Size || f.Precision != want[i].Precision {
+			t.Errorf("field %d: got %s %c(%d,%d), want %s %c(%d,%d)", i,
+				f, f.Fieldtype, f.Size, f.Precision,
+				want[i], want[i].Fieldtype, want[i].Size, want[i].Precision)
+		}
+	}
+
+	wantRows := [][]string{{"north", "12345", ""}, {"south-east", "1.50", "42"}, {"west", "2.25", "7"}}
+	n := 0
+	for r.Next() {
+		row, _ := r.Shape()
+		for i, v := range wantRows[row] {
+			if got := r.ReadAttribute(row, i); got != v {
+				t.Errorf("row %d field %d: got %q, want %q", row, i, got, v)
+			}
+		}
+		n++
+	}
+	if n != len(wantRows) {
+		t.Errorf("got %d records, want %d", n, len(wantRows))
+	}
+}
+
+func TestMergeShapefilesUnionTypeMismatch(t *testing.T) {
+	dir := t.TempDir()
+	for _, st := range []ShapeType{POINT, POLYLINE} {
+		w, err := Create(dir+"/"+st.String()+".shp", st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+	}
+	err := MergeShapefilesUnion([]string{dir + "/POINT.shp", dir + "/POLYLINE.shp"}, dir+"/out.shp")
+	if err == nil {
+		t.Error("expected error for mixed shape types")
+	}
+}
@@ -0,0 +1,60 @@
+package shp
+
+import (
+	"os"
+	"strings"
+)
+
+// Metadata describes a shapefile without keeping any of its files open.
+type Metadata struct {
+	GeometryType ShapeType
+	BBox         Box
+	// NumRecords is the number of records, taken from the SHX file or, if
+	// that does not exist, from the DBF file or by counting the shapes.
+	NumRecords int
+	// Fields of the DBF file, nil if there is none.
+	Fields []Field
+	// Projection is the WKT content of the .prj file, empty if there is none.
+	Projection string
+	// Encoding is the content of the .cpg file, empty if there is none.
+	Encoding string
+}
+
+// ReadMetadata reads the header information of the shapefile filename and its
+// sidecar files and closes all of them before returning, which makes it
+// suitable for cataloging many shapefiles.
+func ReadMetadata(filename string) (*Metadata, error) {
+	r, err := Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	m := &Metadata{
+		GeometryType: r.GeometryType,
+		BBox:         r.BBox(),
+		Fields:       r.Fields(),
+	}
+
+	base := shapefileBase(filename)
+	if stat, err := os.Stat(base + ".shx"); err == nil {
+		m.NumRecords = int((stat.Size() - shpHeaderLen) / shxRecordLen)
+	} else if r.dbf != nil {
+		m.NumRecords = int(r.dbfNumRecords)
+	} else {
+		for r.Next() {
+			m.NumRecords++
+		}
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if prj, err := os.ReadFile(base + ".prj"); err == nil {
+		m.Projection = strings.TrimSpace(string(prj))
+	}
+	if cpg, err := os.ReadFile(base + ".cpg"); err == nil {
+		m.Encoding = strings.TrimSpace(string(cpg))
+	}
+	return m, nil
+}
@@ -0,0 +1,97 @@
+package shp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiTypeWriter writes shapes of different types that share a single
+// attribute schema. Since a shapefile can only hold one shape type, every
+// type gets its own set of files, named after the base filename with the
+// lower-case type appended, e.g. "roads_polyline.shp". The files are created
+// lazily when the first shape of a type is written.
+type MultiTypeWriter struct {
+	filename string
+	opts     []WriterOption
+	fields   []Field
+	writers  map[ShapeType]*Writer
+	files    map[ShapeType]string
+}
+
+// NewMultiTypeWriter returns a MultiTypeWriter that creates its files next to
+// filename. A ".shp" extension on filename is ignored. The options are passed
+// on to Create for every file.
+func NewMultiTypeWriter(filename string, opts ...WriterOption) *MultiTypeWriter {
+	if strings.HasSuffix(strings.ToLower(filename), ".shp") {
+		filename = filename[0 : len(filename)-4]
+	}
+	return &MultiTypeWriter{
+		filename: filename,
+		opts:     opts,
+		writers:  make(map[ShapeType]*Writer),
+		files:    make(map[ShapeType]string),
+	}
+}
+
+// SetFields sets the attribute schema of all files. It must be called before
+// the first shape is written.
+func (m *MultiTypeWriter) SetFields(fields []Field) error {
+	if len(m.writers) > 0 {
+		return fmt.Errorf("cannot set fields after shapes have been written")
+	}
+	m.fields = fields
+	return nil
+}
+
+// Write writes shape to the file for its type and returns the index of the
+// record within that file, which is the row to pass to WriteAttribute.
+func (m *MultiTypeWriter) Write(shape Shape) (int32, error) {
+	if shape == nil {
+		return 0, fmt.Errorf("cannot write nil shape")
+	}
+	w, err := m.writer(shapeTypeOf(shape))
+	if err != nil {
+		return 0, err
+	}
+	return w.WriteChecked(shape)
+}
+
+// WriteAttribute writes value for field into the given row of the file that
+// holds shapes of type t.
+func (m *MultiTypeWriter) WriteAttribute(t ShapeType, row int, field int, value interface{}) error {
+	w, ok := m.writers[t]
+	if !ok {
+		return fmt.Errorf("no shapes of type %s have been written", t)
+	}
+	return w.WriteAttribute(row, field, value)
+}
+
+// Close closes all files and returns the path of the ".shp" file created for
+// every shape type.
+func (m *MultiTypeWriter) Close() map[ShapeType]string {
+	for _, w := range m.writers {
+		w.Close()
+	}
+	return m.files
+}
+
+// writer returns the writer for shape type t, creating it if necessary.
+func (m *MultiTypeWriter) writer(t ShapeType) (*Writer, error) {
+	if w, ok := m.writers[t]; ok {
+		return w, nil
+	}
+	filename := fmt.Sprintf("%s_%s.shp", m.filename, strings.ToLower(t.String()))
+	w, err := Create(filename, t, m.opts...)
+	if err != nil {
+		return nil, err
+	}
+	if m.fields != nil {
+		if err := w.SetFields(m.fields); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+	m.writers[t] = w
+	m.files[t] = filename
+	return w, nil
+}
@@ -1,5 +1,10 @@
 package shp
 
+import (
+	"io"
+	"time"
+)
+
 // ReaderOption 定义读取器选项
 type ReaderOption func(*ReaderConfig)
 
@@ -15,8 +20,98 @@ type ReaderConfig struct {
 	BufferSize int
 	// Debug 是否启用调试输出
 	Debug bool
+	// Logger 调试输出的目标，为 nil 时输出到标准输出；Debug 为 false 时不输出任何内容
+	Logger io.Writer
+	// SkipSystemFields 是否在 Fields() 与属性读取中隐藏系统字段（如 _NullFlags）
+	SkipSystemFields bool
+	// BBoxFilter 空间过滤范围，非 nil 时 Next 只返回与其相交的形状
+	BBoxFilter *Box
+	// IDField 转换为 GeoJSON 时作为 Feature id 的 DBF 字段名
+	IDField string
+	// NullAttributePolicy 转换为 GeoJSON 时空属性值的处理方式
+	NullAttributePolicy NullAttributePolicy
+	// StrictRecordCount 打开时 SHP 记录数与 DBF 记录数不一致是否返回错误
+	StrictRecordCount bool
+	// AttributeEncoding DBF 字符串编码，为空时根据 .cpg 文件或 DBF 语言驱动 ID 检测
+	AttributeEncoding string
+	// RFC7946Winding 转换为 GeoJSON 时是否按 RFC 7946 右手定则调整环的方向
+	RFC7946Winding bool
+	// ElevationPolicy 转换为 GeoJSON 时 PointZ/PointM 的 Z、M 值的表示方式
+	ElevationPolicy ElevationPolicy
+	// FileLengthSource 迭代记录时文件长度的来源
+	FileLengthSource FileLengthSource
+	// GeometryOnly 是否只读取几何，从不打开 DBF 文件
+	GeometryOnly bool
+	// RecordOrder Next 迭代记录的顺序
+	RecordOrder RecordOrder
+	// ExplodeMultipart 转换为 GeoJSON 时是否将多部件形状的每个部件输出为单独的 Feature
+	ExplodeMultipart bool
+	// VerifyAgainstIndex Next 是否逐条核对记录位置与 SHX 索引中的偏移量
+	VerifyAgainstIndex bool
+	// AmbiguousRingPolicy 转换为 GeoJSON 时无法按方向区分外环与内环的多边形的处理方式
+	AmbiguousRingPolicy AmbiguousRingPolicy
 }
 
+// AmbiguousRingPolicy 定义多边形所有环方向相同且互相嵌套、无法按方向区分外环与内环时的处理方式
+type AmbiguousRingPolicy int
+
+const (
+	// LargestAsOuter 面积（绝对值）最大的环为外环，位于其内的环为它的内环，
+	// 其余的环按同样的规则分组（默认）
+	LargestAsOuter AmbiguousRingPolicy = iota
+	// TreatAllAsOuter 每个环都是外环，各自成为一个多边形
+	TreatAllAsOuter
+	// AmbiguousRingsError 返回 ErrInvalidFormat 类型的错误，该记录不被转换
+	AmbiguousRingsError
+)
+
+// RecordOrder 定义 Next 迭代记录的顺序
+type RecordOrder int
+
+const (
+	// RecordOrderFile 按记录在 SHP 文件中的物理顺序迭代（默认）
+	RecordOrderFile RecordOrder = iota
+	// RecordOrderIndex 按 SHX 索引中偏移量的顺序迭代，第 i 个索引项对应 DBF 的第 i 行
+	RecordOrderIndex
+)
+
+// FileLengthSource 定义读取 SHP 文件时以哪个长度作为记录的边界
+type FileLengthSource int
+
+const (
+	// FileLengthAuto 读到文件头记录的长度与实际文件大小中较小的一个：文件被截断时
+	// 读到实际文件末尾而不报错，文件头记录的长度之后的数据被忽略（默认）
+	FileLengthAuto FileLengthSource = iota
+	// FileLengthFromFileSize 以实际文件大小为准，忽略文件头记录的长度
+	FileLengthFromFileSize
+	// FileLengthFromHeader 以文件头记录的长度为准，实际文件更短时打开失败
+	FileLengthFromHeader
+)
+
+// ElevationPolicy 定义 PointZ/PointM 的 Z、M 值在 GeoJSON 中的表示方式
+type ElevationPolicy int
+
+const (
+	// ElevationInCoordinates Z 作为第三个坐标输出，M 不输出（默认）
+	ElevationInCoordinates ElevationPolicy = iota
+	// ElevationAlsoProperty Z 保留在坐标中，同时将 Z、M 写入 properties 的 "elevation"、"measure"
+	ElevationAlsoProperty
+	// ElevationPropertyOnly 坐标只包含 X、Y，Z、M 只写入 properties 的 "elevation"、"measure"
+	ElevationPropertyOnly
+)
+
+// NullAttributePolicy 定义空的 DBF 属性值在 GeoJSON properties 中的表示方式
+type NullAttributePolicy int
+
+const (
+	// NullAsNull 空值输出为 null（默认）
+	NullAsNull NullAttributePolicy = iota
+	// NullAsEmptyString 空值输出为空字符串 ""
+	NullAsEmptyString
+	// NullOmit 空值不出现在 properties 中
+	NullOmit
+)
+
 // DefaultReaderConfig 默认读取器配置
 func DefaultReaderConfig() *ReaderConfig {
 	return &ReaderConfig{
@@ -42,7 +137,9 @@ func WithMaxMemoryUsage(size int64) ReaderOption {
 	}
 }
 
-// WithBuffering 设置缓冲选项
+// WithBuffering 设置缓冲选项。流式转换为 GeoJSON/GeoJSONL 或用 SaveGeoJSONToFileWithOptions 保存时，输出经大小为 size 的缓冲区写出，
+// 减少逐个 Feature 写入的系统调用次数；enabled 为 false 或 size 不为正数时直接写出。
+// 按文件顺序读取 POINT 类型的 Shapefile 时，Next 也按 size 大小成块读取记录，而不逐条定位和读取
 func WithBuffering(enabled bool, size int) ReaderOption {
 	return func(config *ReaderConfig) {
 		config.EnableBuffering = enabled
@@ -50,13 +147,154 @@ func WithBuffering(enabled bool, size int) ReaderOption {
 	}
 }
 
-// WithDebug 设置调试模式
+// WithDebug 设置调试模式，开启后读取过程中的诊断信息（文件长度、逐条记录的位置、
+// 跳过的损坏记录等）写入 WithLogger 设置的目标，默认为标准输出。默认关闭，读取时不产生任何输出
 func WithDebug(debug bool) ReaderOption {
 	return func(config *ReaderConfig) {
 		config.Debug = debug
 	}
 }
 
+// WithLogger 设置调试输出的目标，例如日志文件或 io.Discard。只在开启 WithDebug 时才会写入
+func WithLogger(w io.Writer) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.Logger = w
+	}
+}
+
+// WithSkipSystemFields 设置是否隐藏 DBF 系统字段
+func WithSkipSystemFields(skip bool) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.SkipSystemFields = skip
+	}
+}
+
+// WithBBoxFilter 设置空间过滤范围
+func WithBBoxFilter(box Box) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.BBoxFilter = &box
+	}
+}
+
+// WithIDField 设置转换为 GeoJSON 时作为 Feature id 的 DBF 字段（字段名不区分大小写），该字段不再出现在 properties 中
+func WithIDField(fieldName string) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.IDField = fieldName
+	}
+}
+
+// WithNullAttributePolicy 设置转换为 GeoJSON 时空属性值的处理方式
+func WithNullAttributePolicy(policy NullAttributePolicy) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.NullAttributePolicy = policy
+	}
+}
+
+// WithStrictRecordCount 设置打开时是否校验 SHP 与 DBF 的记录数一致，不一致时返回错误。
+// 未启用时，调试模式下只输出警告
+func WithStrictRecordCount(strict bool) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.StrictRecordCount = strict
+	}
+}
+
+// WithAttributeEncoding 设置 DBF 字符串编码（如 "UTF-8"、"ISO-8859-1"、"1252"），
+// 覆盖从 .cpg 文件或 DBF 头检测到的编码
+func WithAttributeEncoding(encoding string) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.AttributeEncoding = encoding
+	}
+}
+
+// WithRFC7946Winding 设置转换为 GeoJSON 时是否按 RFC 7946 右手定则输出多边形：
+// 外环逆时针、内环顺时针（与 Shapefile 的约定相反）。默认保持 Shapefile 中的方向
+func WithRFC7946Winding(enabled bool) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.RFC7946Winding = enabled
+	}
+}
+
+// WithElevationProperty 设置转换为 GeoJSON 时 PointZ/PointM 的 Z、M 值的表示方式，
+// 便于不支持三维坐标的旧工具读取。同名的 DBF 属性优先，不会被覆盖
+func WithElevationProperty(policy ElevationPolicy) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.ElevationPolicy = policy
+	}
+}
+
+// WithTrustFileSize 设置是否以实际文件大小作为记录的边界，文件头记录的长度之后的数据
+// 无法解析时返回错误。为 false 时只撤销本选项的设置，恢复默认的 FileLengthAuto，
+// 不影响 WithTrustHeaderLength(true)
+func WithTrustFileSize(trust bool) ReaderOption {
+	return func(config *ReaderConfig) {
+		if trust {
+			config.FileLengthSource = FileLengthFromFileSize
+		} else if config.FileLengthSource == FileLengthFromFileSize {
+			config.FileLengthSource = FileLengthAuto
+		}
+	}
+}
+
+// WithTrustHeaderLength 设置是否以文件头记录的长度作为记录的边界，
+// 此时实际文件比文件头记录的更短（被截断）会返回 HeaderLengthError。
+// 为 false 时只撤销本选项的设置，恢复默认的 FileLengthAuto，不影响 WithTrustFileSize(true)
+func WithTrustHeaderLength(trust bool) ReaderOption {
+	return func(config *ReaderConfig) {
+		if trust {
+			config.FileLengthSource = FileLengthFromHeader
+		} else if config.FileLengthSource == FileLengthFromHeader {
+			config.FileLengthSource = FileLengthAuto
+		}
+	}
+}
+
+// WithGeometryOnly 设置是否只读取几何而从不打开 DBF 文件，用于重投影、简化等
+// 不需要属性的处理，DBF 缺失或损坏时也能读取。此时 Fields() 返回空，
+// 属性读取方法返回空值且不报错
+func WithGeometryOnly(enabled bool) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.GeometryOnly = enabled
+	}
+}
+
+// WithRecordOrder 设置 Next 迭代记录的顺序。某些工具会重排 SHX 索引而不重排 SHP 文件，
+// 此时两种顺序不同，属性对齐取决于信任哪一种。使用 RecordOrderIndex 并开启调试时，
+// 打开文件会输出 CheckRecordOrder 发现的不一致
+func WithRecordOrder(order RecordOrder) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.RecordOrder = order
+	}
+}
+
+// WithVerifyAgainstIndex 设置 Next 是否在读取每条记录前核对其在 SHP 文件中的位置与
+// SHX 索引中对应项的偏移量，在第一处不一致时停止，Err 返回 ErrCorruptedFile 类型的错误，
+// 其 Cause 为 *RecordOrderError，错误信息包含出错的记录下标，便于定位文件从哪条记录开始损坏。
+// SHP 文件提前结束或比索引多出记录也视为不一致。没有 SHX 文件时不做核对，
+// 按 RecordOrderIndex 迭代时记录位置本就取自索引，也不做核对
+func WithVerifyAgainstIndex(enabled bool) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.VerifyAgainstIndex = enabled
+	}
+}
+
+// WithExplodeMultipart 设置转换为 GeoJSON 时是否“炸开”多部件形状：多线的每条线、
+// 多点的每个点、多边形的每个外环（连同其内环）各输出为一个 Feature，
+// 属性与 id 复制到每个 Feature。默认每条记录输出一个 Feature
+func WithExplodeMultipart(enabled bool) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.ExplodeMultipart = enabled
+	}
+}
+
+// WithAmbiguousRingPolicy 设置转换为 GeoJSON 时多边形环的分组方式在方向无法区分外环与内环时的回退规则。
+// 通常顺时针的环为外环、逆时针的环为内环并归入包含它的外环，多个外环输出为 MultiPolygon；
+// 所有环方向相同时，互不嵌套的环都视为外环，有环嵌套在其他环内时按 policy 处理
+func WithAmbiguousRingPolicy(policy AmbiguousRingPolicy) ReaderOption {
+	return func(config *ReaderConfig) {
+		config.AmbiguousRingPolicy = policy
+	}
+}
+
 // WriterOption 定义写入器选项
 type WriterOption func(*WriterConfig)
 
@@ -70,15 +308,36 @@ type WriterConfig struct {
 	BufferSize int
 	// EnableSync 是否在每次写入后同步到磁盘
 	EnableSync bool
+	// StringEncoding DBF 字符串编码（"UTF-8" 或 "ISO-8859-1"），为空时按原样写入字节
+	StringEncoding string
+	// SanitizeStrings 是否将无法编码的字符替换为 '?'，而不是返回错误
+	SanitizeStrings bool
+	// NonFiniteAsNull 是否将 NaN/Inf 浮点值写为空值，否则返回错误
+	NonFiniteAsNull bool
+	// FloatPrecision 浮点数小数位数，负数表示使用字段定义的精度
+	FloatPrecision int
+	// SwapXY 从 GeoJSON 导入时是否交换 X/Y 坐标（修正 [lat, lon] 顺序的数据）
+	SwapXY bool
+	// Overwrite 是否覆盖已存在的 .shp/.shx/.dbf 文件，为 false 时文件已存在则创建失败
+	Overwrite bool
+	// CoordinateDecimals 写入前坐标（含 Z/M）保留的小数位数，负数表示不取整
+	CoordinateDecimals int
+	// LastUpdate 写入 DBF 文件头的最后更新日期，为零值时使用写入时的当前日期
+	LastUpdate time.Time
+	// EnforcePolygonWinding 写入前是否将多边形的环调整为 Shapefile 的方向（外环顺时针、内环逆时针）
+	EnforcePolygonWinding bool
 }
 
 // DefaultWriterConfig 默认写入器配置
 func DefaultWriterConfig() *WriterConfig {
 	return &WriterConfig{
-		CompressionLevel: 0,
-		EnableValidation: true,
-		BufferSize:       64 * 1024, // 64KB
-		EnableSync:       false,
+		CompressionLevel:   0,
+		EnableValidation:   true,
+		BufferSize:         64 * 1024, // 64KB
+		EnableSync:         false,
+		FloatPrecision:     -1,
+		Overwrite:          true,
+		CoordinateDecimals: -1,
 	}
 }
 
@@ -111,3 +370,94 @@ func WithSync(enabled bool) WriterOption {
 		config.EnableSync = enabled
 	}
 }
+
+// WithStringEncoding 设置 DBF 字符串编码，并在创建 DBF 时写出对应的 .cpg 文件
+func WithStringEncoding(encoding string) WriterOption {
+	return func(config *WriterConfig) {
+		config.StringEncoding = encoding
+	}
+}
+
+// WithSanitizeStrings 设置是否替换无法编码的字符
+func WithSanitizeStrings(enabled bool) WriterOption {
+	return func(config *WriterConfig) {
+		config.SanitizeStrings = enabled
+	}
+}
+
+// WithNonFiniteAsNull 设置是否将 NaN/Inf 浮点值写为空值
+func WithNonFiniteAsNull(enabled bool) WriterOption {
+	return func(config *WriterConfig) {
+		config.NonFiniteAsNull = enabled
+	}
+}
+
+// WithFloatPrecision 设置浮点数小数位数，覆盖字段定义的精度
+func WithFloatPrecision(precision int) WriterOption {
+	return func(config *WriterConfig) {
+		config.FloatPrecision = precision
+	}
+}
+
+// WithSwapXY 设置从 GeoJSON 导入时是否交换 X/Y 坐标
+func WithSwapXY(enabled bool) WriterOption {
+	return func(config *WriterConfig) {
+		config.SwapXY = enabled
+	}
+}
+
+// WithOverwrite 设置是否覆盖已存在的文件，为 false 时若 .shp、.shx 或 .dbf 已存在则 Create 返回错误
+func WithOverwrite(enabled bool) WriterOption {
+	return func(config *WriterConfig) {
+		config.Overwrite = enabled
+	}
+}
+
+// WithCoordinateRounding 设置写入前将坐标（含 Z/M）四舍五入到指定的小数位数，
+// 边界框按取整后的坐标重新计算，可减少噪声并提高输出的可压缩性
+func WithCoordinateRounding(decimals int) WriterOption {
+	return func(config *WriterConfig) {
+		config.CoordinateDecimals = decimals
+	}
+}
+
+// WithEnforcePolygonWinding 设置写入前是否按包含关系判定 Polygon、PolygonZ、PolygonM
+// 的外环与内环，并将外环调整为顺时针、内环调整为逆时针（同时调整 Z/M 值的顺序），
+// 使按 GeoJSON 方向构造的多边形也能写出符合规范的文件。环的先后顺序不变
+func WithEnforcePolygonWinding(enabled bool) WriterOption {
+	return func(config *WriterConfig) {
+		config.EnforcePolygonWinding = enabled
+	}
+}
+
+// WithLastUpdate 设置写入 DBF 文件头的最后更新日期，便于生成可重复的输出。
+// 默认使用写入时的当前日期
+func WithLastUpdate(date time.Time) WriterOption {
+	return func(config *WriterConfig) {
+		config.LastUpdate = date
+	}
+}
+
+// AnalyzeOption 定义统计分析选项
+type AnalyzeOption func(*AnalyzeOptions)
+
+// AnalyzeOptions 统计分析配置
+type AnalyzeOptions struct {
+	// MaxUniqueValues 每个字段最多收集到 Values 中的唯一值数量，<= 0 表示不限制。
+	// 唯一值数量 UniqueValues 总是精确的
+	MaxUniqueValues int
+}
+
+// DefaultAnalyzeOptions 默认统计分析配置
+func DefaultAnalyzeOptions() *AnalyzeOptions {
+	return &AnalyzeOptions{
+		MaxUniqueValues: 1000,
+	}
+}
+
+// WithMaxUniqueValues 设置每个字段最多收集到 Values 中的唯一值数量，<= 0 表示不限制
+func WithMaxUniqueValues(n int) AnalyzeOption {
+	return func(options *AnalyzeOptions) {
+		options.MaxUniqueValues = n
+	}
+}
@@ -0,0 +1,234 @@
+package shp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// IntersectionArea 计算两个多边形相交部分的面积，例如两块地块重叠的面积；
+// 除以其中一个多边形的面积即为重叠比例。适用于任意简单多边形，包括凹多边形、
+// 多个外环和内环（洞）。外环与内环按 ClassifyRings 的包含关系判定，与环的方向无关。
+//
+// 实现上将每个多边形表示为以公共参考点为顶点、以各条边为底的有向三角形之和，
+// 对两组三角形两两用 Sutherland–Hodgman 算法裁剪并累加有向面积，
+// 时间复杂度为两个多边形顶点数之积。多边形为 nil 或部件下标无效时返回错误
+func (GeometryUtils) IntersectionArea(a, b *Polygon) (float64, error) {
+	if a == nil || b == nil {
+		return 0, errors.New("polygon is nil")
+	}
+	// 以两者范围的中心为参考点，减小坐标较大时的舍入误差
+	box := a.BBox()
+	box.Extend(b.BBox())
+	origin := Point{X: (box.MinX + box.MaxX) / 2, Y: (box.MinY + box.MaxY) / 2}
+
+	ta, err := signedTriangles(a, origin)
+	if err != nil {
+		return 0, err
+	}
+	tb, err := signedTriangles(b, origin)
+	if err != nil {
+		return 0, err
+	}
+
+	area := 0.0
+	for _, s := range ta {
+		sBox := triangleBox(s.points)
+		for _, t := range tb {
+			if !sBox.Intersects(triangleBox(t.points)) {
+				continue
+			}
+			area += s.sign * t.sign * convexIntersectionArea(s.points[:], t.points[:])
+		}
+	}
+	return math.Max(area, 0), nil
+}
+
+// signedTriangle 是逆时针排列的三角形及其符号
+type signedTriangle struct {
+	points [3]Point
+	sign   float64
+}
+
+// signedTriangles 将多边形分解为以 origin 为公共顶点的有向三角形：外环按逆时针、
+// 内环按顺时针取向后，多边形内部每一点被三角形覆盖的有向次数之和为 1，外部为 0
+func signedTriangles(poly *Polygon, origin Point) ([]signedTriangle, error) {
+	infos, err := GeometryUtils{}.ClassifyRings(poly)
+	if err != nil {
+		return nil, err
+	}
+	var triangles []signedTriangle
+	for i, ring := range splitParts(poly.Parts, poly.Points) {
+		if infos[i].Area == 0 {
+			continue
+		}
+		ring = orientRing(ring, !infos[i].Outer)
+		for k := 0; k+1 < len(ring); k++ {
+			p, q := ring[k], ring[k+1]
+			cross := (p.X-origin.X)*(q.Y-origin.Y) - (q.X-origin.X)*(p.Y-origin.Y)
+			switch {
+			case cross > 0:
+				triangles = append(triangles, signedTriangle{[3]Point{origin, p, q}, 1})
+			case cross < 0:
+				triangles = append(triangles, signedTriangle{[3]Point{origin, q, p}, -1})
+			}
+		}
+	}
+	return triangles, nil
+}
+
+// triangleBox 返回三角形的外包矩形
+func triangleBox(t [3]Point) Box {
+	box := Box{MinX: t[0].X, MinY: t[0].Y, MaxX: t[0].X, MaxY: t[0].Y}
+	box.ExtendWithPoint(t[1])
+	box.ExtendWithPoint(t[2])
+	return box
+}
+
+// convexIntersectionArea 用 Sutherland–Hodgman 算法以凸多边形 clip 裁剪凸多边形 subject，
+// 返回相交部分的面积。两者均为逆时针且不闭合
+func convexIntersectionArea(subject, clip []Point) float64 {
+	output := subject
+	for i := range clip {
+		if len(output) == 0 {
+			return 0
+		}
+		e1, e2 := clip[i], clip[(i+1)%len(clip)]
+		inside := func(p Point) bool {
+			return (e2.X-e1.X)*(p.Y-e1.Y)-(e2.Y-e1.Y)*(p.X-e1.X) >= 0
+		}
+		input := output
+		output = make([]Point, 0, len(input)+1)
+		for j, cur := range input {
+			prev := input[(j+len(input)-1)%len(input)]
+			if inside(cur) {
+				if !inside(prev) {
+					output = append(output, lineIntersection(prev, cur, e1, e2))
+				}
+				output = append(output, cur)
+			} else if inside(prev) {
+				output = append(output, lineIntersection(prev, cur, e1, e2))
+			}
+		}
+	}
+	return GeometryUtils{}.Area(output)
+}
+
+// lineIntersection 返回线段 p1-p2 与直线 e1-e2 的交点，调用方保证两者相交
+func lineIntersection(p1, p2, e1, e2 Point) Point {
+	dx, dy := p2.X-p1.X, p2.Y-p1.Y
+	ex, ey := e2.X-e1.X, e2.Y-e1.Y
+	denom := dx*ey - dy*ex
+	if denom == 0 {
+		return p1
+	}
+	t := ((e1.X-p1.X)*ey - (e1.Y-p1.Y)*ex) / denom
+	return Point{X: p1.X + t*dx, Y: p1.Y + t*dy}
+}
+
+// LineIntersections 返回两个线要素（PolyLine、PolyLineZ 或 PolyLineM）所有线段之间的
+// 交点，包括端点相接的情况；共线重叠的线段返回重叠部分的两个端点。
+// 每个交点只返回一次，按在 a 中出现的顺序排列。其他几何类型返回 ErrUnsupportedType 类型的错误
+func (GeometryUtils) LineIntersections(a, b Shape) ([]Point, error) {
+	partsA, err := lineParts(a)
+	if err != nil {
+		return nil, err
+	}
+	partsB, err := lineParts(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []Point
+	seen := make(map[Point]bool)
+	add := func(p Point) {
+		if !seen[p] {
+			seen[p] = true
+			points = append(points, p)
+		}
+	}
+	for _, pa := range partsA {
+		for i := 0; i+1 < len(pa); i++ {
+			a1, a2 := pa[i], pa[i+1]
+			boxA := segmentBox(a1, a2)
+			for _, pb := range partsB {
+				for j := 0; j+1 < len(pb); j++ {
+					b1, b2 := pb[j], pb[j+1]
+					if !boxA.Intersects(segmentBox(b1, b2)) {
+						continue
+					}
+					if p, ok := segmentIntersection(a1, a2, b1, b2); ok {
+						add(p)
+					} else {
+						for _, p := range collinearOverlap(a1, a2, b1, b2) {
+							add(p)
+						}
+					}
+				}
+			}
+		}
+	}
+	return points, nil
+}
+
+// lineParts 返回线要素的各个部分
+func lineParts(shape Shape) ([][]Point, error) {
+	parts, polygon := shapeParts(shape)
+	if parts == nil || polygon {
+		return nil, NewShapeError(ErrUnsupportedType,
+			fmt.Sprintf("%s is not a line shape", shapeTypeOf(shape)), nil)
+	}
+	return parts, nil
+}
+
+// segmentBox 返回线段的外包矩形
+func segmentBox(p, q Point) Box {
+	box := Box{MinX: p.X, MinY: p.Y, MaxX: p.X, MaxY: p.Y}
+	box.ExtendWithPoint(q)
+	return box
+}
+
+// collinearOverlap 返回共线线段 a1-a2 与 b1-b2 重叠部分的端点（重叠为一点时只返回一个），
+// 不共线或不重叠时返回 nil
+func collinearOverlap(a1, a2, b1, b2 Point) []Point {
+	cross := func(o, p, q Point) float64 {
+		return (p.X-o.X)*(q.Y-o.Y) - (p.Y-o.Y)*(q.X-o.X)
+	}
+	if cross(a1, a2, b1) != 0 || cross(a1, a2, b2) != 0 || cross(b1, b2, a1) != 0 || cross(b1, b2, a2) != 0 {
+		return nil
+	}
+	// 沿 a 的方向投影，a 退化为一点时沿 b 的方向
+	dx, dy := a2.X-a1.X, a2.Y-a1.Y
+	if dx == 0 && dy == 0 {
+		dx, dy = b2.X-b1.X, b2.Y-b1.Y
+	}
+	if dx == 0 && dy == 0 {
+		if a1 == b1 {
+			return []Point{a1}
+		}
+		return nil
+	}
+	t := func(p Point) float64 { return (p.X-a1.X)*dx + (p.Y-a1.Y)*dy }
+	lo, hi := a1, a2
+	if t(lo) > t(hi) {
+		lo, hi = hi, lo
+	}
+	blo, bhi := b1, b2
+	if t(blo) > t(bhi) {
+		blo, bhi = bhi, blo
+	}
+	if t(blo) > t(lo) {
+		lo = blo
+	}
+	if t(bhi) < t(hi) {
+		hi = bhi
+	}
+	switch {
+	case t(lo) > t(hi):
+		return nil
+	case lo == hi:
+		return []Point{lo}
+	default:
+		return []Point{lo, hi}
+	}
+}
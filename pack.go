@@ -0,0 +1,302 @@
+package shp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// DeleteRecord marks record row of the shapefile filename as deleted by
+// setting the deletion flag of the corresponding DBF row. The geometry is left
+// untouched until Pack is called.
+func DeleteRecord(filename string, row int) error {
+	dbf, err := os.OpenFile(shapefileBase(filename)+".dbf", os.O_RDWR, 0o666)
+	if err != nil {
+		return NewShapeError(ErrIO, "failed to open DBF", err)
+	}
+	defer func() { _ = dbf.Close() }()
+
+	numRecords, headerLength, recordLength, err := readDbfLayout(dbf)
+	if err != nil {
+		return err
+	}
+	if row < 0 || row >= int(numRecords) {
+		return NewShapeError(ErrInvalidField,
+			fmt.Sprintf("record %d out of range [0, %d)", row, numRecords), nil)
+	}
+	offset := int64(headerLength) + int64(row)*int64(recordLength)
+	if _, err := dbf.WriteAt([]byte{dbfDeletionFlagDeleted}, offset); err != nil {
+		return NewShapeError(ErrIO, "failed to mark record as deleted", err)
+	}
+	return nil
+}
+
+// Pack removes the records marked as deleted from the shapefile filename. The
+// SHP, SHX and DBF files are rewritten together, so that after compaction
+// record i of the SHP file still corresponds to row i of the DBF file. Records
+// are renumbered, the SHX offsets are rebuilt and the bounding box in the
+// headers is recomputed from the remaining shapes. The records of the SHP file
+// are read up to the shorter of the file length in its header and its size,
+// as by default by Reader. The DBF gets the current date as its last update,
+// or the one set with WithLastUpdate, and ends with the end-of-file marker.
+//
+// The files are written to temporary files first and only replace the
+// originals once all of them have been written successfully. The three files
+// cannot be replaced atomically: the originals are moved aside to files with
+// the suffix ".orig~" before the packed files take their place, and are moved
+// back if that fails. Only if the process dies during the swap can the
+// shapefile be left incomplete, with the originals still in the ".orig~"
+// files.
+func Pack(filename string, opts ...WriterOption) error {
+	config := DefaultWriterConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+	base := shapefileBase(filename)
+	tmpBase := base + ".pack~"
+	if err := pack(base, tmpBase, config); err != nil {
+		removeShapefileFiles(tmpBase)
+		return err
+	}
+	if err := swapShapefile(base, tmpBase, base+".orig~"); err != nil {
+		removeShapefileFiles(tmpBase)
+		return err
+	}
+	return nil
+}
+
+// swapShapefile replaces the SHP, SHX and DBF files of base with those of
+// tmpBase. The originals are moved to origBase first and restored if any file
+// cannot be replaced; once all are replaced they are removed.
+func swapShapefile(base, tmpBase, origBase string) error {
+	exts := []string{".shp", ".shx", ".dbf"}
+	var moved []string // extensions of the originals moved to origBase
+	restore := func() {
+		for _, ext := range exts {
+			_ = os.Remove(base + ext)
+		}
+		for _, ext := range moved {
+			_ = os.Rename(origBase+ext, base+ext)
+		}
+	}
+	for _, ext := range exts {
+		err := os.Rename(base+ext, origBase+ext)
+		if os.IsNotExist(err) {
+			continue // e.g. a shapefile without an index
+		}
+		if err != nil {
+			restore()
+			return NewShapeError(ErrIO, "failed to move aside "+base+ext, err)
+		}
+		moved = append(moved, ext)
+	}
+	for _, ext := range exts {
+		if err := os.Rename(tmpBase+ext, base+ext); err != nil {
+			restore()
+			return NewShapeError(ErrIO, "failed to replace "+base+ext, err)
+		}
+	}
+	removeShapefileFiles(origBase)
+	return nil
+}
+
+// removeShapefileFiles removes the SHP, SHX and DBF files of base, ignoring
+// errors.
+func removeShapefileFiles(base string) {
+	for _, ext := range []string{".shp", ".shx", ".dbf"} {
+		_ = os.Remove(base + ext)
+	}
+}
+
+// pack writes the compacted shapefile base to tmpBase.
+//
+//nolint:gocyclo
+func pack(base, tmpBase string, config *WriterConfig) error {
+	shpIn, err := os.Open(base + ".shp")
+	if err != nil {
+		return NewShapeError(ErrIO, "failed to open shapefile", err)
+	}
+	defer func() { _ = shpIn.Close() }()
+	dbfIn, err := os.Open(base + ".dbf")
+	if err != nil {
+		return NewShapeError(ErrIO, "failed to open DBF", err)
+	}
+	defer func() { _ = dbfIn.Close() }()
+
+	numRecords, headerLength, recordLength, err := readDbfLayout(dbfIn)
+	if err != nil {
+		return err
+	}
+	shpHeader := make([]byte, shpHeaderLen)
+	dbfHeader := make([]byte, headerLength)
+	if _, err := io.ReadFull(shpIn, shpHeader); err != nil {
+		return NewShapeError(ErrCorruptedFile, "failed to read SHP header", err)
+	}
+	if _, err := dbfIn.Seek(0, io.SeekStart); err != nil {
+		return NewShapeError(ErrIO, "failed to seek in DBF", err)
+	}
+	if _, err := io.ReadFull(dbfIn, dbfHeader); err != nil {
+		return NewShapeError(ErrCorruptedFile, "failed to read DBF header", err)
+	}
+	// the records end at the file length in the header unless the file was
+	// truncated
+	shpEnd := int64(binary.BigEndian.Uint32(shpHeader[shpOffsetToFileLength:])) * 2
+	if stat, err := shpIn.Stat(); err != nil {
+		return NewShapeError(ErrIO, "failed to get file stats", err)
+	} else if stat.Size() < shpEnd {
+		shpEnd = stat.Size()
+	}
+	date := time.Now()
+	if !config.LastUpdate.IsZero() {
+		date = config.LastUpdate
+	}
+	copy(dbfHeader[1:4], []byte{byte(date.Year() - 1900), byte(date.Month()), byte(date.Day())})
+
+	files := make(map[string]*os.File, 3)
+	for _, ext := range []string{".shp", ".shx", ".dbf"} {
+		f, err := os.Create(tmpBase + ext)
+		if err != nil {
+			return NewShapeError(ErrIO, "failed to create "+tmpBase+ext, err)
+		}
+		defer func() { _ = f.Close() }()
+		files[ext] = f
+	}
+	shpOut := bufio.NewWriter(files[".shp"])
+	shxOut := bufio.NewWriter(files[".shx"])
+	dbfOut := bufio.NewWriter(files[".dbf"])
+	ewShp := &errWriter{Writer: shpOut}
+	ewShx := &errWriter{Writer: shxOut}
+	ewDbf := &errWriter{Writer: dbfOut}
+	writeLE(ewShp, shpHeader)
+	writeLE(ewShx, shpHeader)
+	writeLE(ewDbf, dbfHeader)
+
+	shpIn2 := bufio.NewReader(shpIn)
+	dbfIn2 := bufio.NewReader(dbfIn)
+	row := make([]byte, recordLength)
+	var (
+		bbox    Box
+		hasBBox bool
+		kept    int32
+		offset  = int64(shpHeaderLen)
+		records uint32
+	)
+	for inOffset := int64(shpHeaderLen); inOffset+8 <= shpEnd; records++ {
+		var num, size int32
+		erShp := &errReader{Reader: shpIn2}
+		readBE(erShp, &num)
+		readBE(erShp, &size)
+		if erShp.e != nil || size < 2 || inOffset+8+int64(size)*2 > shpEnd {
+			return NewShapeError(ErrCorruptedFile,
+				fmt.Sprintf("invalid header of record %d", records), erShp.e)
+		}
+		inOffset += 8 + int64(size)*2
+		content := make([]byte, int(size)*2)
+		if _, err := io.ReadFull(shpIn2, content); err != nil {
+			return NewShapeError(ErrCorruptedFile,
+				fmt.Sprintf("failed to read record %d", records), err)
+		}
+		if records >= numRecords {
+			return NewShapeError(ErrCorruptedFile,
+				fmt.Sprintf("DBF has %d rows but SHP has more records", numRecords), nil)
+		}
+		if _, err := io.ReadFull(dbfIn2, row); err != nil {
+			return NewShapeError(ErrCorruptedFile,
+				fmt.Sprintf("failed to read DBF row %d", records), err)
+		}
+		if row[0] == dbfDeletionFlagDeleted {
+			continue
+		}
+
+		shapetype := ShapeType(binary.LittleEndian.Uint32(content))
+		if shape, err := newShape(shapetype); err == nil && shapetype != NULL {
+			shape.read(bytes.NewReader(content[4:]))
+			if !hasBBox {
+				bbox, hasBBox = shape.BBox(), true
+			} else {
+				bbox.Extend(shape.BBox())
+			}
+		}
+
+		kept++
+		writeBE(ewShp, []int32{kept, size})
+		writeLE(ewShp, content)
+		writeBE(ewShx, []int32{int32(offset / 2), size})
+		writeLE(ewDbf, row)
+		offset += 8 + int64(size)*2
+	}
+	if records != numRecords {
+		return NewShapeError(ErrCorruptedFile,
+			fmt.Sprintf("DBF has %d rows but SHP has %d records", numRecords, records), nil)
+	}
+	writeLE(ewDbf, []byte{dbfEndOfFile})
+	for _, ew := range []*errWriter{ewShp, ewShx, ewDbf} {
+		if ew.e != nil {
+			return NewShapeError(ErrIO, "failed to write packed shapefile", ew.e)
+		}
+	}
+	for _, bw := range []*bufio.Writer{shpOut, shxOut, dbfOut} {
+		if err := bw.Flush(); err != nil {
+			return NewShapeError(ErrIO, "failed to write packed shapefile", err)
+		}
+	}
+
+	// patch file lengths, bounding box and record count into the headers
+	shxLength := int64(shpHeaderLen) + int64(kept)*shxRecordLen
+	var bboxBuf bytes.Buffer
+	_ = binary.Write(&bboxBuf, binary.LittleEndian, bbox)
+	for _, h := range []struct {
+		f      *os.File
+		length int64
+	}{{files[".shp"], offset}, {files[".shx"], shxLength}} {
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(h.length/2))
+		if _, err := h.f.WriteAt(length, shpOffsetToFileLength); err != nil {
+			return NewShapeError(ErrIO, "failed to update SHP header", err)
+		}
+		if _, err := h.f.WriteAt(bboxBuf.Bytes(), shpOffsetToGeomType+4); err != nil {
+			return NewShapeError(ErrIO, "failed to update SHP header", err)
+		}
+	}
+	count := make([]byte, 4)
+	binary.LittleEndian.PutUint32(count, uint32(kept))
+	if _, err := files[".dbf"].WriteAt(count, dbfOffsetNumRecords); err != nil {
+		return NewShapeError(ErrIO, "failed to update DBF header", err)
+	}
+
+	for _, f := range files {
+		if err := f.Close(); err != nil {
+			return NewShapeError(ErrIO, "failed to close packed shapefile", err)
+		}
+	}
+	return nil
+}
+
+// readDbfLayout reads the number of records, the header length and the record
+// length from the header of a DBF file.
+func readDbfLayout(dbf io.ReadSeeker) (numRecords uint32, headerLength, recordLength int16, err error) {
+	if _, err = dbf.Seek(dbfOffsetNumRecords, io.SeekStart); err != nil {
+		return 0, 0, 0, NewShapeError(ErrIO, "failed to seek in DBF", err)
+	}
+	er := &errReader{Reader: dbf}
+	readLE(er, &numRecords)
+	readLE(er, &headerLength)
+	readLE(er, &recordLength)
+	if er.e != nil {
+		return 0, 0, 0, NewShapeError(ErrCorruptedFile, "failed to read DBF header", er.e)
+	}
+	return numRecords, headerLength, recordLength, nil
+}
+
+// shapefileBase returns filename without a ".shp" extension.
+func shapefileBase(filename string) string {
+	if strings.HasSuffix(strings.ToLower(filename), ".shp") {
+		return filename[:len(filename)-4]
+	}
+	return filename
+}
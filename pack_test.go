@@ -0,0 +1,153 @@
+package shp
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPack(t *testing.T) {
+	filename := t.TempDir() + "/pack.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 5), NumberField("ID", 5)}); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"a", "b", "c", "d", "e"}
+	for i, name := range names {
+		n := w.Write(&Point{float64(i), float64(i)})
+		_ = w.WriteAttribute(int(n), 0, name)
+		_ = w.WriteAttribute(int(n), 1, i)
+	}
+	w.Close()
+
+	for _, row := range []int{1, 3} {
+		if err := DeleteRecord(filename, row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := DeleteRecord(filename, len(names)); err == nil {
+		t.Error("deleting a record out of range succeeded")
+	}
+	if err := Pack(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.AttributeCount() != 3 {
+		t.Fatalf("got %d DBF records, want 3", r.AttributeCount())
+	}
+	if want := (Box{0, 0, 4, 4}); r.BBox() != want {
+		t.Errorf("got bbox %v, want %v", r.BBox(), want)
+	}
+
+	type record struct {
+		point Point
+		name  string
+		id    string
+	}
+	want := []record{{Point{0, 0}, "a", "0"}, {Point{2, 2}, "c", "2"}, {Point{4, 4}, "e", "4"}}
+	var got []record
+	for r.Next() {
+		n, shape := r.Shape()
+		got = append(got, record{*shape.(*Point), r.ReadAttribute(n, 0), r.ReadAttribute(n, 1)})
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got records %v, want %v", got, want)
+	}
+
+	// the rebuilt index must point at the remaining records
+	shape, err := r.ReadShapeAt(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := *shape.(*Point); p != want[2].point {
+		t.Errorf("ReadShapeAt(2): got %v, want %v", p, want[2].point)
+	}
+}
+
+func TestPackBoundsAndDbfEnd(t *testing.T) {
+	dir := t.TempDir()
+	filename := dir + "/pack.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{NumberField("ID", 5)}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		n := w.Write(&Point{float64(i), float64(i)})
+		_ = w.WriteAttribute(int(n), 0, i)
+	}
+	w.Close()
+	if err := DeleteRecord(filename, 0); err != nil {
+		t.Fatal(err)
+	}
+	// bytes after the file length in the header are not records
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(make([]byte, 16)); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	date := time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)
+	if err := Pack(filename, WithLastUpdate(date)); err != nil {
+		t.Fatal(err)
+	}
+	dbf, err := os.ReadFile(dir + "/pack.dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dbf[1:4]; !bytes.Equal(got, []byte{121, 3, 4}) {
+		t.Errorf("got last update %v, want [121 3 4]", got)
+	}
+	if got := dbf[len(dbf)-1]; got != dbfEndOfFile {
+		t.Errorf("got last DBF byte %#x, want %#x", got, dbfEndOfFile)
+	}
+	for _, name := range []string{"pack.pack~.shp", "pack.orig~.shp", "pack.orig~.dbf"} {
+		if _, err := os.Stat(dir + "/" + name); !os.IsNotExist(err) {
+			t.Errorf("%s was left behind", name)
+		}
+	}
+
+	// appended records follow the last record, not the end-of-file marker
+	w, err = Append(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := w.Write(&Point{5, 5})
+	_ = w.WriteAttribute(int(n), 0, 5)
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var got []string
+	for r.Next() {
+		n, _ := r.Shape()
+		got = append(got, r.ReadAttribute(n, 0))
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1", "2", "5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got IDs %v, want %v", got, want)
+	}
+}
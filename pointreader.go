@@ -0,0 +1,121 @@
+package shp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Lengths of the records of a Point shapefile including the 8-byte record
+// header: a point has the shape type and two coordinates, a Null record only
+// the shape type.
+const (
+	pointRecordLen     = 8 + 4 + 16
+	nullPointRecordLen = 8 + 4
+)
+
+// pointBlock holds a block of records of a Point shapefile that Next decodes
+// without seeking or reading per record. The SHP file is positioned at the
+// end of the block while it holds records.
+type pointBlock struct {
+	buf   []byte
+	start int64 // file offset of buf[0]
+	pos   int   // offset in buf of the next record
+}
+
+// usePointBlocks reports whether Next may read the records of r in blocks:
+// the file must be a Point shapefile read in file order with buffering
+// enabled, and no option may need to inspect every record as it is read.
+func (r *Reader) usePointBlocks() bool {
+	c := r.config
+	return r.GeometryType == POINT && c != nil &&
+		c.EnableBuffering && c.BufferSize >= pointRecordLen &&
+		c.RecordOrder != RecordOrderIndex && !c.VerifyAgainstIndex &&
+		!c.IgnoreCorruptedShapes && !c.Debug
+}
+
+// resetPointBlock discards the buffered records, e.g. after the position of
+// the SHP file was changed.
+func (r *Reader) resetPointBlock() {
+	if r.points != nil {
+		r.points.buf = r.points.buf[:0]
+		r.points.pos = 0
+	}
+}
+
+// nextPoint reads the next record of a Point shapefile from the current
+// block. Point and Null records of the usual length are decoded directly;
+// anything else, such as padded or truncated records, is left to next.
+func (r *Reader) nextPoint() bool {
+	b := r.points
+	if len(b.buf)-b.pos < pointRecordLen {
+		if err := r.fillPointBlock(); err != nil {
+			r.err = fmt.Errorf("Error when reading metadata of next shape: %v", err)
+			return false
+		}
+	}
+	cur := b.start + int64(b.pos)
+	if cur >= r.filelength {
+		return false
+	}
+
+	rec := b.buf[b.pos:]
+	if len(rec) >= nullPointRecordLen {
+		num := int32(binary.BigEndian.Uint32(rec))
+		size := int32(binary.BigEndian.Uint32(rec[4:]))
+		switch ShapeType(binary.LittleEndian.Uint32(rec[8:])) {
+		case POINT:
+			if size*2 == pointRecordLen-8 && len(rec) >= pointRecordLen && cur+pointRecordLen <= r.filelength {
+				r.shapeCount++
+				r.num = num
+				r.shape = &Point{
+					X: math.Float64frombits(binary.LittleEndian.Uint64(rec[12:])),
+					Y: math.Float64frombits(binary.LittleEndian.Uint64(rec[20:])),
+				}
+				b.pos += pointRecordLen
+				return true
+			}
+		case NULL:
+			if size*2 == nullPointRecordLen-8 && cur+nullPointRecordLen <= r.filelength {
+				r.shapeCount++
+				r.num = num
+				r.shape = &Null{}
+				b.pos += nullPointRecordLen
+				return true
+			}
+		}
+	}
+
+	if _, err := r.shp.Seek(cur, io.SeekStart); err != nil {
+		r.err = fmt.Errorf("Error seeking to shape at position %d: %v", cur, err)
+		return false
+	}
+	r.resetPointBlock()
+	return r.next()
+}
+
+// fillPointBlock moves the unread records to the start of the block and
+// fills the rest of it from the SHP file.
+func (r *Reader) fillPointBlock() error {
+	b := r.points
+	if b.buf == nil {
+		b.buf = make([]byte, 0, r.config.BufferSize)
+	}
+	n := copy(b.buf[:cap(b.buf)], b.buf[b.pos:])
+	if n > 0 {
+		b.start += int64(b.pos)
+	} else {
+		start, err := r.shp.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		b.start = start
+	}
+	m, err := io.ReadFull(r.shp, b.buf[n:cap(b.buf)])
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	b.buf, b.pos = b.buf[:n+m], 0
+	return err
+}
@@ -0,0 +1,154 @@
+package shp
+
+import (
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// writePoints writes n points to filename, every seventh of them a Null
+// record, with the index of the point as attribute.
+func writePoints(t testing.TB, filename string, n int) {
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{NumberField("ID", 6)}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		var shape Shape = &Point{float64(i), float64(-i)}
+		if i%7 == 3 {
+			shape = &Null{}
+		}
+		w.Write(shape)
+		_ = w.WriteAttribute(i, 0, i)
+	}
+	w.Close()
+}
+
+// readAllShapes returns the shapes and record numbers returned by Next.
+func readAllShapes(t *testing.T, r *Reader) ([]Shape, []int32) {
+	t.Helper()
+	var shapes []Shape
+	var nums []int32
+	for r.Next() {
+		_, shape := r.Shape()
+		shapes = append(shapes, shape)
+		nums = append(nums, r.RecordNumber())
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return shapes, nums
+}
+
+func TestPointBlocks(t *testing.T) {
+	filename := t.TempDir() + "/points.shp"
+	writePoints(t, filename, 1000)
+
+	unbuffered, err := Open(filename, WithBuffering(false, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unbuffered.Close()
+	if unbuffered.points != nil {
+		t.Fatal("read points in blocks without buffering")
+	}
+	wantShapes, wantNums := readAllShapes(t, unbuffered)
+
+	// a block size that isn't a multiple of the record length splits records
+	r, err := Open(filename, WithBuffering(true, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.points == nil {
+		t.Fatal("didn't read points in blocks")
+	}
+	shapes, nums := readAllShapes(t, r)
+	if !reflect.DeepEqual(shapes, wantShapes) || !reflect.DeepEqual(nums, wantNums) {
+		t.Fatalf("got %d shapes, want %d equal to those read one at a time", len(shapes), len(wantShapes))
+	}
+
+	// random access and seeking in between reads
+	if err := r.SeekRecord(500); err != nil {
+		t.Fatal(err)
+	}
+	for i := 500; i < 510; i++ {
+		if !r.Next() {
+			t.Fatal(r.Err())
+		}
+		if _, err := r.ReadShapeAt(999 - i); err != nil {
+			t.Fatal(err)
+		}
+		n, shape := r.Shape()
+		if n != i || !reflect.DeepEqual(shape, wantShapes[i]) {
+			t.Errorf("got shape %d %+v, want %d %+v", n, shape, i, wantShapes[i])
+		}
+		if got := r.ReadAttributeTyped(n, 0); got != int64(i) {
+			t.Errorf("shape %d: got attribute %v", i, got)
+		}
+	}
+}
+
+func TestPointBlocksPaddedRecord(t *testing.T) {
+	filename := t.TempDir() + "/padded.shp"
+	writePoints(t, filename, 3)
+	if err := os.Remove(filename[:len(filename)-4] + ".shx"); err != nil {
+		t.Fatal(err)
+	}
+
+	// append four bytes of padding to the second record
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	padded := append([]byte(nil), data[:128]...)
+	binary.BigEndian.PutUint32(padded[104:], 12)
+	padded = append(append(padded, 0, 0, 0, 0), data[128:]...)
+	binary.BigEndian.PutUint32(padded[24:], uint32(len(padded)/2))
+	if err := os.WriteFile(filename, padded, 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	shapes, nums := readAllShapes(t, r)
+	want := []Shape{&Point{0, 0}, &Point{1, -1}, &Point{2, -2}}
+	if !reflect.DeepEqual(shapes, want) || !reflect.DeepEqual(nums, []int32{1, 2, 3}) {
+		t.Errorf("got shapes %v with record numbers %v", shapes, nums)
+	}
+}
+
+func BenchmarkReaderNextPoints(b *testing.B) {
+	filename := b.TempDir() + "/points.shp"
+	writePoints(b, filename, 100000)
+
+	for _, bench := range []struct {
+		name string
+		opts []ReaderOption
+	}{
+		{"Blocks", nil},
+		{"OneAtATime", []ReaderOption{WithBuffering(false, 0)}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				r, err := Open(filename, bench.opts...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for r.Next() {
+				}
+				if err := r.Err(); err != nil {
+					b.Fatal(err)
+				}
+				r.Close()
+			}
+		})
+	}
+}
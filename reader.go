@@ -1,10 +1,12 @@
 package shp
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -15,6 +17,8 @@ import (
 type Reader struct {
 	GeometryType ShapeType
 	bbox         Box
+	zRange       [2]float64
+	mRange       [2]float64
 	err          error
 
 	shp        readSeekCloser
@@ -26,15 +30,45 @@ type Reader struct {
 	shapeCount      int
 	dbf             readSeekCloser
 	dbfFields       []Field
+	dbfVisible      []Field // fields exposed by Fields() when system fields are skipped
+	dbfFieldIndex   []int   // maps indices of dbfVisible to indices of dbfFields
 	dbfNumRecords   int32
 	dbfHeaderLength int16
 	dbfRecordLength int16
+	dbfCodePage     byte
+	dbfReserved     []byte // header bytes 12-31
+	// maps upper-case names of the fields returned by Fields to their index,
+	// built on first use by FieldIndex
+	dbfNameIndex map[string]int
+
+	// string encoding of the DBF, detected lazily by Encoding
+	encoding       string
+	encodingLoaded bool
 
 	// Configuration
 	config *ReaderConfig
 
 	// internal reusable buffer for attribute reads to reduce allocations
 	attrBuf []byte
+
+	// extent of the records returned by Next
+	filteredBBox    Box
+	hasFilteredBBox bool
+
+	// byte offsets of the record headers, loaded lazily for random access
+	offsets []int64
+	// position in offsets of the next record read by Next and index of the
+	// current record when iterating in RecordOrderIndex
+	indexPos, indexRow int
+
+	// SHX offsets that Next checks the records against with
+	// WithVerifyAgainstIndex, and the index of the next record to check
+	verifyOffsets []int64
+	verifyRow     int
+
+	// records of a Point shapefile buffered by Next, nil if they are read one
+	// at a time
+	points *pointBlock
 }
 
 type readSeekCloser interface {
@@ -77,21 +111,105 @@ func OpenWithConfig(filename string, config *ReaderConfig, opts ...ReaderOption)
 		return nil, err
 	}
 
+	if config.StrictRecordCount || config.Debug {
+		if err := s.CheckRecordCount(); err != nil {
+			if config.StrictRecordCount {
+				_ = s.Close()
+				return nil, err
+			}
+			s.debugf("Warning: %v\n", err)
+		}
+	}
+	if config.VerifyAgainstIndex {
+		if err := s.loadVerifyOffsets(); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
+	if config.RecordOrder == RecordOrderIndex && config.Debug {
+		issues, err := s.CheckRecordOrder()
+		if err != nil {
+			s.debugf("Warning: %v\n", err)
+		}
+		for _, issue := range issues {
+			s.debugf("Warning: SHX and SHP record order differ at %v\n", issue)
+		}
+	}
+	if s.usePointBlocks() {
+		s.points = &pointBlock{}
+	}
+
 	return s, nil
 }
 
+// debugf writes a diagnostic message to the configured logger, or to standard
+// output if there is none, when the Reader was opened with WithDebug.
+func (r *Reader) debugf(format string, args ...interface{}) {
+	if r.config == nil || !r.config.Debug {
+		return
+	}
+	var w io.Writer = os.Stdout
+	if r.config.Logger != nil {
+		w = r.config.Logger
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
 // BBox returns the bounding box of the shapefile.
 func (r *Reader) BBox() Box {
 	return r.bbox
 }
 
+// ZRange returns the range of Z values, Zmin and Zmax, stored in the SHP
+// header. Files without Z values usually store zeros.
+func (r *Reader) ZRange() [2]float64 {
+	return r.zRange
+}
+
+// MRange returns the range of measures, Mmin and Mmax, stored in the SHP
+// header. Files without measures usually store zeros.
+func (r *Reader) MRange() [2]float64 {
+	return r.mRange
+}
+
+// BBoxEmpty reports whether the shapefile has no extent because it has no
+// records or only Null shapes, e.g. an attribute-only table. The header of
+// such a file has an all-zero bounding box, which BBox returns as is but which
+// is not a location at the origin. Only when the header box is all zeros are
+// the record headers read to tell the two apart; the position used by Next
+// is not affected.
+func (r *Reader) BBoxEmpty() bool {
+	if r.bbox != (Box{}) {
+		return false
+	}
+	if err := r.loadOffsets(); err != nil {
+		return false
+	}
+	cur, err := r.shp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false
+	}
+	defer func() { _, _ = r.shp.Seek(cur, io.SeekStart) }()
+	for _, offset := range r.offsets {
+		if _, err := r.shp.Seek(offset, io.SeekStart); err != nil {
+			return false
+		}
+		_, _, shapetype, err := readShapeRecordHeader(r.shp)
+		if err != nil || shapetype != NULL {
+			return false
+		}
+	}
+	return true
+}
+
 // Read and parse headers in the Shapefile. This will
-// fill out GeometryType, filelength and bbox.
+// fill out GeometryType, filelength, bbox and the Z and M ranges.
 func (r *Reader) readHeaders() error {
-	fl, geom, bbox, err := readShpHeaderSeeker(r.shp)
+	h, err := readShpHeaderSeeker(r.shp)
 	if err != nil {
 		return err
 	}
+	fl := h.fileLength
 
 	// 获取实际文件大小
 	stat, err := r.shp.(*os.File).Stat()
@@ -100,17 +218,33 @@ func (r *Reader) readHeaders() error {
 	}
 	actualSize := stat.Size()
 
-	if r.config != nil && r.config.Debug {
-		fmt.Printf("Header reports file length: %d bytes, actual file size: %d bytes\n", fl, actualSize)
-	}
+	r.debugf("Header reports file length: %d bytes, actual file size: %d bytes\n", fl, actualSize)
 
-	if fl > actualSize {
-		return fmt.Errorf("header reports file length %d but actual file size is %d", fl, actualSize)
+	source := FileLengthAuto
+	if r.config != nil {
+		source = r.config.FileLengthSource
 	}
-
-	r.filelength = fl
-	r.GeometryType = geom
-	r.bbox = bbox
+	switch source {
+	case FileLengthFromHeader:
+		if fl > actualSize {
+			return NewShapeError(ErrCorruptedFile, "file is shorter than its header reports",
+				&HeaderLengthError{Reported: fl, Actual: actualSize})
+		}
+		r.filelength = fl
+	case FileLengthFromFileSize:
+		r.filelength = actualSize
+	default:
+		// a truncated file is read up to its end, a file holding more than
+		// the header reports up to the length in the header
+		r.filelength = actualSize
+		if fl < actualSize {
+			r.filelength = fl
+		}
+	}
+	r.GeometryType = h.geometryType
+	r.bbox = h.bbox
+	r.zRange = h.zRange
+	r.mRange = h.mRange
 	return nil
 }
 
@@ -130,13 +264,36 @@ func (r *Reader) Close() error {
 // object index starting from zero in the shapefile which
 // can be used as row in ReadAttribute, and the Shape is the object.
 func (r *Reader) Shape() (int, Shape) {
-	return int(r.num) - 1, r.shape
+	return r.row(), r.shape
+}
+
+// row returns the index of the most recent feature read by Next: its
+// position in the SHX file when iterating in RecordOrderIndex, otherwise its
+// record number minus one.
+func (r *Reader) row() int {
+	if r.indexOrder() {
+		return r.indexRow
+	}
+	return int(r.num) - 1
+}
+
+// indexOrder reports whether Next follows the order of the SHX file.
+func (r *Reader) indexOrder() bool {
+	return r.config != nil && r.config.RecordOrder == RecordOrderIndex
+}
+
+// RecordNumber returns the record number stored in the record header of the
+// most recent feature that was read by a call to Next. Record numbers start
+// at 1 and increase by one in a valid shapefile, so that this is the index
+// returned by Shape plus one; use CheckRecordNumbers to verify that.
+func (r *Reader) RecordNumber() int32 {
+	return r.num
 }
 
 // Attribute returns value of the n-th attribute of the most recent feature
 // that was read by a call to Next.
 func (r *Reader) Attribute(n int) string {
-	return r.ReadAttribute(int(r.num)-1, n)
+	return r.ReadAttribute(r.row(), n)
 }
 
 // constructor table to reduce switch duplication
@@ -166,31 +323,124 @@ func newShape(shapetype ShapeType) (Shape, error) {
 		fmt.Sprintf("unsupported shape type: %v", shapetype), nil)
 }
 
+// shapeTypeOf returns the shape type of shape, the inverse of newShape.
+func shapeTypeOf(shape Shape) ShapeType {
+	switch shape.(type) {
+	case *Point:
+		return POINT
+	case *PolyLine:
+		return POLYLINE
+	case *Polygon:
+		return POLYGON
+	case *MultiPoint:
+		return MULTIPOINT
+	case *PointZ:
+		return POINTZ
+	case *PolyLineZ:
+		return POLYLINEZ
+	case *PolygonZ:
+		return POLYGONZ
+	case *MultiPointZ:
+		return MULTIPOINTZ
+	case *PointM:
+		return POINTM
+	case *PolyLineM:
+		return POLYLINEM
+	case *PolygonM:
+		return POLYGONM
+	case *MultiPointM:
+		return MULTIPOINTM
+	case *MultiPatch:
+		return MULTIPATCH
+	default:
+		return NULL
+	}
+}
+
 // Next reads in the next Shape in the Shapefile, which
 // will then be available through the Shape method. It
 // returns false when the reader has reached the end of the
-// file or encounters an error.
+// file or encounters an error. If a bounding box filter is
+// configured, shapes outside of it are skipped.
+func (r *Reader) Next() bool {
+	for r.nextRecord() {
+		if r.config != nil && r.config.BBoxFilter != nil {
+			if _, ok := r.shape.(*Null); ok || !r.shape.BBox().Intersects(*r.config.BBoxFilter) {
+				continue
+			}
+		}
+		// Null records have no extent, their zero box would add the origin
+		if _, null := r.shape.(*Null); !null {
+			r.extendFilteredBBox(r.shape.BBox())
+		}
+		return true
+	}
+	return false
+}
+
+// extendFilteredBBox adds box to the extent of the records returned by Next.
+func (r *Reader) extendFilteredBBox(box Box) {
+	if !r.hasFilteredBBox {
+		r.filteredBBox = box
+		r.hasFilteredBBox = true
+		return
+	}
+	r.filteredBBox.Extend(box)
+}
 
+// FilteredBBox returns the bounding box of the records returned by Next so
+// far. With a bounding box filter this is the extent of the matched features,
+// which is usually smaller than both the filter and BBox. Null records do not
+// contribute to it.
+func (r *Reader) FilteredBBox() Box {
+	return r.filteredBBox
+}
+
+// nextRecord reads the next shape in the configured record order without
+// applying any filter.
+func (r *Reader) nextRecord() bool {
+	if !r.indexOrder() {
+		if r.points != nil {
+			return r.nextPoint()
+		}
+		return r.next()
+	}
+	if err := r.loadOffsets(); err != nil {
+		r.err = err
+		return false
+	}
+	if r.indexPos >= len(r.offsets) {
+		return false
+	}
+	r.indexRow = r.indexPos
+	r.indexPos++
+	if _, err := r.shp.Seek(r.offsets[r.indexRow], io.SeekStart); err != nil {
+		r.err = fmt.Errorf("Error seeking to shape %d: %v", r.indexRow, err)
+		return false
+	}
+	return r.next()
+}
+
+// next reads the next shape without applying any filter.
+//
 //nolint:gocyclo
-func (r *Reader) Next() bool {
+func (r *Reader) next() bool {
 	r.shapeCount++
-	if r.config != nil && r.config.Debug {
-		fmt.Printf("Processing shape #%d\n", r.shapeCount)
-	}
+	r.debugf("Processing shape #%d\n", r.shapeCount)
 	cur, _ := r.shp.Seek(0, io.SeekCurrent)
 	if cur >= r.filelength {
+		r.verifyRecordOffset(-1)
 		return false
 	}
 
 	num, size, shapetype, err := readShapeRecordHeader(r.shp)
 	if err != nil {
 		if err == io.EOF {
+			r.verifyRecordOffset(-1)
 			return false // 正常结束，不设置错误
 		}
 		if r.config != nil && r.config.IgnoreCorruptedShapes {
-			if r.config.Debug {
-				fmt.Printf("Warning: Error reading shape header, skipping: %v\n", err)
-			}
+			r.debugf("Warning: Error reading shape header, skipping: %v\n", err)
 			return r.trySkipToNextValidShape(cur)
 		}
 		r.err = fmt.Errorf("Error when reading metadata of next shape: %v", err)
@@ -198,16 +448,16 @@ func (r *Reader) Next() bool {
 	}
 
 	// 添加调试信息
-	if r.config != nil && r.config.Debug {
-		fmt.Printf("Reading shape %d: size=%d, type=%v, position=%d\n", num, size, shapetype, cur)
+	r.debugf("Reading shape %d: size=%d, type=%v, position=%d\n", num, size, shapetype, cur)
+
+	if !r.verifyRecordOffset(cur) {
+		return false
 	}
 
 	// 检查记录大小是否合理
 	if size < 0 {
 		if r.config != nil && r.config.IgnoreCorruptedShapes {
-			if r.config.Debug {
-				fmt.Printf("Warning: Invalid negative shape record size: %d at position %d, skipping\n", size, cur)
-			}
+			r.debugf("Warning: Invalid negative shape record size: %d at position %d, skipping\n", size, cur)
 			return r.trySkipToNextValidShape(cur)
 		}
 		r.err = fmt.Errorf("Invalid negative shape record size: %d at position %d", size, cur)
@@ -218,9 +468,7 @@ func (r *Reader) Next() bool {
 	expectedEndPos := cur + int64(size)*2 + 8
 	if expectedEndPos > r.filelength {
 		if r.config != nil && r.config.IgnoreCorruptedShapes {
-			if r.config.Debug {
-				fmt.Printf("Warning: Shape record extends beyond file: expected end %d, file length %d, skipping\n", expectedEndPos, r.filelength)
-			}
+			r.debugf("Warning: Shape record extends beyond file: expected end %d, file length %d, skipping\n", expectedEndPos, r.filelength)
 			return r.trySkipToNextValidShape(cur)
 		}
 		r.err = fmt.Errorf("Shape record extends beyond file: expected end %d, file length %d", expectedEndPos, r.filelength)
@@ -231,15 +479,13 @@ func (r *Reader) Next() bool {
 	r.shape, err = newShape(shapetype)
 	if err != nil {
 		if r.config != nil && r.config.IgnoreCorruptedShapes {
-			if r.config.Debug {
-				fmt.Printf("Warning: Error decoding shape type: %v, skipping\n", err)
-			}
+			r.debugf("Warning: Error decoding shape type: %v, skipping\n", err)
 			// Try to skip to next shape based on size
 			nextPos := cur + int64(size)*2 + 8
 			if nextPos <= r.filelength {
 				_, seekErr := r.shp.Seek(nextPos, 0)
 				if seekErr == nil {
-					return r.Next() // Recursively try next shape
+					return r.next() // Recursively try next shape
 				}
 			}
 			return false
@@ -250,29 +496,23 @@ func (r *Reader) Next() bool {
 
 	// 在读取前记录当前位置
 	beforeRead, _ := r.shp.Seek(0, io.SeekCurrent)
-	if r.config != nil && r.config.Debug {
-		fmt.Printf("About to read shape data at position %d\n", beforeRead)
-	}
+	r.debugf("About to read shape data at position %d\n", beforeRead)
 
 	er := &errReader{Reader: r.shp}
 	r.shape.read(er)
 	if er.e != nil {
 		if r.config != nil && r.config.IgnoreCorruptedShapes {
 			if er.e == io.EOF {
-				if r.config.Debug {
-					fmt.Printf("Warning: Unexpected end of file while reading shape %d at position %d, skipping\n", num, beforeRead)
-				}
+				r.debugf("Warning: Unexpected end of file while reading shape %d at position %d, skipping\n", num, beforeRead)
 			} else {
-				if r.config.Debug {
-					fmt.Printf("Warning: Error while reading shape %d: %v, skipping\n", num, er.e)
-				}
+				r.debugf("Warning: Error while reading shape %d: %v, skipping\n", num, er.e)
 			}
 			// Try to skip to next shape based on size
 			nextPos := cur + int64(size)*2 + 8
 			if nextPos <= r.filelength {
 				_, seekErr := r.shp.Seek(nextPos, 0)
 				if seekErr == nil {
-					return r.Next() // Recursively try next shape
+					return r.next() // Recursively try next shape
 				}
 			}
 			return false
@@ -285,14 +525,12 @@ func (r *Reader) Next() bool {
 		return false
 	}
 
-	// 验证读取后的位置
+	// 验证读取后的位置。定长的点记录后的填充字节是合法的，由记录长度跳过
 	afterRead, _ := r.shp.Seek(0, io.SeekCurrent)
-	expectedPos := beforeRead + int64(size)*2
-	if afterRead != expectedPos {
-		if r.config != nil && r.config.Debug {
-			fmt.Printf("Warning: position mismatch after reading shape %d. Expected: %d, Actual: %d\n",
-				num, expectedPos, afterRead)
-		}
+	expectedPos := cur + int64(size)*2 + 8 // size includes the shape type
+	if afterRead != expectedPos && !(afterRead < expectedPos && isFixedSizeShape(r.shape)) {
+		r.debugf("Warning: position mismatch after reading shape %d. Expected: %d, Actual: %d\n",
+			num, expectedPos, afterRead)
 	}
 
 	// move to next object
@@ -300,9 +538,7 @@ func (r *Reader) Next() bool {
 	_, err = r.shp.Seek(nextPos, 0)
 	if err != nil {
 		if r.config != nil && r.config.IgnoreCorruptedShapes {
-			if r.config.Debug {
-				fmt.Printf("Warning: Error seeking to next position %d: %v, skipping\n", nextPos, err)
-			}
+			r.debugf("Warning: Error seeking to next position %d: %v, skipping\n", nextPos, err)
 			return false
 		}
 		r.err = fmt.Errorf("Error seeking to next position %d: %v", nextPos, err)
@@ -312,13 +548,60 @@ func (r *Reader) Next() bool {
 	return true
 }
 
+// loadVerifyOffsets reads the SHX offsets used by WithVerifyAgainstIndex.
+// Without a SHX file there is nothing to verify against.
+func (r *Reader) loadVerifyOffsets() error {
+	shx, err := os.Open(r.filename + ".shx")
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return NewShapeError(ErrIO, "failed to open shapefile index", err)
+	}
+	defer func() { _ = shx.Close() }()
+	r.verifyOffsets, err = readShxOffsets(shx)
+	return err
+}
+
+// verifyRecordOffset compares the offset cur of the record that Next is about
+// to read, or -1 at the end of the SHP file, with the next entry of the SHX
+// file when verifying against the index. At the first difference it sets
+// r.err to an error of type ErrCorruptedFile with a *RecordOrderError cause
+// and returns false.
+func (r *Reader) verifyRecordOffset(cur int64) bool {
+	if r.verifyOffsets == nil || r.indexOrder() {
+		return true
+	}
+	row := r.verifyRow
+	indexOffset := int64(-1)
+	if row < len(r.verifyOffsets) {
+		indexOffset = r.verifyOffsets[row]
+	}
+	if indexOffset == cur {
+		r.verifyRow++
+		return true
+	}
+	r.err = NewShapeError(ErrCorruptedFile, fmt.Sprintf("record %d does not match the index", row),
+		&RecordOrderError{IndexOffset: indexOffset, FileOffset: cur})
+	return false
+}
+
+// isFixedSizeShape reports whether records of the type of shape always have
+// the same content length, so that a longer record can only be padding.
+func isFixedSizeShape(shape Shape) bool {
+	switch shape.(type) {
+	case *Null, *Point, *PointZ, *PointM:
+		return true
+	default:
+		return false
+	}
+}
+
 // trySkipToNextValidShape 尝试跳过损坏的shape，寻找下一个有效的shape
 //
 //nolint:gocyclo
 func (r *Reader) trySkipToNextValidShape(currentPos int64) bool {
-	if r.config != nil && r.config.Debug {
-		fmt.Printf("Attempting to skip corrupted shape and find next valid shape...\n")
-	}
+	r.debugf("Attempting to skip corrupted shape and find next valid shape...\n")
 
 	// 从当前位置开始，以小步长前进寻找下一个有效的shape头
 	for pos := currentPos + 8; pos < r.filelength-8; pos += 4 {
@@ -338,21 +621,17 @@ func (r *Reader) trySkipToNextValidShape(currentPos int64) bool {
 			(shapetype >= NULL && shapetype <= MULTIPATCH) { // 有效的shape类型
 			expectedEndPos := pos + int64(size)*2 + 8
 			if expectedEndPos <= r.filelength {
-				if r.config != nil && r.config.Debug {
-					fmt.Printf("Found potential valid shape at position %d\n", pos)
-				}
+				r.debugf("Found potential valid shape at position %d\n", pos)
 				// 重新定位到这个位置，让下一次Next()调用处理它
 				_, err = r.shp.Seek(pos, 0)
 				if err == nil {
-					return r.Next() // 递归调用Next尝试读取这个shape
+					return r.next() // 递归调用next尝试读取这个shape
 				}
 			}
 		}
 	}
 
-	if r.config != nil && r.config.Debug {
-		fmt.Printf("No more valid shapes found\n")
-	}
+	r.debugf("No more valid shapes found\n")
 	return false
 }
 
@@ -393,14 +672,22 @@ func (r *Reader) trySkipToNextValidShape(currentPos int64) bool {
 // will parse the header and fill out all dbf* values int
 // the f object.
 func (r *Reader) openDbf() (err error) {
-	if r.dbf != nil {
+	if r.dbf != nil || r.geometryOnly() {
 		return
 	}
 
-	r.dbf, err = os.Open(r.filename + ".dbf")
+	dbf, err := os.Open(r.filename + ".dbf")
 	if err != nil {
 		return
 	}
+	r.dbf = dbf
+	defer func() {
+		// don't keep a DBF whose layout can't be trusted
+		if err != nil {
+			_ = dbf.Close()
+			r.dbf, r.dbfFields, r.dbfVisible, r.dbfFieldIndex, r.dbfNumRecords = nil, nil, nil, nil, 0
+		}
+	}()
 
 	// read header
 	_, _ = r.dbf.Seek(dbfOffsetNumRecords, io.SeekStart)
@@ -409,18 +696,44 @@ func (r *Reader) openDbf() (err error) {
 	readLE(er, &r.dbfHeaderLength)
 	readLE(er, &r.dbfRecordLength)
 
-	_, _ = r.dbf.Seek(dbfHeaderPaddingLen, io.SeekCurrent) // skip padding
+	padding := make([]byte, dbfHeaderPaddingLen)
+	readLE(er, padding)
+	r.dbfCodePage = padding[dbfOffsetCodePage-dbfOffsetPadding]
+	r.dbfReserved = padding
 	numFields := calcNumFields(r.dbfHeaderLength)
 	if r.dbfFields, err = readDbfFields(r.dbf, numFields); err != nil {
 		return err
 	}
+	// the fields are located by their sizes, so a record length that doesn't
+	// match them would shift every attribute
+	recordLength := dbfRowDeletionFlagSz
+	for _, f := range r.dbfFields {
+		recordLength += int(f.Size)
+	}
+	if recordLength != int(r.dbfRecordLength) {
+		return NewShapeError(ErrCorruptedFile,
+			fmt.Sprintf("DBF record length %d does not match the %d bytes of its fields", r.dbfRecordLength, recordLength), nil)
+	}
+	if r.config != nil && r.config.SkipSystemFields {
+		r.dbfVisible, r.dbfFieldIndex = visibleDbfFields(r.dbfFields)
+	}
 	return
 }
 
+// geometryOnly reports whether the Reader was opened with WithGeometryOnly
+// and never reads the DBF file.
+func (r *Reader) geometryOnly() bool {
+	return r.config != nil && r.config.GeometryOnly
+}
+
 // Fields returns a slice of Fields that are present in the
-// DBF table.
+// DBF table. System fields are left out if the Reader was
+// opened with WithSkipSystemFields.
 func (r *Reader) Fields() []Field {
 	_ = r.openDbf() // make sure we have dbf file to read from
+	if r.dbfFieldIndex != nil {
+		return r.dbfVisible
+	}
 	return r.dbfFields
 }
 
@@ -432,6 +745,45 @@ func (r *Reader) Err() error {
 	return r.err
 }
 
+// Encoding returns the string encoding of the DBF table: the encoding set with
+// WithAttributeEncoding, else the content of the .cpg file, else the code page
+// of the language driver ID in the DBF header. It returns an empty string if
+// the encoding is unknown.
+func (r *Reader) Encoding() string {
+	if r.encodingLoaded {
+		return r.encoding
+	}
+	r.encodingLoaded = true
+	if r.config != nil && r.config.AttributeEncoding != "" {
+		r.encoding = r.config.AttributeEncoding
+	} else if cpg, err := os.ReadFile(r.filename + ".cpg"); err == nil && len(bytes.TrimSpace(cpg)) > 0 {
+		r.encoding = string(bytes.TrimSpace(cpg))
+	} else if r.openDbf() == nil {
+		r.encoding = dbfCodePageNames[r.dbfCodePage]
+	}
+	return r.encoding
+}
+
+// DbfHeaderReserved returns a copy of the 20 reserved bytes at offsets 12 to
+// 31 of the DBF header, where dBASE and other tools keep the transaction and
+// encryption flags, the work area ID, the MDX flag and the language driver ID.
+// It returns nil if there is no DBF. Append and Pack keep these bytes.
+func (r *Reader) DbfHeaderReserved() []byte {
+	if r.openDbf() != nil {
+		return nil
+	}
+	return append([]byte(nil), r.dbfReserved...)
+}
+
+// WorkAreaID returns the dBASE work area ID stored in the DBF header, or 0 if
+// there is no DBF.
+func (r *Reader) WorkAreaID() byte {
+	if r.openDbf() != nil || len(r.dbfReserved) < dbfHeaderPaddingLen {
+		return 0
+	}
+	return r.dbfReserved[dbfOffsetWorkAreaID-dbfOffsetPadding]
+}
+
 // AttributeCount returns number of records in the DBF table.
 func (r *Reader) AttributeCount() int {
 	_ = r.openDbf() // make sure we have a dbf file to read from
@@ -439,9 +791,32 @@ func (r *Reader) AttributeCount() int {
 }
 
 // ReadAttribute returns the attribute value at row for field in
-// the DBF table as a string. Both values starts at 0.
+// the DBF table as a string. Both values starts at 0. An empty string is
+// returned if row is outside of the DBF table; use ReadAttributeChecked to
+// tell that apart from an empty value.
 func (r *Reader) ReadAttribute(row int, field int) string {
-	_ = r.openDbf() // make sure we have a dbf file to read from
+	value, _ := r.ReadAttributeChecked(row, field)
+	return value
+}
+
+// ReadAttributeChecked is like ReadAttribute, but returns an error of type
+// ErrInvalidField with a *RowOutOfRangeError cause if row is outside of the
+// DBF table, which happens when the DBF has fewer records than the SHP file.
+// Readers opened with WithGeometryOnly always return an empty string.
+func (r *Reader) ReadAttributeChecked(row int, field int) (string, error) {
+	if r.geometryOnly() {
+		return "", nil
+	}
+	if err := r.openDbf(); err != nil { // make sure we have a dbf file to read from
+		return "", NewShapeError(ErrIO, "failed to open DBF", err)
+	}
+	if row < 0 || row >= int(r.dbfNumRecords) {
+		return "", NewShapeError(ErrInvalidField, "attribute row out of range",
+			&RowOutOfRangeError{Row: row, NumRecords: int(r.dbfNumRecords)})
+	}
+	if r.dbfFieldIndex != nil {
+		field = r.dbfFieldIndex[field]
+	}
 	seekTo := dbfFieldOffset(r.dbfHeaderLength, r.dbfRecordLength, row, r.dbfFields, field)
 	_, _ = r.dbf.Seek(seekTo, io.SeekStart)
 	size := int(r.dbfFields[field].Size)
@@ -450,9 +825,79 @@ func (r *Reader) ReadAttribute(row int, field int) string {
 	}
 	buf := r.attrBuf[:size]
 	_, _ = r.dbf.Read(buf)
+	if value, ok := binaryFieldValue(r.dbfFields[field], buf); ok {
+		return value, nil
+	}
 	// trim spaces without creating an intermediate string
 	trimmed := bytesTrimSpaceRight(buf)
-	return string(trimmed)
+	return string(trimmed), nil
+}
+
+// ReadAttributeTyped returns the attribute of the field at index field of
+// record row converted according to the DBF field type, like RecordTyped does
+// for sequential readers. Numeric values written with digit grouping, a
+// leading '+' or a decimal comma are parsed too; values that cannot be parsed
+// are returned as strings.
+func (r *Reader) ReadAttributeTyped(row int, field int) interface{} {
+	if r.geometryOnly() {
+		return nil
+	}
+	value := trimAttribute(r.ReadAttribute(row, field))
+	return typedAttribute(r.Fields()[field], value)
+}
+
+// FieldIndex returns the index of the field named name among the fields
+// returned by Fields, matching the name ignoring case as DBF field names are.
+// It returns an error of type ErrInvalidField if there is no such field.
+func (r *Reader) FieldIndex(name string) (int, error) {
+	if err := r.openDbf(); err != nil {
+		return 0, NewShapeError(ErrIO, "failed to open DBF", err)
+	}
+	if r.dbfNameIndex == nil {
+		fields := r.Fields()
+		r.dbfNameIndex = make(map[string]int, len(fields))
+		for i, f := range fields {
+			key := strings.ToUpper(f.String())
+			if _, ok := r.dbfNameIndex[key]; !ok {
+				r.dbfNameIndex[key] = i
+			}
+		}
+	}
+	field, ok := r.dbfNameIndex[strings.ToUpper(name)]
+	if !ok {
+		return 0, NewShapeError(ErrInvalidField, fmt.Sprintf("unknown field %q", name), nil)
+	}
+	return field, nil
+}
+
+// ReadAttributeByName is ReadAttributeChecked for the field named fieldName,
+// for code that refers to columns by name regardless of their position.
+func (r *Reader) ReadAttributeByName(row int, fieldName string) (string, error) {
+	if r.geometryOnly() {
+		return "", nil
+	}
+	field, err := r.FieldIndex(fieldName)
+	if err != nil {
+		return "", err
+	}
+	return r.ReadAttributeChecked(row, field)
+}
+
+// ReadAttributeTypedByName is ReadAttributeTyped for the field named
+// fieldName. It returns the same errors as ReadAttributeByName.
+func (r *Reader) ReadAttributeTypedByName(row int, fieldName string) (interface{}, error) {
+	if r.geometryOnly() {
+		return nil, nil
+	}
+	field, err := r.FieldIndex(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	value, err := r.ReadAttributeChecked(row, field)
+	if err != nil {
+		return nil, err
+	}
+	return typedAttribute(r.Fields()[field], trimAttribute(value)), nil
 }
 
 // bytesTrimSpaceRight trims ASCII spaces on both ends, optimized for DBF which uses space padding.
@@ -469,3 +914,273 @@ func bytesTrimSpaceRight(b []byte) []byte {
 	}
 	return b[i:j]
 }
+
+// ReadShapeAt reads the shape with the given zero-based index without
+// affecting the position used by Next. Record offsets are taken from the
+// SHX file; if it is missing they are rebuilt once by scanning the SHP file.
+func (r *Reader) ReadShapeAt(index int) (Shape, error) {
+	if err := r.loadOffsets(); err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(r.offsets) {
+		return nil, NewShapeError(ErrInvalidFormat,
+			fmt.Sprintf("shape index %d out of range [0, %d)", index, len(r.offsets)), nil)
+	}
+
+	cur, err := r.shp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, NewShapeError(ErrIO, "failed to get current position", err)
+	}
+	defer func() { _, _ = r.shp.Seek(cur, io.SeekStart) }()
+
+	return r.readShapeAtOffset(index)
+}
+
+// ReadShapes reads the shapes with the given zero-based indices, e.g. the
+// result of a spatial index query, without affecting the position used by
+// Next. The shapes are returned in the order of indices, but read in file
+// order so that the SHP file is traversed at most once; an index listed more
+// than once yields the same Shape value each time. Record offsets are taken
+// from the SHX file as for ReadShapeAt. An index out of range is an error of
+// type ErrInvalidFormat and nothing is read.
+func (r *Reader) ReadShapes(indices []int) ([]Shape, error) {
+	if err := r.loadOffsets(); err != nil {
+		return nil, err
+	}
+	for _, index := range indices {
+		if index < 0 || index >= len(r.offsets) {
+			return nil, NewShapeError(ErrInvalidFormat,
+				fmt.Sprintf("shape index %d out of range [0, %d)", index, len(r.offsets)), nil)
+		}
+	}
+
+	order := make([]int, len(indices))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return r.offsets[indices[order[i]]] < r.offsets[indices[order[j]]]
+	})
+
+	cur, err := r.shp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, NewShapeError(ErrIO, "failed to get current position", err)
+	}
+	defer func() { _, _ = r.shp.Seek(cur, io.SeekStart) }()
+
+	shapes := make([]Shape, len(indices))
+	for k, i := range order {
+		if k > 0 && indices[order[k-1]] == indices[i] {
+			shapes[i] = shapes[order[k-1]]
+			continue
+		}
+		shape, err := r.readShapeAtOffset(indices[i])
+		if err != nil {
+			return nil, err
+		}
+		shapes[i] = shape
+	}
+	return shapes, nil
+}
+
+// readShapeAtOffset seeks to the record with the given index, which must be
+// in range of the loaded offsets, and reads its shape.
+func (r *Reader) readShapeAtOffset(index int) (Shape, error) {
+	if _, err := r.shp.Seek(r.offsets[index], io.SeekStart); err != nil {
+		return nil, NewShapeError(ErrIO, fmt.Sprintf("failed to seek to shape %d", index), err)
+	}
+	_, _, shapetype, err := readShapeRecordHeader(r.shp)
+	if err != nil {
+		return nil, NewShapeError(ErrCorruptedFile, fmt.Sprintf("failed to read header of shape %d", index), err)
+	}
+	shape, err := newShape(shapetype)
+	if err != nil {
+		return nil, err
+	}
+	er := &errReader{Reader: r.shp}
+	shape.read(er)
+	if er.e != nil {
+		return nil, NewShapeError(ErrCorruptedFile, fmt.Sprintf("failed to read shape %d", index), er.e)
+	}
+	return shape, nil
+}
+
+// SeekRecord positions the Reader so that the next call to Next reads the
+// shape with the given zero-based index, e.g. to resume processing after an
+// interruption. Record offsets are taken from the SHX file; if it is missing
+// they are rebuilt once by scanning the SHP file. Seeking to the number of
+// records is allowed and makes Next return false.
+func (r *Reader) SeekRecord(index int) error {
+	if err := r.loadOffsets(); err != nil {
+		return err
+	}
+	if index < 0 || index > len(r.offsets) {
+		return NewShapeError(ErrInvalidFormat,
+			fmt.Sprintf("record %d out of range [0, %d]", index, len(r.offsets)), nil)
+	}
+	if r.indexOrder() {
+		r.indexPos = index
+		return nil
+	}
+	r.verifyRow = index
+	pos := r.filelength
+	if index < len(r.offsets) {
+		pos = r.offsets[index]
+	}
+	if _, err := r.shp.Seek(pos, io.SeekStart); err != nil {
+		return NewShapeError(ErrIO, fmt.Sprintf("failed to seek to shape %d", index), err)
+	}
+	r.resetPointBlock()
+	return nil
+}
+
+// CheckRecordCount compares the number of records in the SHP file, taken from
+// the SHX file or by scanning the SHP file, with the number of records in the
+// DBF file. It returns an error of type ErrCorruptedFile with a
+// *RecordCountMismatchError cause if they differ. Shapefiles without a DBF
+// file and Readers opened with WithGeometryOnly are not checked.
+func (r *Reader) CheckRecordCount() error {
+	if r.geometryOnly() {
+		return nil
+	}
+	if err := r.openDbf(); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return NewShapeError(ErrIO, "failed to open DBF", err)
+	}
+	if err := r.loadOffsets(); err != nil {
+		return err
+	}
+	if len(r.offsets) != int(r.dbfNumRecords) {
+		return NewShapeError(ErrCorruptedFile, "record count mismatch between SHP and DBF",
+			&RecordCountMismatchError{ShapeRecords: len(r.offsets), AttributeRecords: int(r.dbfNumRecords)})
+	}
+	return nil
+}
+
+// CheckRecordNumbers reads the record number of every record and returns an
+// issue for each record whose number is not its zero-based position plus one
+// or was already used by a previous record, which indicates gaps or
+// duplicates. The Err of every issue is a
+// *RecordNumberError. The position used by Next is not affected.
+func (r *Reader) CheckRecordNumbers() ([]ValidationIssue, error) {
+	if err := r.loadOffsets(); err != nil {
+		return nil, err
+	}
+	cur, err := r.shp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, NewShapeError(ErrIO, "failed to get current position", err)
+	}
+	defer func() { _, _ = r.shp.Seek(cur, io.SeekStart) }()
+
+	var issues []ValidationIssue
+	seen := make(map[int32]bool, len(r.offsets))
+	for i, offset := range r.offsets {
+		if _, err := r.shp.Seek(offset, io.SeekStart); err != nil {
+			return nil, NewShapeError(ErrIO, fmt.Sprintf("failed to seek to shape %d", i), err)
+		}
+		var num int32
+		er := &errReader{Reader: r.shp}
+		readBE(er, &num)
+		if er.e != nil {
+			return nil, NewShapeError(ErrCorruptedFile, fmt.Sprintf("failed to read header of shape %d", i), er.e)
+		}
+		if expected := int32(i + 1); num != expected || seen[num] {
+			issues = append(issues, ValidationIssue{
+				Row: i,
+				Err: &RecordNumberError{Number: num, Expected: expected, Duplicate: seen[num]},
+			})
+		}
+		seen[num] = true
+	}
+	return issues, nil
+}
+
+// CheckRecordOrder compares the record offsets in the SHX file with the
+// offsets of the records in the physical order of the SHP file and returns an
+// issue for every position at which they differ, e.g. because a tool
+// reordered the index without reordering the SHP file. The Err of every
+// issue is a *RecordOrderError. Shapefiles without a SHX file are not
+// checked. The position used by Next is not affected.
+func (r *Reader) CheckRecordOrder() ([]ValidationIssue, error) {
+	shx, err := os.Open(r.filename + ".shx")
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewShapeError(ErrIO, "failed to open shapefile index", err)
+	}
+	defer func() { _ = shx.Close() }()
+	indexOffsets, err := readShxOffsets(shx)
+	if err != nil {
+		return nil, err
+	}
+	fileOffsets, err := r.scanOffsets()
+	if err != nil {
+		return nil, err
+	}
+
+	offsetAt := func(offsets []int64, i int) int64 {
+		if i < len(offsets) {
+			return offsets[i]
+		}
+		return -1
+	}
+	var issues []ValidationIssue
+	for i := 0; i < len(indexOffsets) || i < len(fileOffsets); i++ {
+		indexOffset, fileOffset := offsetAt(indexOffsets, i), offsetAt(fileOffsets, i)
+		if indexOffset != fileOffset {
+			issues = append(issues, ValidationIssue{
+				Row: i,
+				Err: &RecordOrderError{IndexOffset: indexOffset, FileOffset: fileOffset},
+			})
+		}
+	}
+	return issues, nil
+}
+
+// loadOffsets fills r.offsets from the SHX file, or by scanning the SHP file
+// if the SHX file does not exist.
+func (r *Reader) loadOffsets() error {
+	if r.offsets != nil {
+		return nil
+	}
+	shx, err := os.Open(r.filename + ".shx")
+	if os.IsNotExist(err) {
+		r.debugf("SHX file not found, rebuilding record offsets from %s.shp\n", r.filename)
+		r.offsets, err = r.scanOffsets()
+		return err
+	}
+	if err != nil {
+		return NewShapeError(ErrIO, "failed to open shapefile index", err)
+	}
+	defer func() { _ = shx.Close() }()
+	r.offsets, err = readShxOffsets(shx)
+	return err
+}
+
+// scanOffsets walks the record headers of the SHP file and returns their
+// byte offsets. The current position of the SHP file is restored afterwards.
+func (r *Reader) scanOffsets() ([]int64, error) {
+	cur, err := r.shp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, NewShapeError(ErrIO, "failed to get current position", err)
+	}
+	defer func() { _, _ = r.shp.Seek(cur, io.SeekStart) }()
+
+	offsets := make([]int64, 0)
+	for pos := int64(shpHeaderLen); pos+8 <= r.filelength; {
+		if _, err := r.shp.Seek(pos, io.SeekStart); err != nil {
+			return nil, NewShapeError(ErrIO, "failed to seek to record header", err)
+		}
+		_, size, _, err := readShapeRecordHeader(r.shp)
+		if err != nil || size < 0 {
+			return nil, NewShapeError(ErrCorruptedFile,
+				fmt.Sprintf("invalid record header at position %d", pos), err)
+		}
+		offsets = append(offsets, pos)
+		pos += int64(size)*2 + 8
+	}
+	return offsets, nil
+}
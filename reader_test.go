@@ -2,7 +2,13 @@ package shp
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"io"
+	"math"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -525,3 +531,1044 @@ func TestReadInvalidShapeType(t *testing.T) {
 		})
 	}
 }
+
+func TestReadSkipSystemFields(t *testing.T) {
+	filename := filenamePrefix + "sysfields"
+	defer removeShapefile(filename)
+
+	nullFlags := Field{Fieldtype: dbfFieldTypeNullFlag, Size: 1}
+	copy(nullFlags.Name[:], "_NullFlags")
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("A", 3), nullFlags, StringField("B", 3)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	_ = w.WriteAttribute(0, 0, "foo")
+	_ = w.WriteAttribute(0, 2, "bar")
+	w.Close()
+
+	r, err := Open(filename+".shp", WithSkipSystemFields(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	fields := r.Fields()
+	if len(fields) != 2 || fields[0].String() != "A" || fields[1].String() != "B" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+	if got := r.ReadAttribute(0, 1); got != "bar" {
+		t.Errorf("got attribute %q, want %q", got, "bar")
+	}
+}
+
+func TestReadBBoxFilter(t *testing.T) {
+	r, err := Open("test_files/point.shp", WithBBoxFilter(Box{4, 4, 11, 11}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	count := 0
+	for r.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("got %d shapes, want 2", count)
+	}
+	if want := (Box{5, 5, 10, 10}); r.FilteredBBox() != want {
+		t.Errorf("got filtered bbox %v, want %v", r.FilteredBBox(), want)
+	}
+}
+
+func TestFilteredBBoxSkipsNull(t *testing.T) {
+	filename := t.TempDir() + "/points.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Null{})
+	w.Write(&Point{5, 5})
+	w.Write(&Null{})
+	w.Write(&Point{10, 8})
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	count := 0
+	for r.Next() {
+		count++
+	}
+	if count != 4 {
+		t.Errorf("got %d shapes, want 4", count)
+	}
+	if want := (Box{5, 5, 10, 8}); r.FilteredBBox() != want {
+		t.Errorf("got filtered bbox %v, want %v", r.FilteredBBox(), want)
+	}
+}
+
+func TestReadShapeAt(t *testing.T) {
+	filename := t.TempDir() + "/points.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		w.Write(&Point{float64(i), float64(-i)})
+	}
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if !r.Next() || !r.Next() {
+		t.Fatal("failed to read the first shapes")
+	}
+	for _, i := range []int{99, 0, 50} {
+		shape, err := r.ReadShapeAt(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (&Point{float64(i), float64(-i)}); !reflect.DeepEqual(shape, want) {
+			t.Errorf("shape %d: got %+v, want %+v", i, shape, want)
+		}
+	}
+	for _, i := range []int{-1, 100} {
+		if _, err := r.ReadShapeAt(i); err == nil {
+			t.Errorf("read shape %d without error", i)
+		}
+	}
+
+	// the next shape is still the third one
+	if !r.Next() {
+		t.Fatal(r.Err())
+	}
+	if n, shape := r.Shape(); n != 2 || !reflect.DeepEqual(shape, &Point{2, -2}) {
+		t.Errorf("got shape %d %+v after random access, want shape 2", n, shape)
+	}
+}
+
+func TestReadShapeAtWithoutShx(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("test_files/polyline.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/polyline.shp", data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	withShx, err := Open("test_files/polyline.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer withShx.Close()
+	withoutShx, err := Open(dir + "/polyline.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer withoutShx.Close()
+
+	for i := 0; i < 2; i++ {
+		want, err := withShx.ReadShapeAt(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := withoutShx.ReadShapeAt(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("shape %d: got %+v, want %+v", i, got, want)
+		}
+	}
+	if _, err := withoutShx.ReadShapeAt(2); err == nil {
+		t.Error("read shape beyond the last record without error")
+	}
+
+	// random access must not disturb sequential reading
+	count := 0
+	for withoutShx.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("got %d shapes after random access, want 2", count)
+	}
+}
+
+func TestReadAttributeByName(t *testing.T) {
+	filename := t.TempDir() + "/byname.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 10), NumberField("POPULATION", 8)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	if err := w.WriteAttribute(0, 0, "town"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteAttribute(0, 1, 1200); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if v, err := r.ReadAttributeByName(0, "Population"); err != nil || v != "1200" {
+		t.Errorf("got %q, %v; want 1200", v, err)
+	}
+	if v, err := r.ReadAttributeTypedByName(0, "POPULATION"); err != nil || v != int64(1200) {
+		t.Errorf("typed: got %#v, %v; want int64(1200)", v, err)
+	}
+	if v, err := r.ReadAttributeTypedByName(0, "NAME"); err != nil || v != "town" {
+		t.Errorf("typed: got %#v, %v; want town", v, err)
+	}
+	if _, err := r.ReadAttributeByName(0, "AREA"); !errors.Is(err, NewShapeError(ErrInvalidField, "", nil)) {
+		t.Errorf("unknown field: expected invalid field error, got %v", err)
+	}
+	var rowErr *RowOutOfRangeError
+	if _, err := r.ReadAttributeByName(1, "NAME"); !errors.As(err, &rowErr) {
+		t.Errorf("row 1: expected RowOutOfRangeError, got %v", err)
+	}
+}
+
+func TestReadAttributeTyped(t *testing.T) {
+	filename := filenamePrefix + "typed"
+	defer removeShapefile(filename)
+
+	values := []struct {
+		raw  string
+		want interface{}
+	}{
+		{"1234", 1234.0},
+		{"+42", 42.0},
+		{"1,234,567", 1234567.0},
+		{"1,234.50", 1234.5},
+		{"1.234,50", 1234.5},
+		{"3,14", 3.14},
+		{"12 345", 12345.0},
+		{"n/a", "n/a"},
+		{"", nil},
+	}
+
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{NumberField("INT", 12), FloatField("FLOAT", 12, 2)}); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range values {
+		w.Write(&Point{1, 1})
+		_ = w.WriteAttribute(i, 0, v.raw)
+		_ = w.WriteAttribute(i, 1, v.raw)
+	}
+	w.Close()
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for i, v := range values {
+		if got := r.ReadAttributeTyped(i, 1); got != v.want {
+			t.Errorf("float %q: got %#v, want %#v", v.raw, got, v.want)
+		}
+		want := v.want
+		if f, ok := want.(float64); ok && f == math.Trunc(f) {
+			want = int64(f)
+		}
+		if got := r.ReadAttributeTyped(i, 0); got != want {
+			t.Errorf("int %q: got %#v, want %#v", v.raw, got, want)
+		}
+	}
+}
+
+func TestOpenTruncatedFile(t *testing.T) {
+	filename := filenamePrefix + "truncated"
+	defer removeShapefile(filename)
+
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	w.Write(&Point{2, 2})
+	w.Close()
+
+	stat, err := os.Stat(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(filename+".shp", stat.Size()-10); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Open(filename+".shp", WithTrustHeaderLength(true))
+	if !errors.Is(err, NewShapeError(ErrCorruptedFile, "", nil)) {
+		t.Fatalf("expected corrupted file error, got %v", err)
+	}
+	var lengthErr *HeaderLengthError
+	if !errors.As(err, &lengthErr) {
+		t.Fatalf("expected HeaderLengthError, got %v", err)
+	}
+	if lengthErr.Reported != stat.Size() || lengthErr.Actual != stat.Size()-10 {
+		t.Errorf("got sizes %d/%d, want %d/%d", lengthErr.Reported, lengthErr.Actual, stat.Size(), stat.Size()-10)
+	}
+
+	// by default the records before the truncation are still readable
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if !r.Next() {
+		t.Fatalf("expected the first record, got error %v", r.Err())
+	}
+	if r.Next() || r.Err() == nil {
+		t.Error("expected an error for the truncated second record")
+	}
+}
+
+func TestOpenPaddedFile(t *testing.T) {
+	filename := t.TempDir() + "/padded.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	w.Write(&Point{2, 2})
+	w.Close()
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	count := func(opts ...ReaderOption) (int, error) {
+		r, err := Open(filename, opts...)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		n := 0
+		for r.Next() {
+			n++
+		}
+		return n, r.Err()
+	}
+	if n, err := count(); n != 2 || err != nil {
+		t.Errorf("default: got %d records, %v; want 2 records", n, err)
+	}
+	if n, err := count(WithTrustHeaderLength(true)); n != 2 || err != nil {
+		t.Errorf("trusting the header: got %d records, %v; want 2 records", n, err)
+	}
+	// the padding is read as records
+	if n, err := count(WithTrustFileSize(true)); n == 2 && err == nil {
+		t.Error("trusting the file size: expected the padding to be read")
+	}
+	// false only clears the source set by the same option
+	if n, err := count(WithTrustHeaderLength(true), WithTrustFileSize(false)); n != 2 || err != nil {
+		t.Errorf("trusting the header, not the file size: got %d records, %v; want 2 records", n, err)
+	}
+	if n, err := count(WithTrustFileSize(true), WithTrustHeaderLength(false)); n == 2 && err == nil {
+		t.Error("trusting the file size, not the header: expected the padding to be read")
+	}
+}
+
+func TestOpenFileLongerThanHeader(t *testing.T) {
+	filename := t.TempDir() + "/longer.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	w.Write(&Point{2, 2})
+	w.Close()
+
+	// a valid third record that the header does not account for
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := append([]byte(nil), data[len(data)-28:]...)
+	binary.BigEndian.PutUint32(record, 3)
+	if err := os.WriteFile(filename, append(data, record...), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(opts ...ReaderOption) int {
+		r, err := Open(filename, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		n := 0
+		for r.Next() {
+			n++
+		}
+		if err := r.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	// by default iteration stops at the shorter of the two lengths
+	if n := count(); n != 2 {
+		t.Errorf("default: got %d records, want 2", n)
+	}
+	if n := count(WithTrustHeaderLength(true)); n != 2 {
+		t.Errorf("trusting the header: got %d records, want 2", n)
+	}
+	if n := count(WithTrustFileSize(true)); n != 3 {
+		t.Errorf("trusting the file size: got %d records, want 3", n)
+	}
+}
+
+func TestReadMetadata(t *testing.T) {
+	m, err := ReadMetadata("test_files/polygon.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.GeometryType != POLYGON || m.NumRecords != 1 || len(m.Fields) == 0 || m.Projection != "" {
+		t.Errorf("unexpected metadata %+v", m)
+	}
+
+	filename := t.TempDir() + "/meta"
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 2})
+	w.Write(&Point{3, 4})
+	w.Close()
+	if err := os.Remove(filename + ".dbf"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filename + ".shx"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename+".prj", []byte("GEOGCS[\"WGS 84\"]\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = ReadMetadata(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metadata{GeometryType: POINT, BBox: Box{1, 2, 3, 4}, NumRecords: 2, Projection: "GEOGCS[\"WGS 84\"]"}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got metadata %+v, want %+v", m, want)
+	}
+}
+
+func TestRecordCountMismatch(t *testing.T) {
+	filename := t.TempDir() + "/mismatch"
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 10)}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		w.Write(&Point{float64(i), float64(i)})
+		if err := w.WriteAttribute(i, 0, "p"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	// drop the last DBF record from the header
+	dbf, err := os.OpenFile(filename+".dbf", os.O_RDWR, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbf.WriteAt([]byte{2, 0, 0, 0}, dbfOffsetNumRecords); err != nil {
+		t.Fatal(err)
+	}
+	_ = dbf.Close()
+
+	_, err = Open(filename+".shp", WithStrictRecordCount(true))
+	var countErr *RecordCountMismatchError
+	if !errors.As(err, &countErr) {
+		t.Fatalf("expected RecordCountMismatchError, got %v", err)
+	}
+	if countErr.ShapeRecords != 3 || countErr.AttributeRecords != 2 {
+		t.Errorf("got counts %d/%d, want 3/2", countErr.ShapeRecords, countErr.AttributeRecords)
+	}
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if v, err := r.ReadAttributeChecked(1, 0); err != nil || v != "p" {
+		t.Errorf("row 1: got %q, %v", v, err)
+	}
+	v, err := r.ReadAttributeChecked(2, 0)
+	var rowErr *RowOutOfRangeError
+	if !errors.As(err, &rowErr) || rowErr.Row != 2 || rowErr.NumRecords != 2 {
+		t.Errorf("row 2: expected RowOutOfRangeError, got %q, %v", v, err)
+	}
+	if v := r.ReadAttribute(2, 0); v != "" {
+		t.Errorf("row 2: got %q, want empty string", v)
+	}
+}
+
+func TestDbfRecordLengthMismatch(t *testing.T) {
+	filename := t.TempDir() + "/reclen"
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 10)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	if err := w.WriteAttribute(0, 0, "p"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	// declare one byte more than the deletion flag and the field need
+	dbf, err := os.OpenFile(filename+".dbf", os.O_RDWR, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbf.WriteAt([]byte{12, 0}, dbfOffsetRecordLen); err != nil {
+		t.Fatal(err)
+	}
+	_ = dbf.Close()
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.ReadAttributeChecked(0, 0); !errors.Is(err, NewShapeError(ErrCorruptedFile, "", nil)) {
+		t.Errorf("expected ErrCorruptedFile, got %v", err)
+	}
+	if fields := r.Fields(); fields != nil {
+		t.Errorf("got fields %v, want none", fields)
+	}
+}
+
+func TestDumpHeader(t *testing.T) {
+	filename := t.TempDir() + "/dump"
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 10), NumberField("POP", 8)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	if err := w.WriteAttribute(0, 0, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	// break the record length so that the DBF can't be opened
+	dbf, err := os.OpenFile(filename+".dbf", os.O_RDWR, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbf.WriteAt([]byte{99, 0}, dbfOffsetRecordLen); err != nil {
+		t.Fatal(err)
+	}
+	_ = dbf.Close()
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.ReadAttributeChecked(0, 0); err == nil {
+		t.Fatal("expected the DBF to fail to open")
+	}
+
+	descriptors, err := r.RawFieldDescriptors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descriptors) != 2 {
+		t.Fatalf("got %d descriptors, want 2", len(descriptors))
+	}
+	if name := string(descriptors[1][:3]); name != "POP" || descriptors[1][11] != 'N' || descriptors[1][16] != 8 {
+		t.Errorf("unexpected descriptor % x", descriptors[1])
+	}
+
+	dump := r.DumpHeader()
+	for _, want := range []string{
+		"file code:   9994",
+		"shape type:  POINT",
+		"record length:   99",
+		`name "NAME", type 'C', size 10`,
+		`name "POP", type 'N', size 8`,
+		"19 bytes per record",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump does not contain %q:\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, "secret") {
+		t.Error("dump contains record data")
+	}
+	if !r.Next() {
+		t.Error("DumpHeader moved the reader")
+	}
+}
+
+func TestRecordOrderIndex(t *testing.T) {
+	filename := t.TempDir() + "/order"
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		w.Write(&Point{float64(i), float64(i)})
+	}
+	w.Close()
+
+	// swap the first and the last entry of the index
+	shx, err := os.ReadFile(filename + ".shx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := append([]byte(nil), shx[100:108]...)
+	copy(shx[100:108], shx[116:124])
+	copy(shx[116:124], first)
+	if err := os.WriteFile(filename+".shx", shx, 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	xs := func(opts ...ReaderOption) []float64 {
+		r, err := Open(filename+".shp", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		var xs []float64
+		for r.Next() {
+			n, shape := r.Shape()
+			if n != len(xs) {
+				t.Errorf("got row %d, want %d", n, len(xs))
+			}
+			xs = append(xs, shape.(*Point).X)
+		}
+		if err := r.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return xs
+	}
+	if got := xs(); !reflect.DeepEqual(got, []float64{0, 1, 2}) {
+		t.Errorf("file order: got %v", got)
+	}
+	if got := xs(WithRecordOrder(RecordOrderIndex)); !reflect.DeepEqual(got, []float64{2, 1, 0}) {
+		t.Errorf("index order: got %v", got)
+	}
+
+	r, err := Open(filename+".shp", WithRecordOrder(RecordOrderIndex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	issues, err := r.CheckRecordOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].Row != 0 || issues[1].Row != 2 {
+		t.Fatalf("got issues %v", issues)
+	}
+	var orderErr *RecordOrderError
+	if !errors.As(issues[0].Err, &orderErr) || orderErr.IndexOffset != 156 || orderErr.FileOffset != 100 {
+		t.Errorf("got %v", issues[0].Err)
+	}
+
+	if err := r.SeekRecord(2); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Next() {
+		t.Fatal(r.Err())
+	}
+	if n, shape := r.Shape(); n != 2 || shape.(*Point).X != 0 {
+		t.Errorf("after SeekRecord(2): got row %d, shape %v", n, shape)
+	}
+	if r.Next() {
+		t.Error("read past the last index entry")
+	}
+}
+
+func TestPaddedPointRecords(t *testing.T) {
+	filename := t.TempDir() + "/padded.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		w.Write(&Point{float64(i), float64(i)})
+	}
+	w.Close()
+
+	// append four bytes of padding to every record
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	padded := append([]byte(nil), data[:100]...)
+	for pos := 100; pos < len(data); pos += 28 {
+		record := append([]byte(nil), data[pos:pos+28]...)
+		binary.BigEndian.PutUint32(record[4:], 12)
+		padded = append(append(padded, record...), 0, 0, 0, 0)
+	}
+	binary.BigEndian.PutUint32(padded[24:], uint32(len(padded)/2))
+	if err := os.WriteFile(filename, padded, 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	var debug bytes.Buffer
+	r, err := Open(filename, WithDebug(true), WithLogger(&debug))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var xs []float64
+	for r.Next() {
+		_, shape := r.Shape()
+		xs = append(xs, shape.(*Point).X)
+	}
+	r.Close()
+
+	if err := r.Err(); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(xs, []float64{0, 1, 2}) {
+		t.Errorf("got points %v", xs)
+	}
+	if !strings.Contains(debug.String(), "Processing shape #3") {
+		t.Errorf("debug output was not written to the logger:\n%s", debug.String())
+	}
+	if strings.Contains(debug.String(), "position mismatch") {
+		t.Errorf("got a position mismatch warning for padded points:\n%s", debug.String())
+	}
+}
+
+func TestGeometryOnly(t *testing.T) {
+	filename := t.TempDir() + "/geometry"
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 10)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 2})
+	w.Write(&Point{3, 4})
+	w.Close()
+
+	// a DBF that can't be parsed must not get in the way
+	if err := os.WriteFile(filename+".dbf", []byte("garbage"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename+".shp", WithGeometryOnly(true), WithStrictRecordCount(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if fields := r.Fields(); len(fields) != 0 {
+		t.Errorf("got fields %v, want none", fields)
+	}
+	n := 0
+	for r.Next() {
+		_, shape := r.Shape()
+		if p, ok := shape.(*Point); !ok || p.X != float64(2*n+1) {
+			t.Errorf("shape %d = %v", n, shape)
+		}
+		if value, err := r.ReadAttributeChecked(n, 0); value != "" || err != nil {
+			t.Errorf("ReadAttributeChecked(%d, 0) = %q, %v", n, value, err)
+		}
+		if value := r.ReadAttributeTyped(n, 0); value != nil {
+			t.Errorf("ReadAttributeTyped(%d, 0) = %v", n, value)
+		}
+		n++
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("read %d shapes, want 2", n)
+	}
+	if r.dbf != nil {
+		t.Error("DBF was opened")
+	}
+}
+
+func TestCheckRecordNumbers(t *testing.T) {
+	filename := t.TempDir() + "/numbers.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		w.Write(&Point{float64(i), float64(i)})
+	}
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues, err := r.CheckRecordNumbers(); err != nil || len(issues) != 0 {
+		t.Errorf("valid file: got issues %v, %v", issues, err)
+	}
+	r.Close()
+
+	// renumber the records 1, 3, 3, 4: record 1 skips a number, record 2
+	// repeats it
+	f, err := os.OpenFile(filename, os.O_RDWR, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordLen := int64(8 + 20) // header and POINT content
+	for i, num := range []byte{1, 3, 3, 4} {
+		if _, err := f.WriteAt([]byte{0, 0, 0, num}, shpHeaderLen+int64(i)*recordLen); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = f.Close()
+
+	r, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Next()
+	r.Next()
+	if num := r.RecordNumber(); num != 3 {
+		t.Errorf("got record number %d, want 3", num)
+	}
+	issues, err := r.CheckRecordNumbers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ValidationIssue{
+		{Row: 1, Err: &RecordNumberError{Number: 3, Expected: 2}},
+		{Row: 2, Err: &RecordNumberError{Number: 3, Expected: 3, Duplicate: true}},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("got issues %v, want %v", issues, want)
+	}
+	// the position used by Next is unchanged
+	if !r.Next() || r.RecordNumber() != 3 {
+		t.Errorf("Next after CheckRecordNumbers returned record %d", r.RecordNumber())
+	}
+}
+
+func TestReadShapes(t *testing.T) {
+	filename := t.TempDir() + "/points.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		w.Write(&Point{X: float64(i), Y: float64(i * 10)})
+	}
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if !r.Next() {
+		t.Fatal("no records")
+	}
+
+	indices := []int{7, 2, 7, 0, 9}
+	shapes, err := r.ReadShapes(indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shapes) != len(indices) {
+		t.Fatalf("got %d shapes, want %d", len(shapes), len(indices))
+	}
+	for i, index := range indices {
+		want := &Point{X: float64(index), Y: float64(index * 10)}
+		if !reflect.DeepEqual(shapes[i], want) {
+			t.Errorf("shape %d: got %+v, want %+v", i, shapes[i], want)
+		}
+	}
+	if shapes, err := r.ReadShapes(nil); err != nil || len(shapes) != 0 {
+		t.Errorf("got %v, %v for no indices", shapes, err)
+	}
+	if _, err := r.ReadShapes([]int{1, 10}); !errors.Is(err, NewShapeError(ErrInvalidFormat, "", nil)) {
+		t.Errorf("expected out of range error, got %v", err)
+	}
+
+	// batch access must not disturb sequential reading
+	count := 1
+	for r.Next() {
+		n, shape := r.Shape()
+		if p := shape.(*Point); p.X != float64(n) {
+			t.Errorf("record %d: got %+v", n, p)
+		}
+		count++
+	}
+	if count != 10 {
+		t.Errorf("read %d records sequentially, want 10", count)
+	}
+}
+
+func TestVerifyAgainstIndex(t *testing.T) {
+	dir := t.TempDir()
+	filename := dir + "/lines.shp"
+	w, err := Create(filename, POLYLINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		x := float64(i)
+		w.Write(NewPolyLine([][]Point{{{x, 0}, {x, 1}, {x + 1, 1}}}))
+	}
+	w.Close()
+
+	count := func(opts ...ReaderOption) (int, error) {
+		r, err := Open(filename, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		n := 0
+		for r.Next() {
+			n++
+		}
+		return n, r.Err()
+	}
+	if n, err := count(WithVerifyAgainstIndex(true)); n != 4 || err != nil {
+		t.Fatalf("intact file: read %d records, err %v", n, err)
+	}
+
+	// grow the content length of record 1 by 2 words, so that record 2 is
+	// looked for 4 bytes after where the index has it
+	shx, err := os.ReadFile(dir + "/lines.shx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shpData, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset1 := int64(binary.BigEndian.Uint32(shx[100+8:])) * 2
+	offset2 := int64(binary.BigEndian.Uint32(shx[100+16:])) * 2
+	length := binary.BigEndian.Uint32(shpData[offset1+4:])
+	binary.BigEndian.PutUint32(shpData[offset1+4:], length+2)
+	if err := os.WriteFile(filename, shpData, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := count(WithVerifyAgainstIndex(true))
+	if n != 2 {
+		t.Errorf("read %d records before the divergence, want 2", n)
+	}
+	var orderErr *RecordOrderError
+	if !errors.As(err, &orderErr) || !strings.Contains(err.Error(), "record 2") {
+		t.Fatalf("expected a RecordOrderError for record 2, got %v", err)
+	}
+	if orderErr.IndexOffset != offset2 || orderErr.FileOffset != offset2+4 {
+		t.Errorf("got %+v, want index offset %d, file offset %d", orderErr, offset2, offset2+4)
+	}
+
+	// the index listing more records than the SHP file has is reported at
+	// the end of the file
+	binary.BigEndian.PutUint32(shpData[offset1+4:], length)
+	if err := os.WriteFile(filename, shpData, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/lines.shx", append(shx, shx[len(shx)-8:]...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := count(WithVerifyAgainstIndex(true)); n != 4 || !errors.As(err, &orderErr) || orderErr.FileOffset != -1 {
+		t.Errorf("extra index entry: read %d records, err %v", n, err)
+	}
+	if n, err := count(); n != 4 || err != nil {
+		t.Errorf("without verification: read %d records, err %v", n, err)
+	}
+}
+
+func TestReaderLogging(t *testing.T) {
+	filename := t.TempDir() + "/points.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{StringField("NAME", 4)})
+	for i := 0; i < 3; i++ {
+		w.Write(&Point{float64(i), 0})
+		_ = w.WriteAttribute(i, 0, "p")
+	}
+	w.Close()
+
+	// read returns what read writes to standard output
+	read := func(opts ...ReaderOption) string {
+		t.Helper()
+		stdout := os.Stdout
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Stdout = pw
+		output := make(chan string)
+		go func() {
+			b, _ := io.ReadAll(pr)
+			output <- string(b)
+		}()
+		defer func() { os.Stdout = stdout }()
+
+		r, err := Open(filename, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for r.Next() {
+			n, _ := r.Shape()
+			_ = r.ReadAttribute(n, 0)
+		}
+		if err := r.Err(); err != nil {
+			t.Error(err)
+		}
+		r.Close()
+		_ = pw.Close()
+		return <-output
+	}
+
+	if out := read(); out != "" {
+		t.Errorf("default reader wrote %q to stdout", out)
+	}
+	var log bytes.Buffer
+	if out := read(WithLogger(&log)); out != "" || log.Len() != 0 {
+		t.Errorf("reader without debugging wrote %q to stdout and %q to the logger", out, log.String())
+	}
+	if out := read(WithDebug(true), WithLogger(&log)); out != "" {
+		t.Errorf("reader with a logger wrote %q to stdout", out)
+	}
+	if !strings.Contains(log.String(), "Processing shape #3") {
+		t.Errorf("got debug output %q", log.String())
+	}
+}
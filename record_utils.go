@@ -1,7 +1,9 @@
 package shp
 
 import (
+	"fmt"
 	"io"
+	"math"
 )
 
 // readShapeRecordHeader reads the per-record header: record number, size (in 16-bit words), and shape type.
@@ -15,3 +17,88 @@ func readShapeRecordHeader(r io.Reader) (num int32, size int32, shapetype ShapeT
 	}
 	return num, size, shapetype, nil
 }
+
+// maxFileWords is the largest length of a shapefile, and thus of a single
+// record, that fits the signed 32-bit count of 16-bit words used by the file
+// header, the record headers and the SHX offsets. It is a variable so that
+// tests can lower it.
+var maxFileWords int64 = math.MaxInt32
+
+// checkRecordLength returns an error if a record with contentLength bytes of
+// content after the shape type, written at byte offset of the SHP file,
+// cannot be addressed by the 32-bit word counts of the format.
+func checkRecordLength(offset, contentLength int64) error {
+	words := (4 + contentLength) / 2
+	if words > maxFileWords {
+		return NewShapeError(ErrInvalidFormat,
+			fmt.Sprintf("record of %d bytes exceeds the maximum record length of %d bytes",
+				4+contentLength, int64(maxFileWords)*2), nil)
+	}
+	if end := offset + 8 + 2*words; end/2 > maxFileWords {
+		return NewShapeError(ErrInvalidFormat,
+			fmt.Sprintf("record would grow the file to %d bytes, exceeding the maximum file length of %d bytes",
+				end, int64(maxFileWords)*2), nil)
+	}
+	return nil
+}
+
+// shapeContentLength returns the number of bytes shape.write writes, which is
+// the content of its record without the shape type.
+func shapeContentLength(shape Shape) int64 {
+	const (
+		box    = 32
+		count  = 4
+		point  = 16
+		value  = 8
+		vRange = 16
+	)
+	n := func(length int, size int64) int64 { return int64(length) * size }
+	switch s := shape.(type) {
+	case *Null:
+		return 0
+	case *Point:
+		return point
+	case *PointZ:
+		return point + 2*value
+	case *PointM:
+		return point + value
+	case *PolyLine:
+		return box + 2*count + n(len(s.Parts), count) + n(len(s.Points), point)
+	case *Polygon:
+		return box + 2*count + n(len(s.Parts), count) + n(len(s.Points), point)
+	case *MultiPoint:
+		return box + count + n(len(s.Points), point)
+	case *PolyLineZ:
+		return box + 2*count + n(len(s.Parts), count) + n(len(s.Points), point) +
+			2*vRange + n(len(s.ZArray), value) + n(len(s.MArray), value)
+	case *PolygonZ:
+		return box + 2*count + n(len(s.Parts), count) + n(len(s.Points), point) +
+			2*vRange + n(len(s.ZArray), value) + n(len(s.MArray), value)
+	case *MultiPointZ:
+		return box + count + n(len(s.Points), point) +
+			2*vRange + n(len(s.ZArray), value) + n(len(s.MArray), value)
+	case *PolyLineM:
+		return box + 2*count + n(len(s.Parts), count) + n(len(s.Points), point) +
+			vRange + n(len(s.MArray), value)
+	case *PolygonM:
+		return box + 2*count + n(len(s.Parts), count) + n(len(s.Points), point) +
+			vRange + n(len(s.MArray), value)
+	case *MultiPointM:
+		return box + count + n(len(s.Points), point) + vRange + n(len(s.MArray), value)
+	case *MultiPatch:
+		return box + 2*count + n(len(s.Parts), count) + n(len(s.PartTypes), count) +
+			n(len(s.Points), point) + 2*vRange + n(len(s.ZArray), value) + n(len(s.MArray), value)
+	default:
+		var c byteCounter
+		shape.write(&c)
+		return int64(c)
+	}
+}
+
+// byteCounter is an io.Writer that only counts the bytes written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
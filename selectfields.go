@@ -0,0 +1,149 @@
+package shp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// SelectFields copies the shapefile input to output keeping only the DBF
+// fields named in keep, in that order, e.g. to strip sensitive columns before
+// sharing a file. Names are matched ignoring case; an unknown or repeated
+// name is an error of type ErrInvalidField. The SHP and SHX files are copied
+// byte for byte, and the kept field values, the deletion flags and the rest
+// of the DBF header are copied without being decoded. The .prj and .cpg files
+// are copied if present.
+func SelectFields(input, output string, keep []string) error {
+	inBase, outBase := shapefileBase(input), shapefileBase(output)
+
+	dbfIn, err := os.Open(inBase + ".dbf")
+	if err != nil {
+		return NewShapeError(ErrIO, "failed to open DBF", err)
+	}
+	defer func() { _ = dbfIn.Close() }()
+	numRecords, headerLength, recordLength, err := readDbfLayout(dbfIn)
+	if err != nil {
+		return err
+	}
+	if _, err := dbfIn.Seek(dbfFieldDescriptorLen, io.SeekStart); err != nil {
+		return NewShapeError(ErrIO, "failed to seek in DBF", err)
+	}
+	fields, err := readDbfFields(dbfIn, calcNumFields(headerLength))
+	if err != nil {
+		return NewShapeError(ErrCorruptedFile, "failed to read DBF fields", err)
+	}
+
+	index := make(map[string]int, len(fields))
+	for i, f := range fields {
+		if _, ok := index[strings.ToUpper(f.String())]; !ok {
+			index[strings.ToUpper(f.String())] = i
+		}
+	}
+	selected := make([]int, len(keep))
+	seen := make(map[int]bool, len(keep))
+	for i, name := range keep {
+		field, ok := index[strings.ToUpper(name)]
+		if !ok {
+			return NewShapeError(ErrInvalidField, fmt.Sprintf("unknown field %q", name), nil)
+		}
+		if seen[field] {
+			return NewShapeError(ErrInvalidField, fmt.Sprintf("field %q selected twice", name), nil)
+		}
+		seen[field] = true
+		selected[i] = field
+	}
+
+	for _, ext := range []string{".shp", ".shx"} {
+		if err := copyFile(inBase+ext, outBase+ext); err != nil {
+			return err
+		}
+	}
+	if err := selectDbfFields(dbfIn, outBase+".dbf", fields, selected, numRecords, headerLength, recordLength); err != nil {
+		return err
+	}
+	return copySidecars(inBase, outBase, ".prj", ".cpg")
+}
+
+// selectDbfFields writes the DBF file output with the fields at the indexes
+// selected of the DBF dbfIn, whose layout is given by the remaining arguments.
+func selectDbfFields(dbfIn io.ReadSeeker, output string, fields []Field, selected []int,
+	numRecords uint32, headerLength, recordLength int16) error {
+	header := make([]byte, dbfFieldDescriptorLen)
+	if _, err := dbfIn.Seek(0, io.SeekStart); err != nil {
+		return NewShapeError(ErrIO, "failed to seek in DBF", err)
+	}
+	if _, err := io.ReadFull(dbfIn, header); err != nil {
+		return NewShapeError(ErrCorruptedFile, "failed to read DBF header", err)
+	}
+	newRecordLength := dbfRowDeletionFlagSz
+	for _, field := range selected {
+		newRecordLength += int(fields[field].Size)
+	}
+	binary.LittleEndian.PutUint16(header[dbfOffsetHeaderLen:], uint16(len(selected)*dbfFieldDescriptorLen+dbfHeaderFieldsBase))
+	binary.LittleEndian.PutUint16(header[dbfOffsetRecordLen:], uint16(newRecordLength))
+
+	f, err := os.Create(output)
+	if err != nil {
+		return NewShapeError(ErrIO, "failed to create "+output, err)
+	}
+	defer func() { _ = f.Close() }()
+	out := bufio.NewWriter(f)
+	ew := &errWriter{Writer: out}
+	writeLE(ew, header)
+	for _, field := range selected {
+		writeLE(ew, fields[field])
+	}
+	writeLE(ew, []byte{dbfFieldTerminator})
+
+	if _, err := dbfIn.Seek(int64(headerLength), io.SeekStart); err != nil {
+		return NewShapeError(ErrIO, "failed to seek in DBF", err)
+	}
+	in := bufio.NewReader(dbfIn)
+	row := make([]byte, recordLength)
+	newRow := make([]byte, 0, newRecordLength)
+	for i := uint32(0); i < numRecords; i++ {
+		if _, err := io.ReadFull(in, row); err != nil {
+			return NewShapeError(ErrCorruptedFile, fmt.Sprintf("failed to read DBF row %d", i), err)
+		}
+		newRow = append(newRow[:0], row[0])
+		for _, field := range selected {
+			start := dbfFieldStartByte(fields, field)
+			newRow = append(newRow, row[start:start+int(fields[field].Size)]...)
+		}
+		writeLE(ew, newRow)
+	}
+	if ew.e != nil {
+		return NewShapeError(ErrIO, "failed to write "+output, ew.e)
+	}
+	if err := out.Flush(); err != nil {
+		return NewShapeError(ErrIO, "failed to write "+output, err)
+	}
+	if err := f.Close(); err != nil {
+		return NewShapeError(ErrIO, "failed to close "+output, err)
+	}
+	return nil
+}
+
+// copyFile copies the file src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return NewShapeError(ErrIO, "failed to open "+src, err)
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.Create(dst)
+	if err != nil {
+		return NewShapeError(ErrIO, "failed to create "+dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return NewShapeError(ErrIO, "failed to copy "+src, err)
+	}
+	if err := out.Close(); err != nil {
+		return NewShapeError(ErrIO, "failed to close "+dst, err)
+	}
+	return nil
+}
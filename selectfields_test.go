@@ -0,0 +1,78 @@
+package shp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSelectFields(t *testing.T) {
+	dir := t.TempDir()
+	input, output := dir+"/full.shp", dir+"/public.shp"
+	w, err := Create(input, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := []Field{StringField("NAME", 5), StringField("SSN", 11), NumberField("ID", 5), DoubleField("VALUE")}
+	if err := w.SetFields(fields); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"a", "b"} {
+		n := w.Write(&Point{float64(i), float64(i)})
+		_ = w.WriteAttribute(int(n), 0, name)
+		_ = w.WriteAttribute(int(n), 1, "123-45-6789")
+		_ = w.WriteAttribute(int(n), 2, i)
+		_ = w.WriteAttribute(int(n), 3, float64(i)+0.5)
+	}
+	w.Close()
+	if err := DeleteRecord(input, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SelectFields(input, output, []string{"value", "NAME"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, ext := range []string{".shp", ".shx"} {
+		a, _ := os.ReadFile(dir + "/full" + ext)
+		b, _ := os.ReadFile(dir + "/public" + ext)
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s was not copied verbatim", ext)
+		}
+	}
+
+	r, err := Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var names []string
+	for _, f := range r.Fields() {
+		names = append(names, f.String())
+	}
+	if !reflect.DeepEqual(names, []string{"VALUE", "NAME"}) {
+		t.Errorf("got fields %v", names)
+	}
+	var got []string
+	for r.Next() {
+		n, _ := r.Shape()
+		got = append(got, r.ReadAttribute(n, 0)+" "+r.ReadAttribute(n, 1))
+	}
+	if want := []string{"0.5 a", "1.5 b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got records %q, want %q", got, want)
+	}
+	dbf, _ := os.ReadFile(dir + "/public.dbf")
+	if bytes.Contains(dbf, []byte("123-45")) {
+		t.Error("dropped field was copied")
+	}
+	if dbf[r.dbfHeaderLength+int16(r.dbfRecordLength)] != dbfDeletionFlagDeleted {
+		t.Error("deletion flag was not copied")
+	}
+
+	for _, keep := range [][]string{{"MISSING"}, {"NAME", "name"}} {
+		if err := SelectFields(input, output, keep); !errors.Is(err, NewShapeError(ErrInvalidField, "", nil)) {
+			t.Errorf("%v: expected invalid field error, got %v", keep, err)
+		}
+	}
+}
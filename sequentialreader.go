@@ -3,6 +3,9 @@ package shp
 import (
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // SequentialReader is the interface that allows reading shapes and attributes one after another. It also embeds io.Closer.
@@ -28,6 +31,14 @@ type SequentialReader interface {
 	// encountered any errors, nil is returned.
 	Fields() []Field
 
+	// ZRange returns the range of Z values, Zmin and Zmax, stored in the SHP
+	// header.
+	ZRange() [2]float64
+
+	// MRange returns the range of measures, Mmin and Mmax, stored in the SHP
+	// header.
+	MRange() [2]float64
+
 	// Err returns the last non-EOF error encountered.
 	Err() error
 }
@@ -44,6 +55,90 @@ func Attributes(sr SequentialReader) []string {
 	return s
 }
 
+// RecordMap returns the attributes of the shape that sr was last advanced to,
+// keyed by field name.
+func RecordMap(sr SequentialReader) map[string]string {
+	if sr.Err() != nil {
+		return nil
+	}
+	fields := sr.Fields()
+	m := make(map[string]string, len(fields))
+	for i, f := range fields {
+		m[f.String()] = trimAttribute(sr.Attribute(i))
+	}
+	return m
+}
+
+// RecordTyped returns the attributes of the shape that sr was last advanced
+// to, keyed by field name and converted according to the DBF field type.
+// Numeric fields become int64 or float64, also when written with digit
+// grouping or a decimal comma, logical fields become bool and empty values
+// become nil. Values that cannot be parsed are kept as strings.
+func RecordTyped(sr SequentialReader) map[string]interface{} {
+	if sr.Err() != nil {
+		return nil
+	}
+	fields := sr.Fields()
+	m := make(map[string]interface{}, len(fields))
+	for i, f := range fields {
+		m[f.String()] = typedAttribute(f, trimAttribute(sr.Attribute(i)))
+	}
+	return m
+}
+
+// trimAttribute removes the space and NUL padding that DBF writers leave in
+// unset or short values.
+func trimAttribute(value string) string {
+	return strings.Trim(value, " \x00")
+}
+
+// typedAttribute converts the raw attribute value according to the type of
+// field f.
+func typedAttribute(f Field, value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	switch f.Fieldtype {
+	case 'N', 'F', dbfFieldTypeInteger:
+		if v, ok := parseNumeric(f, value); ok {
+			return v
+		}
+		if v, ok := parseNumeric(f, normalizeNumeric(value)); ok {
+			return v
+		}
+	case dbfFieldTypeDouble:
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case dbfFieldTypeTimestamp, dbfFieldTypeDateTime:
+		if t, err := time.Parse(dbfTimestampLayout, value); err == nil {
+			return t
+		}
+	case 'L':
+		switch strings.ToUpper(value) {
+		case "T", "Y":
+			return true
+		case "F", "N":
+			return false
+		}
+	}
+	return value
+}
+
+// parseNumeric parses value as int64 if f has no decimals and the value is
+// integral, and as float64 otherwise.
+func parseNumeric(f Field, value string) (interface{}, bool) {
+	if f.Precision == 0 {
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i, true
+		}
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		return v, true
+	}
+	return nil, false
+}
+
 // AttributeCount returns the number of fields of the database.
 func AttributeCount(sr SequentialReader) int {
 	return len(sr.Fields())
@@ -57,6 +152,8 @@ type seqReader struct {
 
 	geometryType ShapeType
 	bbox         Box
+	zRange       [2]float64
+	mRange       [2]float64
 
 	shape      Shape
 	num        int32
@@ -70,17 +167,19 @@ type seqReader struct {
 }
 
 // Read and parse headers in the Shapefile. This will fill out GeometryType,
-// filelength and bbox.
+// filelength, bbox and the Z and M ranges.
 func (sr *seqReader) readHeaders() {
 	// contrary to Reader.readHeaders we cannot seek with ReadCloser
-	fl, geom, bbox, err := readShpHeaderReader(sr.shp)
+	h, err := readShpHeaderReader(sr.shp)
 	if err != nil {
 		sr.err = fmt.Errorf("Error when reading SHP header: %v", err)
 		return
 	}
-	sr.filelength = fl
-	sr.geometryType = geom
-	sr.bbox = bbox
+	sr.filelength = h.fileLength
+	sr.geometryType = h.geometryType
+	sr.bbox = h.bbox
+	sr.zRange = h.zRange
+	sr.mRange = h.mRange
 
 	// dbf header
 	er := &errReader{Reader: sr.dbf}
@@ -233,8 +332,11 @@ func (sr *seqReader) Attribute(n int) string {
 	}
 	start := dbfFieldStartByte(sr.dbfFields, n)
 	end := start + int(sr.dbfFields[n].Size)
-	// trim ASCII spaces on both ends without intermediate string
 	b := sr.dbfRow[start:end]
+	if value, ok := binaryFieldValue(sr.dbfFields[n], b); ok {
+		return value
+	}
+	// trim ASCII spaces on both ends without intermediate string
 	i := 0
 	for i < len(b) && b[i] == ' ' {
 		i++
@@ -270,6 +372,18 @@ func (sr *seqReader) Fields() []Field {
 	return sr.dbfFields
 }
 
+// ZRange returns the range of Z values, Zmin and Zmax, stored in the SHP
+// header.
+func (sr *seqReader) ZRange() [2]float64 {
+	return sr.zRange
+}
+
+// MRange returns the range of measures, Mmin and Mmax, stored in the SHP
+// header.
+func (sr *seqReader) MRange() [2]float64 {
+	return sr.mRange
+}
+
 // SequentialReaderFromExt returns a new SequentialReader that interprets shp
 // as a source of shapes whose attributes can be retrieved from dbf.
 func SequentialReaderFromExt(shp, dbf io.ReadCloser) SequentialReader {
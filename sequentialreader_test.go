@@ -1,6 +1,8 @@
 package shp
 
 import (
+	"encoding/binary"
+	"math"
 	"os"
 	"testing"
 )
@@ -41,3 +43,93 @@ func TestSequentialReader(t *testing.T) {
 		testshapeIdentity(t, prefix, getShapesSequentially)
 	}
 }
+
+func TestRecordMapAndTyped(t *testing.T) {
+	filename := filenamePrefix + "records"
+	defer removeShapefile(filename)
+
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{
+		StringField("NAME", 10),
+		NumberField("ID", 5),
+		FloatField("VALUE", 8, 2),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	row := w.Write(&Point{1, 2})
+	_ = w.WriteAttribute(int(row), 0, "alpha")
+	_ = w.WriteAttribute(int(row), 1, 42)
+	w.Close()
+
+	sr := SequentialReaderFromExt(openFile(filename+".shp", t), openFile(filename+".dbf", t))
+	defer sr.Close()
+	if !sr.Next() {
+		t.Fatalf("failed to read record: %v", sr.Err())
+	}
+
+	m := RecordMap(sr)
+	if m["NAME"] != "alpha" || m["ID"] != "42" || m["VALUE"] != "" {
+		t.Errorf("unexpected record map: %v", m)
+	}
+
+	typed := RecordTyped(sr)
+	if typed["NAME"] != "alpha" {
+		t.Errorf("NAME = %v, want alpha", typed["NAME"])
+	}
+	if typed["ID"] != int64(42) {
+		t.Errorf("ID = %#v, want int64(42)", typed["ID"])
+	}
+	if typed["VALUE"] != nil {
+		t.Errorf("VALUE = %#v, want nil", typed["VALUE"])
+	}
+}
+
+func TestZMRanges(t *testing.T) {
+	filename := t.TempDir() + "/pointz"
+	w, err := Create(filename+".shp", POINTZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&PointZ{X: 1, Y: 2, Z: 3, M: 4})
+	w.Close()
+
+	// store the ranges in the header
+	data, err := os.ReadFile(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range []float64{-10, 250.5, 0.25, 99} {
+		binary.LittleEndian.PutUint64(data[68+8*i:], math.Float64bits(v))
+	}
+	if err := os.WriteFile(filename+".shp", data, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	wantZ, wantM := [2]float64{-10, 250.5}, [2]float64{0.25, 99}
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.ZRange() != wantZ || r.MRange() != wantM {
+		t.Errorf("Reader: got Z range %v and M range %v, want %v and %v", r.ZRange(), r.MRange(), wantZ, wantM)
+	}
+	if !r.Next() {
+		t.Fatal(r.Err())
+	}
+
+	sr := SequentialReaderFromExt(openFile(filename+".shp", t), openFile(filename+".dbf", t))
+	defer sr.Close()
+	if sr.ZRange() != wantZ || sr.MRange() != wantM {
+		t.Errorf("SequentialReader: got Z range %v and M range %v, want %v and %v", sr.ZRange(), sr.MRange(), wantZ, wantM)
+	}
+	if !sr.Next() {
+		t.Fatal(sr.Err())
+	}
+	if _, shape := sr.Shape(); *shape.(*PointZ) != (PointZ{X: 1, Y: 2, Z: 3, M: 4}) {
+		t.Errorf("got shape %+v", shape)
+	}
+}
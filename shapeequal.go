@@ -0,0 +1,112 @@
+package shp
+
+import "math"
+
+// ShapesEqual reports whether a and b are the same geometry: they must have
+// the same shape type and the same parts, part types and number of points,
+// and all X, Y, Z and M values may differ by at most epsilon. Bounding boxes
+// and ranges are derived from the points and are not compared. NaN values
+// are equal to each other, so missing M values compare equal.
+func ShapesEqual(a, b Shape, epsilon float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ca, ok := shapeComponentsOf(a)
+	if !ok {
+		return false
+	}
+	cb, ok := shapeComponentsOf(b)
+	if !ok || ca.shapeType != cb.shapeType || len(ca.points) != len(cb.points) {
+		return false
+	}
+	if !int32sEqual(ca.parts, cb.parts) || !int32sEqual(ca.partTypes, cb.partTypes) {
+		return false
+	}
+	for i := range ca.points {
+		if !ca.points[i].AlmostEquals(cb.points[i], epsilon) {
+			return false
+		}
+	}
+	return floatsAlmostEqual(ca.z, cb.z, epsilon) && floatsAlmostEqual(ca.m, cb.m, epsilon)
+}
+
+// shapeComponents holds the values of a shape that make up its geometry.
+type shapeComponents struct {
+	shapeType ShapeType
+	parts     []int32
+	partTypes []int32
+	points    []Point
+	z, m      []float64
+}
+
+// shapeComponentsOf returns the components of shape, or false if shape is
+// not one of the shape types of this package.
+func shapeComponentsOf(shape Shape) (shapeComponents, bool) {
+	c := shapeComponents{shapeType: shapeTypeOf(shape)}
+	switch s := shape.(type) {
+	case *Null:
+	case *Point:
+		c.points = []Point{*s}
+	case *PointZ:
+		c.points = []Point{{s.X, s.Y}}
+		c.z, c.m = []float64{s.Z}, []float64{s.M}
+	case *PointM:
+		c.points = []Point{{s.X, s.Y}}
+		c.m = []float64{s.M}
+	case *PolyLine:
+		c.parts, c.points = s.Parts, s.Points
+	case *Polygon:
+		c.parts, c.points = s.Parts, s.Points
+	case *MultiPoint:
+		c.points = s.Points
+	case *PolyLineZ:
+		c.parts, c.points, c.z, c.m = s.Parts, s.Points, s.ZArray, s.MArray
+	case *PolygonZ:
+		c.parts, c.points, c.z, c.m = s.Parts, s.Points, s.ZArray, s.MArray
+	case *MultiPointZ:
+		c.points, c.z, c.m = s.Points, s.ZArray, s.MArray
+	case *PolyLineM:
+		c.parts, c.points, c.m = s.Parts, s.Points, s.MArray
+	case *PolygonM:
+		c.parts, c.points, c.m = s.Parts, s.Points, s.MArray
+	case *MultiPointM:
+		c.points, c.m = s.Points, s.MArray
+	case *MultiPatch:
+		c.parts, c.partTypes, c.points, c.z, c.m = s.Parts, s.PartTypes, s.Points, s.ZArray, s.MArray
+	default:
+		return c, false
+	}
+	return c, true
+}
+
+func int32sEqual(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// floatsAlmostEqual reports whether a and b have the same length and their
+// values differ by at most epsilon, treating two NaNs as equal.
+func floatsAlmostEqual(a, b []float64, epsilon float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.IsNaN(a[i]) || math.IsNaN(b[i]) {
+			if !math.IsNaN(a[i]) || !math.IsNaN(b[i]) {
+				return false
+			}
+			continue
+		}
+		if math.Abs(a[i]-b[i]) > epsilon {
+			return false
+		}
+	}
+	return true
+}
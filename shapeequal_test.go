@@ -0,0 +1,67 @@
+package shp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShapesEqualRoundTrip(t *testing.T) {
+	for _, prefix := range []string{
+		"point", "polyline", "polygon", "multipoint",
+		"pointz", "polylinez", "polygonz", "multipointz",
+		"pointm", "polylinem", "polygonm", "multipointm", "multipatch",
+	} {
+		shapes := getShapesFromFile("test_files/"+prefix, t)
+		filename := t.TempDir() + "/" + prefix + ".shp"
+		w, err := Create(filename, shapeTypeOf(shapes[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, shape := range shapes {
+			if _, err := w.WriteChecked(shape); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.Close()
+
+		got := getShapesFromFile(shapefileBase(filename), t)
+		if len(got) != len(shapes) {
+			t.Fatalf("%s: read %d shapes, want %d", prefix, len(got), len(shapes))
+		}
+		for i := range shapes {
+			if !ShapesEqual(shapes[i], got[i], 0) {
+				t.Errorf("%s: shape %d changed in round trip", prefix, i)
+			}
+		}
+	}
+}
+
+func TestShapesEqual(t *testing.T) {
+	square := [][]Point{{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}}
+	shifted := [][]Point{{{0, 0}, {0, 1}, {1, 1.001}, {1, 0}, {0, 0}}}
+	nan := math.NaN()
+
+	tests := []struct {
+		name    string
+		a, b    Shape
+		epsilon float64
+		want    bool
+	}{
+		{"same polygon", NewPolygon(square), NewPolygon(square), 0, true},
+		{"within epsilon", NewPolygon(square), NewPolygon(shifted), 0.01, true},
+		{"beyond epsilon", NewPolygon(square), NewPolygon(shifted), 0.0001, false},
+		{"different type", NewPolygon(square), NewPolyLine(square), 0, false},
+		{"different parts", NewPolyLine([][]Point{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}}),
+			NewPolyLine([][]Point{{{0, 0}}, {{1, 1}, {2, 2}, {3, 3}}}), 0, false},
+		{"different z", &PointZ{1, 2, 3, 0}, &PointZ{1, 2, 4, 0}, 0.5, false},
+		{"missing m", &PointM{1, 2, nan}, &PointM{1, 2, nan}, 0, true},
+		{"missing and present m", &PointM{1, 2, nan}, &PointM{1, 2, 0}, 0, false},
+		{"null", &Null{}, &Null{}, 0, true},
+		{"nil", nil, &Null{}, 0, false},
+	}
+	for _, tc := range tests {
+		if got := ShapesEqual(tc.a, tc.b, tc.epsilon); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
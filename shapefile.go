@@ -1,7 +1,9 @@
 package shp
 
 import (
+	"fmt"
 	"io"
+	"math"
 	"strings"
 )
 
@@ -77,6 +79,35 @@ func getBBoxFromShapePoints(points []Point) Box {
 	return BBoxFromPoints(points)
 }
 
+// RecomputeBBox updates the stored bounding box of shape from its current
+// points. The stored box is what gets written to the record, so this should be
+// called after editing the Points of a shape directly. Shapes without a stored
+// box (points and Null) are left untouched.
+func RecomputeBBox(shape Shape) {
+	switch s := shape.(type) {
+	case *PolyLine:
+		s.Box = s.BBox()
+	case *Polygon:
+		s.Box = s.BBox()
+	case *MultiPoint:
+		s.Box = s.BBox()
+	case *PolyLineZ:
+		s.Box = s.BBox()
+	case *PolygonZ:
+		s.Box = s.BBox()
+	case *MultiPointZ:
+		s.Box = s.BBox()
+	case *PolyLineM:
+		s.Box = s.BBox()
+	case *PolygonM:
+		s.Box = s.BBox()
+	case *MultiPointM:
+		s.Box = s.BBox()
+	case *MultiPatch:
+		s.Box = s.BBox()
+	}
+}
+
 // readBasicPolygonShape reads common polygon-like shape data
 func readBasicPolygonShape(file io.Reader, box *Box, numParts *int32, numPoints *int32, parts *[]int32, points *[]Point) {
 	var er *errReader
@@ -105,6 +136,37 @@ func writeBasicPolygonShape(file io.Writer, box Box, numParts int32, numPoints i
 	writeLE(ew, points)
 }
 
+// zNoData is the value written for Z values that are NaN, i.e. unknown. The
+// specification treats any value below -10^38 as no data, so readers map
+// values at or below it back to NaN.
+const zNoData = -1e38
+
+// zToFile returns z with NaN values replaced by zNoData. z is returned as is
+// if it has no NaN values.
+func zToFile(z []float64) []float64 {
+	for i, v := range z {
+		if math.IsNaN(v) {
+			out := append([]float64(nil), z...)
+			for j := i; j < len(out); j++ {
+				if math.IsNaN(out[j]) {
+					out[j] = zNoData
+				}
+			}
+			return out
+		}
+	}
+	return z
+}
+
+// zFromFile replaces no data values in z with NaN in place.
+func zFromFile(z []float64) {
+	for i, v := range z {
+		if v <= zNoData {
+			z[i] = math.NaN()
+		}
+	}
+}
+
 // readPolygonShapeWithZ reads polygon-like shapes with Z and M arrays
 func readPolygonShapeWithZ(file io.Reader, box *Box, numParts *int32, numPoints *int32, parts *[]int32, points *[]Point, zRange *[2]float64, zArray *[]float64, mRange *[2]float64, mArray *[]float64) {
 	var er *errReader
@@ -125,6 +187,8 @@ func readPolygonShapeWithZ(file io.Reader, box *Box, numParts *int32, numPoints
 	readLE(er, points)
 	readLE(er, zRange)
 	readLE(er, zArray)
+	zFromFile(zRange[:])
+	zFromFile(*zArray)
 
 	// Try to read M data, but don't fail if it's incomplete (common at file end)
 	beforeMRange := er.e
@@ -159,8 +223,8 @@ func writePolygonShapeWithZ(file io.Writer, box Box, numParts int32, numPoints i
 	writeLE(ew, numPoints)
 	writeLE(ew, parts)
 	writeLE(ew, points)
-	writeLE(ew, zRange)
-	writeLE(ew, zArray)
+	writeLE(ew, zToFile(zRange[:]))
+	writeLE(ew, zToFile(zArray))
 	writeLE(ew, mRange)
 	writeLE(ew, mArray)
 }
@@ -237,6 +301,8 @@ func readMultiPointWithZ(file io.Reader, box *Box, numPoints *int32, points *[]P
 	readLE(er, points)
 	readLE(er, zRange)
 	readLE(er, zArray)
+	zFromFile(zRange[:])
+	zFromFile(*zArray)
 	readLE(er, mRange)
 	readLE(er, mArray)
 }
@@ -247,8 +313,8 @@ func writeMultiPointWithZ(file io.Writer, box Box, numPoints int32, points []Poi
 	writeLE(ew, box)
 	writeLE(ew, numPoints)
 	writeLE(ew, points)
-	writeLE(ew, zRange)
-	writeLE(ew, zArray)
+	writeLE(ew, zToFile(zRange[:]))
+	writeLE(ew, zToFile(zArray))
 	writeLE(ew, mRange)
 	writeLE(ew, mArray)
 }
@@ -323,6 +389,12 @@ func (p Point) BBox() Box {
 	return Box{p.X, p.Y, p.X, p.Y}
 }
 
+// AlmostEquals reports whether p and o differ by at most epsilon in both X and
+// Y.
+func (p Point) AlmostEquals(o Point, epsilon float64) bool {
+	return math.Abs(p.X-o.X) <= epsilon && math.Abs(p.Y-o.Y) <= epsilon
+}
+
 func (p *Point) read(file io.Reader) {
 	var er *errReader
 	if reader, ok := file.(*errReader); ok {
@@ -353,6 +425,26 @@ func flatten(points [][]Point) []Point {
 	return r
 }
 
+// splitParts is the inverse of flatten: it slices points into the parts that
+// start at the offsets in parts. Invalid offsets are clamped to the points.
+func splitParts(parts []int32, points []Point) [][]Point {
+	r := make([][]Point, 0, len(parts))
+	for i, start := range parts {
+		end := len(points)
+		if i+1 < len(parts) {
+			end = int(parts[i+1])
+		}
+		if end > len(points) {
+			end = len(points)
+		}
+		if int(start) > end || start < 0 {
+			continue
+		}
+		r = append(r, points[start:end])
+	}
+	return r
+}
+
 // PolyLine is a shape type that consists of an ordered set of vertices that
 // consists of one or more parts. A part is a connected sequence of two ore
 // more points. Parts may or may not be connected to another and may or may not
@@ -386,6 +478,75 @@ func NewPolyLine(parts [][]Point) *PolyLine {
 	return p
 }
 
+// NewPolyLineChecked is like NewPolyLine but returns an error if there are no
+// parts or if a part has fewer than two points.
+func NewPolyLineChecked(parts [][]Point) (*PolyLine, error) {
+	if err := checkParts(parts, minLinePoints, false); err != nil {
+		return nil, err
+	}
+	return NewPolyLine(parts), nil
+}
+
+// NewPolygon returns a pointer to a new Polygon created with the provided
+// rings. The inner slice should be the points that the ring consists of.
+func NewPolygon(rings [][]Point) *Polygon {
+	return (*Polygon)(NewPolyLine(rings))
+}
+
+// NewPolygonChecked is like NewPolygon but returns an error if there are no
+// rings or if a ring has fewer than four points or is not closed.
+func NewPolygonChecked(rings [][]Point) (*Polygon, error) {
+	if err := checkParts(rings, minRingPoints, true); err != nil {
+		return nil, err
+	}
+	return NewPolygon(rings), nil
+}
+
+// NewPolygonWithHoles returns a pointer to a new Polygon with the exterior
+// ring outer followed by the given holes. The rings are oriented the way
+// shapefiles require, the exterior clockwise and the holes counter-clockwise,
+// and rings that are not closed are closed. The given slices are not modified.
+func NewPolygonWithHoles(outer []Point, holes [][]Point) *Polygon {
+	rings := make([][]Point, 0, len(holes)+1)
+	rings = append(rings, orientRing(outer, true))
+	for _, hole := range holes {
+		rings = append(rings, orientRing(hole, false))
+	}
+	return NewPolygon(rings)
+}
+
+// Minimum number of points of a line part and of a closed polygon ring.
+const (
+	minLinePoints = 2
+	minRingPoints = 4
+)
+
+// checkParts verifies that every part has at least minPoints points and, if
+// closed is set, that its first and last point are equal.
+func checkParts(parts [][]Point, minPoints int, closed bool) error {
+	if len(parts) == 0 {
+		return NewShapeError(ErrInvalidFormat, "shape has no parts", nil)
+	}
+	for i, part := range parts {
+		if err := checkPart(part, minPoints, closed); err != nil {
+			return NewShapeError(ErrInvalidFormat, fmt.Sprintf("invalid part %d", i), err)
+		}
+	}
+	return nil
+}
+
+// checkPart verifies a single part, see checkParts.
+func checkPart(part []Point, minPoints int, closed bool) error {
+	if len(part) < minPoints {
+		return NewShapeError(ErrInvalidFormat,
+			fmt.Sprintf("part has %d points, need at least %d", len(part), minPoints), nil)
+	}
+	if closed && part[0] != part[len(part)-1] {
+		return NewShapeError(ErrInvalidFormat, "ring is not closed", nil)
+	}
+	return nil
+}
+
 // BBox returns the bounding box of the PolyLine feature
 func (p PolyLine) BBox() Box {
 	return getBBoxFromShapePoints(p.Points)
@@ -458,11 +619,18 @@ func (p *PointZ) read(file io.Reader) {
 		er = &errReader{Reader: file}
 	}
 	readLE(er, p)
+	if p.Z <= zNoData {
+		p.Z = math.NaN()
+	}
 }
 
 func (p *PointZ) write(file io.Writer) {
 	ew := &errWriter{Writer: file}
-	writeLE(ew, p)
+	out := *p
+	if math.IsNaN(out.Z) {
+		out.Z = zNoData
+	}
+	writeLE(ew, &out)
 }
 
 // PolyLineZ is a shape which consists of one or more parts. A part is a
@@ -666,6 +834,8 @@ func (p *MultiPatch) read(file io.Reader) {
 	readLE(er, &p.Points)
 	readLE(er, &p.ZRange)
 	readLE(er, &p.ZArray)
+	zFromFile(p.ZRange[:])
+	zFromFile(p.ZArray)
 	readLE(er, &p.MRange)
 	readLE(er, &p.MArray)
 }
@@ -678,8 +848,8 @@ func (p *MultiPatch) write(file io.Writer) {
 	writeLE(ew, p.Parts)
 	writeLE(ew, p.PartTypes)
 	writeLE(ew, p.Points)
-	writeLE(ew, p.ZRange)
-	writeLE(ew, p.ZArray)
+	writeLE(ew, zToFile(p.ZRange[:]))
+	writeLE(ew, zToFile(p.ZArray))
 	writeLE(ew, p.MRange)
 	writeLE(ew, p.MArray)
 }
@@ -700,6 +870,16 @@ func (f Field) String() string {
 	return strings.TrimRight(string(f.Name[:]), "\x00")
 }
 
+// IsSystem reports whether the field is a system column that is not part of
+// the user data, such as the _NullFlags column or columns flagged as hidden
+// by Visual FoxPro and ArcGIS.
+func (f Field) IsSystem() bool {
+	if f.Fieldtype == dbfFieldTypeNullFlag || f.Padding[dbfFieldFlagsOffset]&dbfFieldFlagSystem != 0 {
+		return true
+	}
+	return strings.EqualFold(f.String(), "_NullFlags")
+}
+
 // StringField returns a Field that can be used in SetFields to initialize the
 // DBF file.
 func StringField(name string, length uint8) Field {
@@ -733,3 +913,40 @@ func DateField(name string) Field {
 	copy(field.Name[:], []byte(name))
 	return field
 }
+
+// IntegerField returns a Field that can be used in SetFields to initialize the
+// DBF file. Used to store 32-bit integers in binary as written by FoxPro and
+// newer tools. Records without a value hold 0.
+func IntegerField(name string) Field {
+	field := Field{Fieldtype: dbfFieldTypeInteger, Size: 4}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// TimestampField returns a Field that can be used in SetFields to initialize
+// the DBF file. Used to store date and time in binary as a Julian day number
+// and milliseconds since midnight, UTC. Values are written from time.Time and
+// read back as time.Time by ReadAttributeTyped. Use DateTimeField for the
+// FoxPro type with the same layout.
+func TimestampField(name string) Field {
+	field := Field{Fieldtype: dbfFieldTypeTimestamp, Size: 8}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// DateTimeField is like TimestampField, but with the FoxPro datetime field
+// type 'T'.
+func DateTimeField(name string) Field {
+	field := Field{Fieldtype: dbfFieldTypeDateTime, Size: 8}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// DoubleField returns a Field that can be used in SetFields to initialize the
+// DBF file. Used to store 64-bit floating points in binary as written by
+// FoxPro and newer tools. Records without a value hold 0.
+func DoubleField(name string) Field {
+	field := Field{Fieldtype: dbfFieldTypeDouble, Size: 8}
+	copy(field.Name[:], []byte(name))
+	return field
+}
@@ -1,6 +1,10 @@
 package shp
 
-import "testing"
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
 
 func TestBoxExtend(t *testing.T) {
 	a := Box{-124.763068, 45.543541, -116.915989, 49.002494}
@@ -20,3 +24,142 @@ func TestBoxExtend(t *testing.T) {
 		t.Errorf("a.MaxY = %v, want %v", a.MaxY, c.MaxY)
 	}
 }
+
+func TestRecomputeBBox(t *testing.T) {
+	l := NewPolyLine([][]Point{{{0, 0}, {1, 1}}})
+	l.Points[1] = Point{5, -2}
+	RecomputeBBox(l)
+	want := Box{0, -2, 5, 0}
+	if l.Box != want {
+		t.Errorf("got box %v, want %v", l.Box, want)
+	}
+
+	pg := &Polygon{NumPoints: 2, Points: []Point{{-1, 3}, {2, 4}}}
+	RecomputeBBox(pg)
+	if want := (Box{-1, 3, 2, 4}); pg.Box != want {
+		t.Errorf("got box %v, want %v", pg.Box, want)
+	}
+}
+
+func TestBoxSerialization(t *testing.T) {
+	b := Box{-1.5, 2, 3, 4.25}
+	if got := BoxFromGeoJSON(b.ToGeoJSON()); got != b {
+		t.Errorf("GeoJSON round trip: got %v, want %v", got, b)
+	}
+	if got := BoxFromGeoJSON([]float64{0, 1, 2, 3, 4, 5}); got != (Box{0, 1, 3, 4}) {
+		t.Errorf("3D bbox: got %v", got)
+	}
+	want := "POLYGON ((-1.500000 2.000000, 3.000000 2.000000, 3.000000 4.250000, -1.500000 4.250000, -1.500000 2.000000))"
+	if got := b.ToWKT(); got != want {
+		t.Errorf("got WKT %q, want %q", got, want)
+	}
+}
+
+func TestBoxDifference(t *testing.T) {
+	b := Box{0, 0, 10, 10}
+	tests := []struct {
+		other Box
+		want  []Box
+	}{
+		{Box{20, 20, 30, 30}, []Box{b}},
+		{Box{10, 0, 20, 10}, []Box{b}},
+		{Box{-1, -1, 11, 11}, nil},
+		{Box{5, 0, 15, 10}, []Box{{0, 0, 5, 10}}},
+		{Box{2, 3, 4, 6}, []Box{{0, 0, 10, 3}, {0, 6, 10, 10}, {0, 3, 2, 6}, {4, 3, 10, 6}}},
+	}
+	for _, test := range tests {
+		if got := b.Difference(test.other); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Difference(%v): got %v, want %v", test.other, got, test.want)
+		}
+	}
+}
+
+func TestCheckedConstructors(t *testing.T) {
+	if _, err := NewPolyLineChecked([][]Point{{{0, 0}}}); err == nil {
+		t.Error("single-point line part accepted")
+	}
+	if _, err := NewPolyLineChecked([][]Point{{{0, 0}, {1, 1}}}); err != nil {
+		t.Errorf("valid line rejected: %v", err)
+	}
+	if _, err := NewPolygonChecked([][]Point{{{0, 0}, {1, 0}, {0, 0}}}); err == nil {
+		t.Error("ring with three points accepted")
+	}
+	if _, err := NewPolygonChecked([][]Point{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}); err == nil {
+		t.Error("unclosed ring accepted")
+	}
+	pg, err := NewPolygonChecked([][]Point{{{0, 0}, {0, 1}, {1, 1}, {0, 0}}})
+	if err != nil {
+		t.Fatalf("valid ring rejected: %v", err)
+	}
+	if err := (&DefaultValidator{}).Validate(pg); err != nil {
+		t.Errorf("validator rejected valid polygon: %v", err)
+	}
+	pg.Points = pg.Points[:3]
+	pg.NumPoints = 3
+	if err := (&DefaultValidator{}).Validate(pg); err == nil {
+		t.Error("validator accepted degenerate ring")
+	}
+}
+
+func TestNewPolygonWithHoles(t *testing.T) {
+	// counter-clockwise and open exterior, clockwise and closed hole
+	outer := []Point{{0, 0}, {4, 0}, {4, 4}, {0, 4}}
+	hole := []Point{{1, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}}
+	pg := NewPolygonWithHoles(outer, [][]Point{hole})
+
+	want := [][]Point{
+		{{0, 4}, {4, 4}, {4, 0}, {0, 0}, {0, 4}},
+		{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}},
+	}
+	if got := splitParts(pg.Parts, pg.Points); !reflect.DeepEqual(got, want) {
+		t.Errorf("got rings %v, want %v", got, want)
+	}
+	if pg.NumParts != 2 || pg.NumPoints != 10 || pg.Box != (Box{0, 0, 4, 4}) {
+		t.Errorf("got %d parts, %d points, bbox %v", pg.NumParts, pg.NumPoints, pg.Box)
+	}
+	if outer[0] != (Point{0, 0}) || hole[1] != (Point{1, 2}) {
+		t.Error("input rings were modified")
+	}
+}
+
+func TestBoxAlmostEquals(t *testing.T) {
+	x, y := 0.1, 0.2
+	b := Box{x + y, 0, 1, 1}
+	if b == (Box{0.3, 0, 1, 1}) {
+		t.Fatal("expected exact comparison to fail")
+	}
+	if !b.AlmostEquals(Box{0.3, 0, 1, 1}, 1e-9) {
+		t.Error("expected boxes to be almost equal")
+	}
+	if b.AlmostEquals(Box{0.3, 0, 1, 1.1}, 1e-9) {
+		t.Error("expected boxes with different MaxY to differ")
+	}
+}
+
+func TestValidatorCoordinateBounds(t *testing.T) {
+	v := NewDefaultValidator(WithCoordinateBounds(GeographicBounds))
+	inside := NewPolyLine([][]Point{{{-180, -90}, {0, 0}, {180, 90}}})
+	if err := v.Validate(inside); err != nil {
+		t.Errorf("rejected line within bounds: %v", err)
+	}
+	// a projected coordinate mixed into geographic data
+	mixed := NewPolyLine([][]Point{{{10, 50}, {11, 51}}, {{12, 52}, {500000, 5700000}}})
+	err := v.Validate(mixed)
+	var boundsErr *CoordinateBoundsError
+	if !errors.As(err, &boundsErr) {
+		t.Fatalf("expected CoordinateBoundsError, got %v", err)
+	}
+	if boundsErr.Vertex != 3 || boundsErr.Point != (Point{500000, 5700000}) {
+		t.Errorf("got vertex %d at %v, want 3 at (500000, 5700000)", boundsErr.Vertex, boundsErr.Point)
+	}
+	if !errors.Is(err, NewShapeError(ErrInvalidFormat, "", nil)) {
+		t.Errorf("expected ErrInvalidFormat, got %v", err)
+	}
+	if err := v.Validate(&PointZ{X: 0, Y: 91}); !errors.As(err, &boundsErr) || boundsErr.Vertex != 0 {
+		t.Errorf("expected point outside bounds to be flagged, got %v", err)
+	}
+	// without bounds any finite coordinate is accepted
+	if err := (&DefaultValidator{}).Validate(mixed); err != nil {
+		t.Errorf("default validator rejected projected coordinates: %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/wangningkai/go-shp/shpgeom
+
+go 1.21
+
+require (
+	github.com/twpayne/go-geom v1.5.4
+	github.com/wangningkai/go-shp v0.0.0
+)
+
+replace github.com/wangningkai/go-shp => ../
@@ -0,0 +1,378 @@
+// Package shpgeom converts between the shapes of go-shp and the geometries
+// of github.com/twpayne/go-geom, so that go-shp can be used for shapefile I/O
+// while the geometry work is done with go-geom. It is a module of its own to
+// keep go-shp itself free of dependencies.
+package shpgeom
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/twpayne/go-geom"
+	shp "github.com/wangningkai/go-shp"
+)
+
+// vertices are the points of a shape with their optional Z and M values.
+type vertices struct {
+	points     []shp.Point
+	z, m       []float64
+	hasZ, hasM bool
+}
+
+// layout returns the go-geom layout of v.
+func (v vertices) layout() geom.Layout {
+	switch {
+	case v.hasZ && v.hasM:
+		return geom.XYZM
+	case v.hasZ:
+		return geom.XYZ
+	case v.hasM:
+		return geom.XYM
+	default:
+		return geom.XY
+	}
+}
+
+// flat returns the coordinates of v in the flat layout of go-geom.
+func (v vertices) flat() ([]float64, error) {
+	if v.hasZ && len(v.z) != len(v.points) {
+		return nil, fmt.Errorf("shpgeom: %d Z values for %d points", len(v.z), len(v.points))
+	}
+	if v.hasM && len(v.m) != len(v.points) {
+		return nil, fmt.Errorf("shpgeom: %d M values for %d points", len(v.m), len(v.points))
+	}
+	flat := make([]float64, 0, len(v.points)*v.layout().Stride())
+	for i, p := range v.points {
+		flat = append(flat, p.X, p.Y)
+		if v.hasZ {
+			flat = append(flat, v.z[i])
+		}
+		if v.hasM {
+			flat = append(flat, v.m[i])
+		}
+	}
+	return flat, nil
+}
+
+// ToGeom converts shape to a go-geom geometry with the layout XY, XYZ, XYM or
+// XYZM depending on the shape type. Z shapes only get M values if they have
+// one for every point and not all of them are NaN. Lines with a single part become a LineString and
+// polygons with a single exterior ring a Polygon, otherwise a
+// MultiLineString or MultiPolygon is returned. Null shapes and MultiPatches
+// cannot be converted.
+func ToGeom(shape shp.Shape) (geom.T, error) {
+	switch s := shape.(type) {
+	case *shp.Point:
+		return geom.NewPointFlat(geom.XY, []float64{s.X, s.Y}), nil
+	case *shp.PointZ:
+		if math.IsNaN(s.M) {
+			return geom.NewPointFlat(geom.XYZ, []float64{s.X, s.Y, s.Z}), nil
+		}
+		return geom.NewPointFlat(geom.XYZM, []float64{s.X, s.Y, s.Z, s.M}), nil
+	case *shp.PointM:
+		return geom.NewPointFlat(geom.XYM, []float64{s.X, s.Y, s.M}), nil
+	case *shp.MultiPoint:
+		return multiPointToGeom(vertices{points: s.Points})
+	case *shp.MultiPointZ:
+		return multiPointToGeom(zVertices(s.Points, s.ZArray, s.MArray))
+	case *shp.MultiPointM:
+		return multiPointToGeom(vertices{points: s.Points, m: s.MArray, hasM: true})
+	case *shp.PolyLine:
+		return linesToGeom(s.Parts, vertices{points: s.Points})
+	case *shp.PolyLineZ:
+		return linesToGeom(s.Parts, zVertices(s.Points, s.ZArray, s.MArray))
+	case *shp.PolyLineM:
+		return linesToGeom(s.Parts, vertices{points: s.Points, m: s.MArray, hasM: true})
+	case *shp.Polygon:
+		return polygonsToGeom(s.Parts, vertices{points: s.Points})
+	case *shp.PolygonZ:
+		return polygonsToGeom(s.Parts, zVertices(s.Points, s.ZArray, s.MArray))
+	case *shp.PolygonM:
+		return polygonsToGeom(s.Parts, vertices{points: s.Points, m: s.MArray, hasM: true})
+	default:
+		return nil, fmt.Errorf("shpgeom: cannot convert %T", shape)
+	}
+}
+
+// zVertices returns the vertices of a Z shape, whose M values are optional.
+func zVertices(points []shp.Point, z, m []float64) vertices {
+	hasM := false
+	if len(m) == len(points) {
+		for _, value := range m {
+			if !math.IsNaN(value) {
+				hasM = true
+				break
+			}
+		}
+	}
+	return vertices{points: points, z: z, m: m, hasZ: true, hasM: hasM}
+}
+
+func multiPointToGeom(v vertices) (geom.T, error) {
+	flat, err := v.flat()
+	if err != nil {
+		return nil, err
+	}
+	return geom.NewMultiPointFlat(v.layout(), flat), nil
+}
+
+func linesToGeom(parts []int32, v vertices) (geom.T, error) {
+	flat, err := v.flat()
+	if err != nil {
+		return nil, err
+	}
+	ends, err := partEnds(parts, len(v.points), v.layout().Stride())
+	if err != nil {
+		return nil, err
+	}
+	if len(ends) == 1 {
+		return geom.NewLineStringFlat(v.layout(), flat), nil
+	}
+	return geom.NewMultiLineStringFlat(v.layout(), flat, ends), nil
+}
+
+// polygonsToGeom groups the rings of a polygon shape into polygons: every
+// clockwise ring and the first ring start a polygon and the counter-clockwise
+// rings that follow are its holes.
+func polygonsToGeom(parts []int32, v vertices) (geom.T, error) {
+	flat, err := v.flat()
+	if err != nil {
+		return nil, err
+	}
+	stride := v.layout().Stride()
+	ends, err := partEnds(parts, len(v.points), stride)
+	if err != nil {
+		return nil, err
+	}
+
+	var endss [][]int
+	start := 0
+	for i, end := range ends {
+		if i == 0 || signedArea(flat[start:end], stride) < 0 {
+			endss = append(endss, nil)
+		}
+		endss[len(endss)-1] = append(endss[len(endss)-1], end)
+		start = end
+	}
+	if len(endss) == 1 {
+		return geom.NewPolygonFlat(v.layout(), flat, endss[0]), nil
+	}
+	return geom.NewMultiPolygonFlat(v.layout(), flat, endss), nil
+}
+
+// partEnds converts the start indexes of the parts of a shape with numPoints
+// points into the end offsets used by go-geom.
+func partEnds(parts []int32, numPoints, stride int) ([]int, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("shpgeom: shape has no parts")
+	}
+	ends := make([]int, len(parts))
+	for i := range parts {
+		end := numPoints
+		if i+1 < len(parts) {
+			end = int(parts[i+1])
+		}
+		if end < int(parts[i]) || end > numPoints {
+			return nil, fmt.Errorf("shpgeom: invalid part %d", i)
+		}
+		ends[i] = end * stride
+	}
+	return ends, nil
+}
+
+// signedArea returns the signed area of the ring with the flat coordinates
+// flat, positive if the ring is counter-clockwise.
+func signedArea(flat []float64, stride int) float64 {
+	area := 0.0
+	n := len(flat) / stride
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		area += flat[i*stride]*flat[j*stride+1] - flat[j*stride]*flat[i*stride+1]
+	}
+	return area / 2
+}
+
+// FromGeom converts g to a shape. Points, MultiPoints, LineStrings,
+// MultiLineStrings, Polygons and MultiPolygons are supported; XY geometries
+// become plain shapes, XYM geometries M shapes and XYZ and XYZM geometries Z
+// shapes. Polygon rings are oriented the way shapefiles require: exterior
+// rings clockwise and holes counter-clockwise.
+func FromGeom(g geom.T) (shp.Shape, error) {
+	if _, ok := g.(*geom.GeometryCollection); ok {
+		// has no flat coordinates of its own
+		return nil, fmt.Errorf("shpgeom: cannot convert %T", g)
+	}
+	layout := g.Layout()
+	stride := layout.Stride()
+	flat := g.FlatCoords()
+	switch g := g.(type) {
+	case *geom.Point:
+		if g.Empty() {
+			return nil, fmt.Errorf("shpgeom: cannot convert an empty point")
+		}
+		v := fromFlat(layout, flat)
+		switch {
+		case v.hasZ:
+			return &shp.PointZ{X: v.points[0].X, Y: v.points[0].Y, Z: v.z[0], M: mValues(v)[0]}, nil
+		case v.hasM:
+			return &shp.PointM{X: v.points[0].X, Y: v.points[0].Y, M: v.m[0]}, nil
+		default:
+			return &v.points[0], nil
+		}
+	case *geom.MultiPoint:
+		return multiPointFromGeom(fromFlat(layout, flat)), nil
+	case *geom.LineString:
+		return partsFromGeom(false, fromFlat(layout, flat), []int{len(flat)}, stride), nil
+	case *geom.MultiLineString:
+		return partsFromGeom(false, fromFlat(layout, flat), g.Ends(), stride), nil
+	case *geom.Polygon:
+		return polygonFromGeom(layout, flat, [][]int{g.Ends()}), nil
+	case *geom.MultiPolygon:
+		return polygonFromGeom(layout, flat, g.Endss()), nil
+	default:
+		return nil, fmt.Errorf("shpgeom: cannot convert %T", g)
+	}
+}
+
+// fromFlat splits flat coordinates of the given layout into vertices.
+func fromFlat(layout geom.Layout, flat []float64) vertices {
+	stride := layout.Stride()
+	zIndex, mIndex := layout.ZIndex(), layout.MIndex()
+	v := vertices{hasZ: zIndex >= 0, hasM: mIndex >= 0}
+	for i := 0; i+stride <= len(flat); i += stride {
+		v.points = append(v.points, shp.Point{X: flat[i], Y: flat[i+1]})
+		if v.hasZ {
+			v.z = append(v.z, flat[i+zIndex])
+		}
+		if v.hasM {
+			v.m = append(v.m, flat[i+mIndex])
+		}
+	}
+	return v
+}
+
+// mValues returns the M values of a Z shape built from v, which are NaN if v
+// has none.
+func mValues(v vertices) []float64 {
+	if v.hasM {
+		return v.m
+	}
+	m := make([]float64, len(v.points))
+	for i := range m {
+		m[i] = math.NaN()
+	}
+	return m
+}
+
+func multiPointFromGeom(v vertices) shp.Shape {
+	n := int32(len(v.points))
+	box := bbox(v.points)
+	switch {
+	case v.hasZ:
+		m := mValues(v)
+		return &shp.MultiPointZ{Box: box, NumPoints: n, Points: v.points,
+			ZRange: valueRange(v.z), ZArray: v.z, MRange: valueRange(m), MArray: m}
+	case v.hasM:
+		return &shp.MultiPointM{Box: box, NumPoints: n, Points: v.points, MRange: valueRange(v.m), MArray: v.m}
+	default:
+		return &shp.MultiPoint{Box: box, NumPoints: n, Points: v.points}
+	}
+}
+
+// polygonFromGeom builds a polygon shape from the rings of one or more
+// polygons, given by the end offsets endss into flat.
+func polygonFromGeom(layout geom.Layout, flat []float64, endss [][]int) shp.Shape {
+	stride := layout.Stride()
+	v := fromFlat(layout, flat)
+	var ends []int
+	start := 0
+	for _, polygonEnds := range endss {
+		for i, end := range polygonEnds {
+			// exterior rings clockwise, holes counter-clockwise
+			clockwise := signedArea(flat[start:end], stride) < 0
+			if clockwise != (i == 0) {
+				reverse(v, start/stride, end/stride)
+			}
+			ends = append(ends, end)
+			start = end
+		}
+	}
+	return partsFromGeom(true, v, ends, stride)
+}
+
+// reverse reverses the order of the vertices from index i to j (exclusive).
+func reverse(v vertices, i, j int) {
+	for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+		v.points[a], v.points[b] = v.points[b], v.points[a]
+		if v.hasZ {
+			v.z[a], v.z[b] = v.z[b], v.z[a]
+		}
+		if v.hasM {
+			v.m[a], v.m[b] = v.m[b], v.m[a]
+		}
+	}
+}
+
+// partsFromGeom builds a line or polygon shape from v, whose parts end at the
+// flat offsets ends.
+func partsFromGeom(polygon bool, v vertices, ends []int, stride int) shp.Shape {
+	parts := make([]int32, len(ends))
+	for i := 1; i < len(ends); i++ {
+		parts[i] = int32(ends[i-1] / stride)
+	}
+	box := bbox(v.points)
+	numParts, numPoints := int32(len(parts)), int32(len(v.points))
+
+	switch {
+	case v.hasZ:
+		m := mValues(v)
+		line := shp.PolyLineZ{Box: box, NumParts: numParts, NumPoints: numPoints, Parts: parts, Points: v.points,
+			ZRange: valueRange(v.z), ZArray: v.z, MRange: valueRange(m), MArray: m}
+		if polygon {
+			p := shp.PolygonZ(line)
+			return &p
+		}
+		return &line
+	case v.hasM:
+		line := shp.PolyLineM{Box: box, NumParts: numParts, NumPoints: numPoints, Parts: parts, Points: v.points,
+			MRange: valueRange(v.m), MArray: v.m}
+		if polygon {
+			p := shp.PolygonM(line)
+			return &p
+		}
+		return &line
+	default:
+		line := shp.PolyLine{Box: box, NumParts: numParts, NumPoints: numPoints, Parts: parts, Points: v.points}
+		if polygon {
+			p := shp.Polygon(line)
+			return &p
+		}
+		return &line
+	}
+}
+
+// bbox returns the bounding box of points.
+func bbox(points []shp.Point) shp.Box {
+	if len(points) == 0 {
+		return shp.Box{}
+	}
+	box := shp.Box{MinX: points[0].X, MinY: points[0].Y, MaxX: points[0].X, MaxY: points[0].Y}
+	for _, p := range points[1:] {
+		box.ExtendWithPoint(p)
+	}
+	return box
+}
+
+// valueRange returns the minimum and maximum of values, ignoring NaN.
+func valueRange(values []float64) [2]float64 {
+	r := [2]float64{math.Inf(1), math.Inf(-1)}
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			r[0], r[1] = math.Min(r[0], v), math.Max(r[1], v)
+		}
+	}
+	if r[0] > r[1] {
+		return [2]float64{}
+	}
+	return r
+}
@@ -0,0 +1,181 @@
+package shpgeom
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/twpayne/go-geom"
+	shp "github.com/wangningkai/go-shp"
+)
+
+var (
+	// two parts of a line
+	lineA = []shp.Point{{X: 0, Y: 0}, {X: 1, Y: 2}, {X: 3, Y: 1}}
+	lineB = []shp.Point{{X: 5, Y: 5}, {X: 6, Y: 6}}
+	// a clockwise exterior ring with a counter-clockwise hole, and a second
+	// clockwise exterior ring
+	exterior = []shp.Point{{X: 0, Y: 0}, {X: 0, Y: 10}, {X: 10, Y: 10}, {X: 10, Y: 0}, {X: 0, Y: 0}}
+	hole     = []shp.Point{{X: 2, Y: 2}, {X: 4, Y: 2}, {X: 4, Y: 4}, {X: 2, Y: 4}, {X: 2, Y: 2}}
+	island   = []shp.Point{{X: 20, Y: 0}, {X: 20, Y: 5}, {X: 25, Y: 5}, {X: 25, Y: 0}, {X: 20, Y: 0}}
+)
+
+// sequence returns n values starting at start.
+func sequence(start float64, n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = start + float64(i)
+	}
+	return values
+}
+
+// join returns the points of parts and the indexes at which they start.
+func join(parts ...[]shp.Point) ([]int32, []shp.Point) {
+	var starts []int32
+	var points []shp.Point
+	for _, part := range parts {
+		starts = append(starts, int32(len(points)))
+		points = append(points, part...)
+	}
+	return starts, points
+}
+
+func polyLine(parts ...[]shp.Point) shp.PolyLine {
+	starts, points := join(parts...)
+	return shp.PolyLine{Box: shp.BBoxFromPoints(points), NumParts: int32(len(starts)),
+		NumPoints: int32(len(points)), Parts: starts, Points: points}
+}
+
+func polyLineZ(parts ...[]shp.Point) shp.PolyLineZ {
+	l := polyLine(parts...)
+	n := len(l.Points)
+	return shp.PolyLineZ{Box: l.Box, NumParts: l.NumParts, NumPoints: l.NumPoints, Parts: l.Parts, Points: l.Points,
+		ZRange: [2]float64{100, float64(100 + n - 1)}, ZArray: sequence(100, n),
+		MRange: [2]float64{-5, float64(-5 + n - 1)}, MArray: sequence(-5, n)}
+}
+
+func polyLineM(parts ...[]shp.Point) shp.PolyLineM {
+	l := polyLine(parts...)
+	n := len(l.Points)
+	return shp.PolyLineM{Box: l.Box, NumParts: l.NumParts, NumPoints: l.NumPoints, Parts: l.Parts, Points: l.Points,
+		MRange: [2]float64{7, float64(7 + n - 1)}, MArray: sequence(7, n)}
+}
+
+func TestRoundTrip(t *testing.T) {
+	multiPoint := shp.MultiPoint{Box: shp.BBoxFromPoints(lineA), NumPoints: 3, Points: lineA}
+	polygon := shp.Polygon(polyLine(exterior, hole))
+	multiPolygon := shp.Polygon(polyLine(exterior, hole, island))
+	polygonZ := shp.PolygonZ(polyLineZ(exterior, hole, island))
+	polygonM := shp.PolygonM(polyLineM(exterior, hole))
+	lineZ, lineM := polyLineZ(lineA), polyLineM(lineA, lineB)
+	line, multiLine := polyLine(lineA), polyLine(lineA, lineB)
+
+	for _, test := range []struct {
+		name   string
+		shape  shp.Shape
+		want   geom.T
+		layout geom.Layout
+	}{
+		{"Point", &shp.Point{X: 1, Y: 2}, &geom.Point{}, geom.XY},
+		{"PointZ", &shp.PointZ{X: 1, Y: 2, Z: 3, M: 4}, &geom.Point{}, geom.XYZM},
+		{"PointM", &shp.PointM{X: 1, Y: 2, M: 4}, &geom.Point{}, geom.XYM},
+		{"MultiPoint", &multiPoint, &geom.MultiPoint{}, geom.XY},
+		{"MultiPointZ", &shp.MultiPointZ{Box: multiPoint.Box, NumPoints: 3, Points: lineA,
+			ZRange: [2]float64{1, 3}, ZArray: sequence(1, 3), MRange: [2]float64{0, 2}, MArray: sequence(0, 3)},
+			&geom.MultiPoint{}, geom.XYZM},
+		{"MultiPointM", &shp.MultiPointM{Box: multiPoint.Box, NumPoints: 3, Points: lineA,
+			MRange: [2]float64{0, 2}, MArray: sequence(0, 3)}, &geom.MultiPoint{}, geom.XYM},
+		{"PolyLine", &line, &geom.LineString{}, geom.XY},
+		{"MultiPartPolyLine", &multiLine, &geom.MultiLineString{}, geom.XY},
+		{"PolyLineZ", &lineZ, &geom.LineString{}, geom.XYZM},
+		{"PolyLineM", &lineM, &geom.MultiLineString{}, geom.XYM},
+		{"Polygon", &polygon, &geom.Polygon{}, geom.XY},
+		{"MultiPartPolygon", &multiPolygon, &geom.MultiPolygon{}, geom.XY},
+		{"PolygonZ", &polygonZ, &geom.MultiPolygon{}, geom.XYZM},
+		{"PolygonM", &polygonM, &geom.Polygon{}, geom.XYM},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := ToGeom(test.shape)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reflect.TypeOf(g) != reflect.TypeOf(test.want) || g.Layout() != test.layout {
+				t.Fatalf("got %T with layout %v, want %T with layout %v", g, g.Layout(), test.want, test.layout)
+			}
+			shape, err := FromGeom(g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(shape, test.shape) {
+				t.Errorf("got %+v, want %+v", shape, test.shape)
+			}
+		})
+	}
+}
+
+func TestRoundTripWithoutM(t *testing.T) {
+	nan := math.NaN()
+	point := &shp.PointZ{X: 1, Y: 2, Z: 3, M: nan}
+	line := polyLineZ(lineA)
+	line.MRange, line.MArray = [2]float64{}, []float64{nan, nan, nan}
+
+	for _, shape := range []shp.Shape{point, &line} {
+		g, err := ToGeom(shape)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Layout() != geom.XYZ {
+			t.Errorf("%T: got layout %v, want XYZ", shape, g.Layout())
+		}
+		back, err := FromGeom(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch s := back.(type) {
+		case *shp.PointZ:
+			if s.X != 1 || s.Y != 2 || s.Z != 3 || !math.IsNaN(s.M) {
+				t.Errorf("got %+v", s)
+			}
+		case *shp.PolyLineZ:
+			m := s.MArray
+			s.MArray = line.MArray
+			if !reflect.DeepEqual(s.Points, line.Points) || !reflect.DeepEqual(s.ZArray, line.ZArray) ||
+				s.ZRange != line.ZRange || s.MRange != line.MRange {
+				t.Errorf("got %+v, want %+v", s, line)
+			}
+			for i, v := range m {
+				if !math.IsNaN(v) {
+					t.Errorf("point %d: got M %v, want NaN", i, v)
+				}
+			}
+		default:
+			t.Errorf("got %T for %T", back, shape)
+		}
+	}
+}
+
+func TestFromGeomOrientsRings(t *testing.T) {
+	// exterior counter-clockwise and hole clockwise, the GeoJSON convention
+	flat := []float64{0, 0, 10, 0, 10, 10, 0, 10, 0, 0, 2, 2, 2, 4, 4, 4, 4, 2, 2, 2}
+	shape, err := FromGeom(geom.NewPolygonFlat(geom.XY, flat, []int{10, 20}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := shp.Polygon(polyLine(exterior, hole))
+	if !reflect.DeepEqual(shape, &want) {
+		t.Errorf("got %+v, want %+v", shape, &want)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	for _, shape := range []shp.Shape{&shp.Null{}, &shp.MultiPatch{}} {
+		if _, err := ToGeom(shape); err == nil {
+			t.Errorf("converted %T without error", shape)
+		}
+	}
+	for _, g := range []geom.T{geom.NewPointEmpty(geom.XY), geom.NewGeometryCollection()} {
+		if _, err := FromGeom(g); err == nil {
+			t.Errorf("converted %T without error", g)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package shp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Spatial index sidecar layout. The file is stored next to the shapefile with
+// the extension ".bbx" and uses little-endian encoding throughout:
+//
+//	magic   [8]byte  "GOSHPIDX"
+//	version int32    currently 1
+//	count   int32    number of entries
+//	extent  Box      bounding box of all entries (4*float64)
+//	entries count * { row int32, box Box }
+//
+// Rows refer to the zero-based record index as returned by Reader.Shape. NULL
+// shapes are not indexed.
+const (
+	spatialIndexExt     = ".bbx"
+	spatialIndexMagic   = "GOSHPIDX"
+	spatialIndexVersion = int32(1)
+
+	spatialIndexHeaderLen = len(spatialIndexMagic) + 4 + 4 + 32 // magic, version, count and extent
+	spatialIndexEntryLen  = 4 + 32
+)
+
+// SpatialIndex holds the bounding box of every non-NULL record of a
+// shapefile and allows cheap bounding box queries without reading geometries.
+type SpatialIndex struct {
+	Extent  Box
+	Entries []SpatialIndexEntry
+}
+
+// SpatialIndexEntry is the bounding box of a single record.
+type SpatialIndexEntry struct {
+	Row int32
+	Box Box
+}
+
+// Intersects reports whether b and o share at least one point. Boxes that
+// only touch at the border are considered to intersect.
+func (b Box) Intersects(o Box) bool {
+	return b.MinX <= o.MaxX && o.MinX <= b.MaxX && b.MinY <= o.MaxY && o.MinY <= b.MaxY
+}
+
+// BuildSpatialIndex returns the spatial index of the shapefile. If a sidecar
+// index written by WriteIndex exists and is not older than the shapefile it is
+// loaded directly, otherwise the shapefile is scanned.
+func BuildSpatialIndex(filename string) (*SpatialIndex, error) {
+	if idx, err := loadSpatialIndex(filename); err == nil {
+		return idx, nil
+	}
+	return scanSpatialIndex(filename)
+}
+
+// WriteIndex scans the shapefile and writes its spatial index to a ".bbx"
+// sidecar file next to it, so that later calls to BuildSpatialIndex can load
+// the index instead of rescanning the shapefile.
+func WriteIndex(filename string) error {
+	idx, err := scanSpatialIndex(filename)
+	if err != nil {
+		return err
+	}
+	return idx.Save(spatialIndexPath(filename))
+}
+
+// Query returns the rows whose bounding box intersects box.
+func (idx *SpatialIndex) Query(box Box) []int {
+	var rows []int
+	if !idx.Extent.Intersects(box) {
+		return rows
+	}
+	for _, e := range idx.Entries {
+		if e.Box.Intersects(box) {
+			rows = append(rows, int(e.Row))
+		}
+	}
+	return rows
+}
+
+// Save writes the index in the sidecar format to path.
+func (idx *SpatialIndex) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return NewShapeError(ErrIO, "failed to create spatial index", err)
+	}
+	bw := bufio.NewWriter(f)
+	ew := &errWriter{Writer: bw}
+	writeLE(ew, []byte(spatialIndexMagic))
+	writeLE(ew, spatialIndexVersion)
+	writeLE(ew, int32(len(idx.Entries)))
+	writeLE(ew, idx.Extent)
+	writeLE(ew, idx.Entries)
+	if ew.e == nil {
+		ew.e = bw.Flush()
+	}
+	if err := f.Close(); err != nil && ew.e == nil {
+		ew.e = err
+	}
+	if ew.e != nil {
+		return NewShapeError(ErrIO, "failed to write spatial index", ew.e)
+	}
+	return nil
+}
+
+// spatialIndexPath returns the sidecar path for the shapefile filename.
+func spatialIndexPath(filename string) string {
+	if strings.HasSuffix(strings.ToLower(filename), ".shp") {
+		filename = filename[:len(filename)-4]
+	}
+	return filename + spatialIndexExt
+}
+
+// scanSpatialIndex builds the index by reading every shape of the shapefile.
+func scanSpatialIndex(filename string) (*SpatialIndex, error) {
+	r, err := Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	idx := &SpatialIndex{}
+	for r.Next() {
+		n, shape := r.Shape()
+		if _, ok := shape.(*Null); ok {
+			continue
+		}
+		box := shape.BBox()
+		if len(idx.Entries) == 0 {
+			idx.Extent = box
+		} else {
+			idx.Extent.Extend(box)
+		}
+		idx.Entries = append(idx.Entries, SpatialIndexEntry{Row: int32(n), Box: box})
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// loadSpatialIndex reads the sidecar index of filename. It fails if the
+// sidecar does not exist, is older than the shapefile or is malformed.
+func loadSpatialIndex(filename string) (*SpatialIndex, error) {
+	path := spatialIndexPath(filename)
+	idxStat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	shpStat, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if idxStat.ModTime().Before(shpStat.ModTime()) {
+		return nil, fmt.Errorf("spatial index %s is older than %s", path, filename)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return readSpatialIndex(bufio.NewReader(f), idxStat.Size())
+}
+
+// readSpatialIndex decodes an index in the sidecar format from r, which holds
+// size bytes. An entry count that does not fit in size is an ErrCorruptedFile
+// error, so that a damaged header cannot cause a huge allocation.
+func readSpatialIndex(r io.Reader, size int64) (*SpatialIndex, error) {
+	er := &errReader{Reader: r}
+	magic := make([]byte, len(spatialIndexMagic))
+	var version, count int32
+	readLE(er, magic)
+	readLE(er, &version)
+	readLE(er, &count)
+	if er.e != nil {
+		return nil, NewShapeError(ErrCorruptedFile, "failed to read spatial index header", er.e)
+	}
+	if string(magic) != spatialIndexMagic || version != spatialIndexVersion || count < 0 {
+		return nil, NewShapeError(ErrInvalidFormat, "unrecognized spatial index", nil)
+	}
+	if int64(count) > (size-int64(spatialIndexHeaderLen))/spatialIndexEntryLen {
+		return nil, NewShapeError(ErrCorruptedFile,
+			fmt.Sprintf("spatial index declares %d entries but holds %d bytes", count, size), nil)
+	}
+	idx := &SpatialIndex{Entries: make([]SpatialIndexEntry, count)}
+	readLE(er, &idx.Extent)
+	readLE(er, idx.Entries)
+	if er.e != nil {
+		return nil, NewShapeError(ErrCorruptedFile, "failed to read spatial index entries", er.e)
+	}
+	return idx, nil
+}
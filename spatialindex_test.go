@@ -0,0 +1,106 @@
+package shp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSpatialIndexRoundTrip(t *testing.T) {
+	filename := "test_files/polyline.shp"
+	defer os.Remove(spatialIndexPath(filename))
+
+	scanned, err := BuildSpatialIndex(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scanned.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(scanned.Entries))
+	}
+
+	if err := WriteIndex(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadSpatialIndex(filename)
+	if err != nil {
+		t.Fatalf("failed to load sidecar index: %v", err)
+	}
+	if !reflect.DeepEqual(scanned, loaded) {
+		t.Errorf("loaded index %+v differs from scanned index %+v", loaded, scanned)
+	}
+
+	if rows := loaded.Query(Box{-1, -1, 1, 1}); !reflect.DeepEqual(rows, []int{0}) {
+		t.Errorf("got rows %v, want [0]", rows)
+	}
+	if rows := loaded.Query(Box{100, 100, 101, 101}); len(rows) != 0 {
+		t.Errorf("got rows %v, want none", rows)
+	}
+}
+
+func TestReadSpatialIndexCorruptCount(t *testing.T) {
+	idx := &SpatialIndex{Extent: Box{0, 0, 1, 1}, Entries: []SpatialIndexEntry{{Row: 0, Box: Box{0, 0, 1, 1}}}}
+	path := t.TempDir() + "/index.bbx"
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSpatialIndex(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("intact index: %v", err)
+	}
+
+	// a count far beyond the size of the file must not be allocated
+	binary.LittleEndian.PutUint32(data[len(spatialIndexMagic)+4:], math.MaxInt32)
+	_, err = readSpatialIndex(bytes.NewReader(data), int64(len(data)))
+	if !errors.Is(err, NewShapeError(ErrCorruptedFile, "", nil)) {
+		t.Errorf("got %v, want ErrCorruptedFile", err)
+	}
+}
+
+func TestLocatePoint(t *testing.T) {
+	filename := t.TempDir() + "/zones.shp"
+	w, err := Create(filename, POLYGON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a square with a hole, followed by a square filling that hole
+	w.Write(NewPolygon([][]Point{
+		{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}},
+		{{4, 4}, {6, 4}, {6, 6}, {4, 6}, {4, 4}},
+	}))
+	w.Write(NewPolygon([][]Point{{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}}}))
+	w.Close()
+
+	tests := []struct {
+		p     Point
+		index int
+		found bool
+	}{
+		{Point{1, 1}, 0, true},
+		{Point{5, 5}, 1, true},
+		{Point{20, 20}, 0, false},
+	}
+	for _, withIndex := range []bool{false, true} {
+		if withIndex {
+			if err := WriteIndex(filename); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, tt := range tests {
+			index, found, err := LocatePoint(filename, tt.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if index != tt.index || found != tt.found {
+				t.Errorf("index %v: LocatePoint(%v) = %d, %v, want %d, %v",
+					withIndex, tt.p, index, found, tt.index, tt.found)
+			}
+		}
+	}
+}
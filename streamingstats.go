@@ -0,0 +1,162 @@
+package shp
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// StreamingStats 流式统计信息，内存占用与记录数无关
+type StreamingStats struct {
+	TotalShapes int
+	ShapeTypes  map[ShapeType]int
+	BoundingBox Box
+	// Fields 各字段的统计信息，顺序与 DBF 字段一致
+	Fields []StreamingFieldStats
+}
+
+// StreamingFieldStats 单个字段的流式统计信息
+type StreamingFieldStats struct {
+	Name      string
+	FieldType byte
+	// Count 非空值的数量，NullValues 空值的数量
+	Count      int
+	NullValues int
+	MinLength  int
+	MaxLength  int
+	// NumericValues 可解析为数字的值的数量（仅数值字段），Min、Max、Mean 为这些值的统计
+	NumericValues int
+	Min           float64
+	Max           float64
+	Mean          float64
+	// EstimatedUnique 不同非空值数量的 HyperLogLog 估计值，相对误差约 3%
+	EstimatedUnique int
+
+	unique hyperLogLog
+}
+
+// AnalyzeShapefileStreaming 流式分析 Shapefile：统计记录数、几何类型，以及每个字段的
+// 空值数、长度范围、数值的最小值/最大值/平均值和不同值数量的估计。与 AnalyzeShapefile
+// 不同，它不保存任何属性值，每个字段只占用约 1KB 的固定内存，适用于内存受限时分析
+// 字段非常多的属性表。不计算面积与周长
+func (StatisticsUtils) AnalyzeShapefileStreaming(filename string) (*StreamingStats, error) {
+	reader, err := Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	fields := reader.Fields()
+	stats := &StreamingStats{
+		ShapeTypes:  make(map[ShapeType]int),
+		BoundingBox: reader.BBox(),
+		Fields:      make([]StreamingFieldStats, len(fields)),
+	}
+	for i, f := range fields {
+		stats.Fields[i] = StreamingFieldStats{Name: f.String(), FieldType: f.Fieldtype}
+	}
+
+	for reader.Next() {
+		n, shape := reader.Shape()
+		stats.TotalShapes++
+		stats.ShapeTypes[shapeTypeOf(shape)]++
+		for i, f := range fields {
+			stats.Fields[i].add(f, trimAttribute(reader.ReadAttribute(n, i)))
+		}
+	}
+	for i := range stats.Fields {
+		stats.Fields[i].EstimatedUnique = stats.Fields[i].unique.estimate()
+		stats.Fields[i].unique = nil
+	}
+	return stats, reader.Err()
+}
+
+// add 记录字段 f 的一个值
+func (s *StreamingFieldStats) add(f Field, value string) {
+	if value == "" {
+		s.NullValues++
+		return
+	}
+	if s.Count == 0 || len(value) < s.MinLength {
+		s.MinLength = len(value)
+	}
+	if len(value) > s.MaxLength {
+		s.MaxLength = len(value)
+	}
+	s.Count++
+	if s.unique == nil {
+		s.unique = make(hyperLogLog, hllRegisters)
+	}
+	s.unique.add(value)
+
+	var v float64
+	switch typed := typedAttribute(f, value).(type) {
+	case int64:
+		v = float64(typed)
+	case float64:
+		v = typed
+	default:
+		return
+	}
+	if s.NumericValues == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.NumericValues == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.NumericValues++
+	// 增量计算平均值，避免大数求和溢出
+	s.Mean += (v - s.Mean) / float64(s.NumericValues)
+}
+
+// HyperLogLog 的精度：2^hllPrecision 个寄存器
+const (
+	hllPrecision = 10
+	hllRegisters = 1 << hllPrecision
+)
+
+// hyperLogLog 是估计不同值数量的 HyperLogLog 草图，每个寄存器一个字节
+type hyperLogLog []uint8
+
+// add 将 value 加入草图
+func (h hyperLogLog) add(value string) {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(value))
+	x := mix64(hasher.Sum64())
+	index := x >> (64 - hllPrecision)
+	// 剩余位的前导零个数加一，最后一位哨兵保证结果不超过 64-hllPrecision+1
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h[index] {
+		h[index] = rank
+	}
+}
+
+// estimate 返回不同值数量的估计值，基数较小时使用线性计数修正
+func (h hyperLogLog) estimate() int {
+	if h == nil {
+		return 0
+	}
+	m := float64(hllRegisters)
+	sum, zeros := 0.0, 0
+	for _, r := range h {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return int(math.Round(e))
+}
+
+// mix64 打散 FNV 哈希的各个位（SplitMix64 的终结函数），使高位分布均匀
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
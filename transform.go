@@ -0,0 +1,328 @@
+package shp
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// transformShapefile copies the shapefile input to output, passing every shape
+// through fn. fn returns the shape to write and the values of the fields
+// extra, which are appended to the attribute table. Existing attributes are
+// copied unchanged, as are the .prj and .cpg files if present.
+func transformShapefile(input, output string, extra []Field, fn func(Shape) (Shape, []interface{})) error {
+	return transformShapefileAs(input, output, NULL, extra, fn)
+}
+
+// transformShapefileAs is transformShapefile writing shapes of type
+// shapeType, or of the type of input if shapeType is NULL. Records for which
+// fn returns a nil shape are left out of output.
+func transformShapefileAs(input, output string, shapeType ShapeType, extra []Field,
+	fn func(Shape) (Shape, []interface{}),
+) error {
+	r, err := Open(input)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	if shapeType == NULL {
+		shapeType = r.GeometryType
+	}
+
+	fields := r.Fields()
+	for _, e := range extra {
+		for _, f := range fields {
+			if strings.EqualFold(f.String(), e.String()) {
+				return NewShapeError(ErrInvalidField, fmt.Sprintf("field %s already exists", e.String()), nil)
+			}
+		}
+	}
+
+	w, err := Create(output, shapeType)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := w.SetFields(append(append([]Field{}, fields...), extra...)); err != nil {
+		return err
+	}
+
+	for r.Next() {
+		n, shape := r.Shape()
+		shape, values := fn(shape)
+		if shape == nil {
+			continue
+		}
+		written, err := w.WriteChecked(shape)
+		if err != nil {
+			return err
+		}
+		row := int(written)
+		for i := range fields {
+			if err := w.WriteAttribute(row, i, r.ReadAttribute(n, i)); err != nil {
+				return err
+			}
+		}
+		for i, v := range values {
+			if err := w.WriteAttribute(row, len(fields)+i, v); err != nil {
+				return err
+			}
+		}
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	return copySidecars(shapefileBase(input), shapefileBase(output), ".prj", ".cpg")
+}
+
+// copySidecars copies the files with the given extensions from the shapefile
+// inBase to outBase. Missing files are skipped.
+func copySidecars(inBase, outBase string, exts ...string) error {
+	for _, ext := range exts {
+		data, err := os.ReadFile(inBase + ext)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return NewShapeError(ErrIO, "failed to read "+inBase+ext, err)
+		}
+		if err := os.WriteFile(outBase+ext, data, 0o666); err != nil {
+			return NewShapeError(ErrIO, "failed to write "+outBase+ext, err)
+		}
+	}
+	return nil
+}
+
+// SimplifyShapefile copies the shapefile input to output, simplifying the
+// geometry of every line and polygon with the Douglas-Peucker algorithm part
+// by part. Polygon rings stay closed; a ring that would collapse to fewer than
+// four points is kept unchanged. Z and M values of the remaining vertices are
+// preserved. Point shapes and attributes are copied unchanged.
+func SimplifyShapefile(input, output string, tolerance float64) error {
+	return transformShapefile(input, output, nil, func(shape Shape) (Shape, []interface{}) {
+		return simplifyShape(shape, tolerance), nil
+	})
+}
+
+// simplifyShape returns a simplified copy of a line or polygon shape and
+// shape itself for all other types.
+func simplifyShape(shape Shape, tolerance float64) Shape {
+	switch s := shape.(type) {
+	case *PolyLine:
+		r := *s
+		r.Parts, r.Points, _, _ = simplifyParts(s.Parts, s.Points, nil, nil, tolerance, false)
+		r.NumParts, r.NumPoints, r.Box = int32(len(r.Parts)), int32(len(r.Points)), BBoxFromPoints(r.Points)
+		return &r
+	case *Polygon:
+		r := *s
+		r.Parts, r.Points, _, _ = simplifyParts(s.Parts, s.Points, nil, nil, tolerance, true)
+		r.NumParts, r.NumPoints, r.Box = int32(len(r.Parts)), int32(len(r.Points)), BBoxFromPoints(r.Points)
+		return &r
+	case *PolyLineZ:
+		r := *s
+		r.Parts, r.Points, r.ZArray, r.MArray = simplifyParts(s.Parts, s.Points, s.ZArray, s.MArray, tolerance, false)
+		r.NumParts, r.NumPoints, r.Box = int32(len(r.Parts)), int32(len(r.Points)), BBoxFromPoints(r.Points)
+		r.ZRange, r.MRange = valueRange(r.ZArray), valueRange(r.MArray)
+		return &r
+	case *PolygonZ:
+		r := *s
+		r.Parts, r.Points, r.ZArray, r.MArray = simplifyParts(s.Parts, s.Points, s.ZArray, s.MArray, tolerance, true)
+		r.NumParts, r.NumPoints, r.Box = int32(len(r.Parts)), int32(len(r.Points)), BBoxFromPoints(r.Points)
+		r.ZRange, r.MRange = valueRange(r.ZArray), valueRange(r.MArray)
+		return &r
+	case *PolyLineM:
+		r := *s
+		r.Parts, r.Points, _, r.MArray = simplifyParts(s.Parts, s.Points, nil, s.MArray, tolerance, false)
+		r.NumParts, r.NumPoints, r.Box = int32(len(r.Parts)), int32(len(r.Points)), BBoxFromPoints(r.Points)
+		r.MRange = valueRange(r.MArray)
+		return &r
+	case *PolygonM:
+		r := *s
+		r.Parts, r.Points, _, r.MArray = simplifyParts(s.Parts, s.Points, nil, s.MArray, tolerance, true)
+		r.NumParts, r.NumPoints, r.Box = int32(len(r.Parts)), int32(len(r.Points)), BBoxFromPoints(r.Points)
+		r.MRange = valueRange(r.MArray)
+		return &r
+	default:
+		return shape
+	}
+}
+
+// simplifyParts simplifies every part of a multi-part geometry and returns the
+// new part offsets and points together with the Z and M values of the kept
+// vertices. zs and ms may be nil. If closed is set, parts that would end up
+// with fewer than minRingPoints points are kept unchanged.
+func simplifyParts(parts []int32, points []Point, zs, ms []float64, tolerance float64, closed bool) ([]int32, []Point, []float64, []float64) {
+	var (
+		newParts  []int32
+		newPoints []Point
+		newZs     []float64
+		newMs     []float64
+	)
+	for i, start := range parts {
+		end := len(points)
+		if i+1 < len(parts) {
+			end = int(parts[i+1])
+		}
+		if int(start) > end || end > len(points) {
+			continue
+		}
+		indices := douglasPeuckerIndices(points[start:end], tolerance)
+		if closed && len(indices) < minRingPoints {
+			indices = douglasPeuckerIndices(points[start:end], 0)
+		}
+		newParts = append(newParts, int32(len(newPoints)))
+		for _, k := range indices {
+			k += int(start)
+			newPoints = append(newPoints, points[k])
+			if k < len(zs) {
+				newZs = append(newZs, zs[k])
+			}
+			if k < len(ms) {
+				newMs = append(newMs, ms[k])
+			}
+		}
+	}
+	return newParts, newPoints, newZs, newMs
+}
+
+// valueRange returns the minimum and maximum of values, ignoring NaN (no data).
+func valueRange(values []float64) [2]float64 {
+	var r [2]float64
+	first := true
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue // no data
+		}
+		if first || v < r[0] {
+			r[0] = v
+		}
+		if first || v > r[1] {
+			r[1] = v
+		}
+		first = false
+	}
+	return r
+}
+
+// CentroidsToShapefile writes the area-weighted centroid of every polygon of
+// the shapefile polygonInput to the POINT shapefile pointOutput, together with
+// the attributes of the polygon, e.g. to place labels. Polygons without any
+// vertices are left out. The .prj and .cpg files are copied if present.
+func CentroidsToShapefile(polygonInput, pointOutput string) error {
+	r, err := Open(polygonInput)
+	if err != nil {
+		return err
+	}
+	shapeType := r.GeometryType
+	_ = r.Close()
+	switch shapeType {
+	case POLYGON, POLYGONZ, POLYGONM:
+	default:
+		return NewShapeError(ErrUnsupportedType,
+			fmt.Sprintf("%s has shape type %s, expected a polygon type", polygonInput, shapeType), nil)
+	}
+
+	return transformShapefileAs(polygonInput, pointOutput, POINT, nil, func(shape Shape) (Shape, []interface{}) {
+		c, ok := GeometryUtils{}.PolygonCentroid(shape)
+		if !ok {
+			return nil, nil
+		}
+		return &c, nil
+	})
+}
+
+// ReprojectShapefileWithProjection copies the shapefile input to output,
+// passing the X and Y coordinates of every point through transform, and
+// writes outWKT to the .prj file of output so that it is tagged with its new
+// coordinate system. Z and M values and attributes are copied unchanged, and
+// the bounding boxes of the shapes and the file are recomputed. If outWKT is
+// empty, output gets no .prj file rather than the stale one of input.
+func ReprojectShapefileWithProjection(input, output string, transform func(Point) Point, outWKT string) error {
+	err := transformShapefile(input, output, nil, func(shape Shape) (Shape, []interface{}) {
+		return mapShapePoints(shape, transform), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	prj := shapefileBase(output) + ".prj"
+	if outWKT == "" {
+		if err := os.Remove(prj); err != nil && !os.IsNotExist(err) {
+			return NewShapeError(ErrIO, "failed to remove "+prj, err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(prj, []byte(outWKT), 0o666); err != nil {
+		return NewShapeError(ErrIO, "failed to write "+prj, err)
+	}
+	return nil
+}
+
+// mapShapePoints returns a copy of shape with fn applied to the X and Y
+// coordinates of every point and the bounding box recomputed. Null shapes are
+// returned as-is.
+func mapShapePoints(shape Shape, fn func(Point) Point) Shape {
+	mapPoints := func(points []Point) []Point {
+		mapped := make([]Point, len(points))
+		for i, p := range points {
+			mapped[i] = fn(p)
+		}
+		return mapped
+	}
+
+	switch s := shape.(type) {
+	case *Point:
+		p := fn(*s)
+		return &p
+	case *PointZ:
+		p := fn(Point{s.X, s.Y})
+		return &PointZ{p.X, p.Y, s.Z, s.M}
+	case *PointM:
+		p := fn(Point{s.X, s.Y})
+		return &PointM{p.X, p.Y, s.M}
+	case *PolyLine:
+		r := *s
+		r.Points = mapPoints(s.Points)
+		shape = &r
+	case *Polygon:
+		r := *s
+		r.Points = mapPoints(s.Points)
+		shape = &r
+	case *MultiPoint:
+		r := *s
+		r.Points = mapPoints(s.Points)
+		shape = &r
+	case *PolyLineZ:
+		r := *s
+		r.Points = mapPoints(s.Points)
+		shape = &r
+	case *PolygonZ:
+		r := *s
+		r.Points = mapPoints(s.Points)
+		shape = &r
+	case *MultiPointZ:
+		r := *s
+		r.Points = mapPoints(s.Points)
+		shape = &r
+	case *PolyLineM:
+		r := *s
+		r.Points = mapPoints(s.Points)
+		shape = &r
+	case *PolygonM:
+		r := *s
+		r.Points = mapPoints(s.Points)
+		shape = &r
+	case *MultiPointM:
+		r := *s
+		r.Points = mapPoints(s.Points)
+		shape = &r
+	case *MultiPatch:
+		r := *s
+		r.Points = mapPoints(s.Points)
+		shape = &r
+	}
+	RecomputeBBox(shape)
+	return shape
+}
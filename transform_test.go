@@ -0,0 +1,187 @@
+package shp
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSimplifyShapefile(t *testing.T) {
+	dir := t.TempDir()
+	input := dir + "/in.shp"
+	w, err := Create(input, POLYGONZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{StringField("NAME", 5)})
+	// a square with a slightly bent edge and a tiny hole that must not collapse
+	outer := []Point{{0, 0}, {0, 5}, {0.01, 10}, {10, 10}, {10, 0}, {0, 0}}
+	hole := []Point{{1, 1}, {1.01, 1}, {1.01, 1.01}, {1, 1}}
+	points := append(append([]Point{}, outer...), hole...)
+	zs := []float64{1, 2, 3, 4, 5, 1, 6, 7, 8, 6}
+	w.Write(&PolygonZ{
+		Box:       BBoxFromPoints(points),
+		NumParts:  2,
+		NumPoints: int32(len(points)),
+		Parts:     []int32{0, int32(len(outer))},
+		Points:    points,
+		ZArray:    zs,
+		MArray:    make([]float64, len(points)),
+	})
+	_ = w.WriteAttribute(0, 0, "a")
+	w.Close()
+
+	output := dir + "/out.shp"
+	if err := SimplifyShapefile(input, output, 0.1); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if !r.Next() {
+		t.Fatal("no shapes in output")
+	}
+	n, shape := r.Shape()
+	p := shape.(*PolygonZ)
+	wantPoints := []Point{{0, 0}, {0.01, 10}, {10, 10}, {10, 0}, {0, 0}, {1, 1}, {1.01, 1}, {1.01, 1.01}, {1, 1}}
+	if !reflect.DeepEqual(p.Points, wantPoints) {
+		t.Errorf("got points %v, want %v", p.Points, wantPoints)
+	}
+	if want := []int32{0, 5}; !reflect.DeepEqual(p.Parts, want) {
+		t.Errorf("got parts %v, want %v", p.Parts, want)
+	}
+	if want := []float64{1, 3, 4, 5, 1, 6, 7, 8, 6}; !reflect.DeepEqual(p.ZArray, want) {
+		t.Errorf("got Z values %v, want %v", p.ZArray, want)
+	}
+	if p.ZRange != [2]float64{1, 8} {
+		t.Errorf("got Z range %v", p.ZRange)
+	}
+	if got := r.ReadAttribute(n, 0); got != "a" {
+		t.Errorf("got attribute %q, want %q", got, "a")
+	}
+}
+
+func TestCentroidsToShapefile(t *testing.T) {
+	dir := t.TempDir()
+	input := dir + "/zones.shp"
+	w, err := Create(input, POLYGON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{StringField("NAME", 5)})
+	// an L shape, whose centroid differs from the average of its vertices,
+	// and a square with a hole in its right half
+	w.Write(NewPolygon([][]Point{{{0, 0}, {0, 2}, {1, 2}, {1, 1}, {2, 1}, {2, 0}, {0, 0}}}))
+	_ = w.WriteAttribute(0, 0, "l")
+	w.Write(NewPolygon([][]Point{
+		{{0, 0}, {0, 4}, {4, 4}, {4, 0}, {0, 0}},
+		{{2, 0}, {4, 0}, {4, 4}, {2, 4}, {2, 0}},
+	}))
+	_ = w.WriteAttribute(1, 0, "sq")
+	w.Close()
+
+	output := dir + "/centroids.shp"
+	if err := CentroidsToShapefile(input, output); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.GeometryType != POINT {
+		t.Fatalf("got shape type %s, want POINT", r.GeometryType)
+	}
+	want := []struct {
+		p    Point
+		name string
+	}{
+		{Point{5.0 / 6, 5.0 / 6}, "l"},
+		{Point{1, 2}, "sq"},
+	}
+	n := 0
+	for r.Next() {
+		i, shape := r.Shape()
+		p := shape.(*Point)
+		if !p.AlmostEquals(want[i].p, 1e-9) {
+			t.Errorf("record %d: got centroid %v, want %v", i, *p, want[i].p)
+		}
+		if name := r.ReadAttribute(i, 0); name != want[i].name {
+			t.Errorf("record %d: got name %q, want %q", i, name, want[i].name)
+		}
+		n++
+	}
+	if n != len(want) {
+		t.Errorf("got %d records, want %d", n, len(want))
+	}
+
+	if err := CentroidsToShapefile(output, dir+"/again.shp"); err == nil {
+		t.Error("expected error for point input")
+	}
+}
+
+func TestReprojectShapefileWithProjection(t *testing.T) {
+	dir := t.TempDir()
+	input := dir + "/in.shp"
+	w, err := Create(input, POLYLINEZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{StringField("NAME", 5)})
+	w.Write(&PolyLineZ{
+		NumParts:  1,
+		NumPoints: 2,
+		Parts:     []int32{0},
+		Points:    []Point{{1, 2}, {3, 4}},
+		ZArray:    []float64{5, 6},
+		MArray:    []float64{7, 8},
+	})
+	_ = w.WriteAttribute(0, 0, "road")
+	w.Close()
+	if err := os.WriteFile(dir+"/in.prj", []byte("OLD"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	output := dir + "/out.shp"
+	shift := func(p Point) Point { return Point{p.X * 10, p.Y + 100} }
+	if err := ReprojectShapefileWithProjection(input, output, shift, "NEW"); err != nil {
+		t.Fatal(err)
+	}
+
+	if prj, err := os.ReadFile(dir + "/out.prj"); err != nil || string(prj) != "NEW" {
+		t.Errorf("got .prj %q, %v", prj, err)
+	}
+	r, err := Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if want := (Box{10, 102, 30, 104}); r.BBox() != want {
+		t.Errorf("got file bbox %v, want %v", r.BBox(), want)
+	}
+	if !r.Next() {
+		t.Fatal("no shapes in output")
+	}
+	n, shape := r.Shape()
+	line := shape.(*PolyLineZ)
+	if want := []Point{{10, 102}, {30, 104}}; !reflect.DeepEqual(line.Points, want) {
+		t.Errorf("got points %v, want %v", line.Points, want)
+	}
+	if want := []float64{5, 6}; !reflect.DeepEqual(line.ZArray, want) {
+		t.Errorf("got Z values %v, want %v", line.ZArray, want)
+	}
+	if name := r.ReadAttribute(n, 0); name != "road" {
+		t.Errorf("got name %q, want road", name)
+	}
+
+	if err := ReprojectShapefileWithProjection(input, output, shift, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir + "/out.prj"); !os.IsNotExist(err) {
+		t.Errorf("stale .prj was kept without a projection")
+	}
+}
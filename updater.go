@@ -0,0 +1,110 @@
+package shp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Updater edits the attributes of an existing shapefile in place. Only the
+// DBF file is opened; geometries and the number of records can't be changed.
+type Updater struct {
+	// w holds the DBF layout and configuration and encodes the values
+	w       *Writer
+	numRows int
+	updated bool
+}
+
+// OpenForUpdate opens the DBF file of the shapefile filename for editing
+// attribute values in place, e.g. to correct a value without rewriting the
+// whole file. Writer options such as WithStringEncoding and WithLastUpdate
+// apply to the values written and to the date of the last update recorded in
+// the header. It is an error if the shapefile has no DBF file.
+func OpenForUpdate(filename string, opts ...WriterOption) (*Updater, error) {
+	base := shapefileBase(filename)
+	dbf, err := os.OpenFile(base+".dbf", os.O_RDWR, 0o666)
+	if err != nil {
+		return nil, NewShapeError(ErrIO, "failed to open DBF", err)
+	}
+	numRecords, _, _, err := readDbfLayout(dbf)
+	if err != nil {
+		_ = dbf.Close()
+		return nil, err
+	}
+	_ = dbf.Close()
+
+	w := &Writer{filename: base, config: DefaultWriterConfig()}
+	for _, opt := range opts {
+		opt(w.config)
+	}
+	if err := openAndInitDbf(base, w); err != nil {
+		return nil, err
+	}
+	return &Updater{w: w, numRows: int(numRecords)}, nil
+}
+
+// Fields returns the fields of the DBF table.
+func (u *Updater) Fields() []Field {
+	return u.w.dbfFields
+}
+
+// UpdateAttribute overwrites the value of field in row with value, which is
+// encoded as by Writer.WriteAttribute. The rest of the field is filled with
+// blanks, so a shorter value replaces a longer one; a nil value blanks the
+// field, which sets binary fields to zero bytes as for records written
+// without a value. Values that don't fit the field size are an error and
+// leave the field unchanged.
+func (u *Updater) UpdateAttribute(row, field int, value interface{}) error {
+	if row < 0 || row >= u.numRows {
+		return NewShapeError(ErrInvalidField, fmt.Sprintf("record %d out of range [0, %d)", row, u.numRows), nil)
+	}
+	if field < 0 || field >= len(u.w.dbfFields) {
+		return NewShapeError(ErrInvalidField, fmt.Sprintf("field %d out of range [0, %d)", field, len(u.w.dbfFields)), nil)
+	}
+	if value == nil {
+		value = ""
+	}
+	buf, err := u.w.encodeAttribute(field, value)
+	if err != nil {
+		return err
+	}
+	if pad := int(u.w.dbfFields[field].Size) - len(buf); pad > 0 {
+		blank := byte(' ')
+		if isBinaryFieldType(u.w.dbfFields[field].Fieldtype) {
+			blank = 0
+		}
+		buf = append(buf, bytes.Repeat([]byte{blank}, pad)...)
+	}
+	if err := u.w.writeFieldBytes(row, field, buf); err != nil {
+		return NewShapeError(ErrIO, "failed to update DBF", err)
+	}
+	u.updated = true
+	return nil
+}
+
+// Close records the date of the last update in the DBF header if any value
+// was changed and closes the file.
+func (u *Updater) Close() error {
+	if u.updated {
+		date := time.Now()
+		if !u.w.config.LastUpdate.IsZero() {
+			date = u.w.config.LastUpdate
+		}
+		if _, err := u.w.dbf.Seek(1, io.SeekStart); err != nil {
+			_ = u.w.dbf.Close()
+			return NewShapeError(ErrIO, "failed to update DBF header", err)
+		}
+		ew := &errWriter{Writer: u.w.dbf}
+		writeLE(ew, []byte{byte(date.Year() - 1900), byte(date.Month()), byte(date.Day())})
+		if ew.e != nil {
+			_ = u.w.dbf.Close()
+			return NewShapeError(ErrIO, "failed to update DBF header", ew.e)
+		}
+	}
+	if err := u.w.dbf.Close(); err != nil {
+		return NewShapeError(ErrIO, "failed to close DBF", err)
+	}
+	return nil
+}
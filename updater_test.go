@@ -0,0 +1,128 @@
+package shp
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestOpenForUpdate(t *testing.T) {
+	filename := t.TempDir() + "/cities.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{StringField("NAME", 10), NumberField("POP", 6), FloatField("AREA", 8, 2)})
+	for i, name := range []string{"Berlinn", "Hamburg"} {
+		w.Write(&Point{float64(i), 0})
+		_ = w.WriteAttribute(i, 0, name)
+		_ = w.WriteAttribute(i, 1, 1000+i)
+		_ = w.WriteAttribute(i, 2, 12.5)
+	}
+	w.Close()
+
+	u, err := OpenForUpdate(filename, WithLastUpdate(time.Date(2020, 5, 17, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Fields()) != 3 {
+		t.Fatalf("got %d fields, want 3", len(u.Fields()))
+	}
+	for _, update := range []struct {
+		row, field int
+		value      interface{}
+	}{
+		{0, 0, "Berlin"},
+		{1, 1, 42},
+		{1, 2, nil},
+	} {
+		if err := u.UpdateAttribute(update.row, update.field, update.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := u.UpdateAttribute(0, 0, "Charlottenburg"); err == nil {
+		t.Error("value longer than the field accepted")
+	}
+	if err := u.UpdateAttribute(2, 0, "x"); err == nil {
+		t.Error("row out of range accepted")
+	}
+	if err := u.UpdateAttribute(0, 3, "x"); err == nil {
+		t.Error("field out of range accepted")
+	}
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	want := [][]string{{"Berlin", "1000", "12.50"}, {"Hamburg", "42", ""}}
+	for row, values := range want {
+		for field, value := range values {
+			if got := r.ReadAttribute(row, field); got != value {
+				t.Errorf("row %d field %d: got %q, want %q", row, field, got, value)
+			}
+		}
+	}
+	if r.AttributeCount() != 2 {
+		t.Errorf("got %d rows, want 2", r.AttributeCount())
+	}
+
+	header := make([]byte, 4)
+	dbf, err := os.Open(shapefileBase(filename) + ".dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbf.Close()
+	if _, err := dbf.ReadAt(header, 0); err != nil {
+		t.Fatal(err)
+	}
+	if header[1] != 120 || header[2] != 5 || header[3] != 17 {
+		t.Errorf("got last update %v, want 120 5 17", header[1:])
+	}
+
+	if _, err := OpenForUpdate(t.TempDir() + "/missing.shp"); err == nil {
+		t.Error("opened a missing shapefile")
+	}
+}
+
+func TestUpdateBinaryField(t *testing.T) {
+	filename := t.TempDir() + "/binary.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{IntegerField("COUNT")})
+	for i := 0; i < 2; i++ {
+		w.Write(&Point{float64(i), 0})
+		_ = w.WriteAttribute(i, 0, 7)
+	}
+	w.Close()
+
+	u, err := OpenForUpdate(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.UpdateAttribute(0, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.UpdateAttribute(1, 0, 538976288); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for row, want := range []int64{0, 538976288} {
+		if got := r.ReadAttributeTyped(row, 0); got != want {
+			t.Errorf("row %d: got %v, want %d", row, got, want)
+		}
+	}
+}
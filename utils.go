@@ -35,6 +35,38 @@ func (GeometryUtils) Area(points []Point) float64 {
 	return math.Abs(area) / 2.0
 }
 
+// signedArea 计算环的有向面积，逆时针为正，顺时针为负
+func signedArea(ring []Point) float64 {
+	area := 0.0
+	n := len(ring)
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		area += ring[i].X*ring[j].Y - ring[j].X*ring[i].Y
+	}
+	return area / 2.0
+}
+
+// isClockwise 判断环是否为顺时针（Shapefile 外环方向）
+func isClockwise(ring []Point) bool {
+	return signedArea(ring) < 0
+}
+
+// groupPolygonRings 将多边形的环按外环分组，每组第一个为外环，其后为其内环（洞）。
+// Shapefile 中外环为顺时针，内环为逆时针；内环归属于它之前最近的外环。
+// 若第一个环即为逆时针，则将其视为外环。
+func groupPolygonRings(rings [][]Point) [][][]Point {
+	var groups [][][]Point
+	for _, ring := range rings {
+		if len(groups) == 0 || isClockwise(ring) {
+			groups = append(groups, [][]Point{ring})
+			continue
+		}
+		last := len(groups) - 1
+		groups[last] = append(groups[last], ring)
+	}
+	return groups
+}
+
 // Centroid 计算多边形质心
 func (GeometryUtils) Centroid(points []Point) Point {
 	if len(points) == 0 {
@@ -51,6 +83,38 @@ func (GeometryUtils) Centroid(points []Point) Point {
 	return Point{X: sumX / n, Y: sumY / n}
 }
 
+// PolygonCentroid 计算多边形（Polygon、PolygonZ 或 PolygonM）按面积加权的质心，
+// 内环（洞）的面积会被扣除。面积为零的多边形退化为所有顶点的平均值；
+// 非多边形或没有顶点的形状返回 false
+func (g GeometryUtils) PolygonCentroid(shape Shape) (Point, bool) {
+	rings, polygon := shapeParts(shape)
+	if !polygon {
+		return Point{}, false
+	}
+
+	var area, cx, cy float64
+	var points []Point
+	for _, ring := range rings {
+		n := len(ring)
+		for i := 0; i < n; i++ {
+			j := (i + 1) % n
+			cross := ring[i].X*ring[j].Y - ring[j].X*ring[i].Y
+			area += cross
+			cx += (ring[i].X + ring[j].X) * cross
+			cy += (ring[i].Y + ring[j].Y) * cross
+		}
+		points = append(points, ring...)
+	}
+	if len(points) == 0 {
+		return Point{}, false
+	}
+	if area == 0 {
+		return g.Centroid(points), true
+	}
+	// area 为两倍的有向面积，质心 = Σ / (6A) = Σ / (3·area)
+	return Point{X: cx / (3 * area), Y: cy / (3 * area)}, true
+}
+
 // IsPointInPolygon 判断点是否在多边形内 (射线法)
 func (GeometryUtils) IsPointInPolygon(point Point, polygon []Point) bool {
 	if len(polygon) < 3 {
@@ -83,8 +147,22 @@ func (GeometryUtils) SimplifyPolyLine(points []Point, tolerance float64) []Point
 
 // douglasPeucker Douglas-Peucker算法实现
 func douglasPeucker(points []Point, tolerance float64) []Point {
+	indices := douglasPeuckerIndices(points, tolerance)
+	result := make([]Point, len(indices))
+	for i, k := range indices {
+		result[i] = points[k]
+	}
+	return result
+}
+
+// douglasPeuckerIndices 返回 Douglas-Peucker 算法保留下来的点的下标（升序）
+func douglasPeuckerIndices(points []Point, tolerance float64) []int {
 	if len(points) <= 2 {
-		return points
+		indices := make([]int, len(points))
+		for i := range indices {
+			indices[i] = i
+		}
+		return indices
 	}
 
 	// 找到距离起点和终点连线最远的点
@@ -102,23 +180,45 @@ func douglasPeucker(points []Point, tolerance float64) []Point {
 		}
 	}
 
-	// 如果最大距离小于容差，返回起点和终点
-	if maxDistance < tolerance {
-		return []Point{start, end}
+	// 如果最大距离小于容差（或所有中间点都在连线上），返回起点和终点
+	if maxDistance < tolerance || maxIndex == 0 {
+		return []int{0, len(points) - 1}
 	}
 
 	// 递归处理两段
-	left := douglasPeucker(points[:maxIndex+1], tolerance)
-	right := douglasPeucker(points[maxIndex:], tolerance)
+	left := douglasPeuckerIndices(points[:maxIndex+1], tolerance)
+	right := douglasPeuckerIndices(points[maxIndex:], tolerance)
 
 	// 合并结果，去除重复点
-	result := make([]Point, 0, len(left)+len(right)-1)
+	result := make([]int, 0, len(left)+len(right)-1)
 	result = append(result, left...)
-	result = append(result, right[1:]...)
+	for _, k := range right[1:] {
+		result = appendDistinctIndex(result, points, k+maxIndex)
+	}
 
 	return result
 }
 
+// simplifyEpsilon 简化时视为同一坐标的最大差值
+const simplifyEpsilon = 1e-12
+
+// appendDistinctIndex 将下标 k 追加到 indices，若 points[k] 与上一个保留点几乎重合则不追加，
+// 避免产生零长度的线段。末点总会保留（保证环闭合），此时改为替换与其重合的上一个点，
+// 起点除外。
+func appendDistinctIndex(indices []int, points []Point, k int) []int {
+	last := len(indices) - 1
+	if !points[k].AlmostEquals(points[indices[last]], simplifyEpsilon) {
+		return append(indices, k)
+	}
+	if k == len(points)-1 {
+		if last == 0 {
+			return append(indices, k)
+		}
+		indices[last] = k
+	}
+	return indices
+}
+
 // pointToLineDistance 计算点到直线的距离
 func pointToLineDistance(point, lineStart, lineEnd Point) float64 {
 	// 如果线段长度为0，返回点到起点的距离
@@ -136,19 +236,438 @@ func pointToLineDistance(point, lineStart, lineEnd Point) float64 {
 	return math.Abs(A*point.X+B*point.Y+C) / math.Sqrt(A*A+B*B)
 }
 
+// Reverse 返回反转了每个部分顶点顺序的新形状（Z/M 值随顶点一起反转）。
+// 边界框与部分结构不变；点类型形状原样返回。
+func (GeometryUtils) Reverse(shape Shape) Shape {
+	switch s := shape.(type) {
+	case *PolyLine:
+		r := *s
+		r.Points = reversedParts(s.Parts, s.Points)
+		return &r
+	case *Polygon:
+		r := *s
+		r.Points = reversedParts(s.Parts, s.Points)
+		return &r
+	case *PolyLineZ:
+		r := *s
+		r.Points = reversedParts(s.Parts, s.Points)
+		r.ZArray = reversedValues(s.Parts, s.ZArray)
+		r.MArray = reversedValues(s.Parts, s.MArray)
+		return &r
+	case *PolygonZ:
+		r := *s
+		r.Points = reversedParts(s.Parts, s.Points)
+		r.ZArray = reversedValues(s.Parts, s.ZArray)
+		r.MArray = reversedValues(s.Parts, s.MArray)
+		return &r
+	case *PolyLineM:
+		r := *s
+		r.Points = reversedParts(s.Parts, s.Points)
+		r.MArray = reversedValues(s.Parts, s.MArray)
+		return &r
+	case *PolygonM:
+		r := *s
+		r.Points = reversedParts(s.Parts, s.Points)
+		r.MArray = reversedValues(s.Parts, s.MArray)
+		return &r
+	case *MultiPatch:
+		r := *s
+		r.Points = reversedParts(s.Parts, s.Points)
+		r.ZArray = reversedValues(s.Parts, s.ZArray)
+		r.MArray = reversedValues(s.Parts, s.MArray)
+		return &r
+	case *MultiPoint:
+		r := *s
+		r.Points = reversedParts([]int32{0}, s.Points)
+		return &r
+	case *MultiPointZ:
+		r := *s
+		r.Points = reversedParts([]int32{0}, s.Points)
+		r.ZArray = reversedValues([]int32{0}, s.ZArray)
+		r.MArray = reversedValues([]int32{0}, s.MArray)
+		return &r
+	case *MultiPointM:
+		r := *s
+		r.Points = reversedParts([]int32{0}, s.Points)
+		r.MArray = reversedValues([]int32{0}, s.MArray)
+		return &r
+	default:
+		return shape
+	}
+}
+
+// reversedParts 返回 points 的副本，其中每个部分内的点顺序被反转
+func reversedParts(parts []int32, points []Point) []Point {
+	r := make([]Point, len(points))
+	copy(r, points)
+	for _, part := range splitParts(parts, r) {
+		for i, j := 0, len(part)-1; i < j; i, j = i+1, j-1 {
+			part[i], part[j] = part[j], part[i]
+		}
+	}
+	return r
+}
+
+// reversedValues 与 reversedParts 相同，但作用于 Z/M 值数组
+func reversedValues(parts []int32, values []float64) []float64 {
+	if values == nil {
+		return nil
+	}
+	r := make([]float64, len(values))
+	copy(r, values)
+	for i, start := range parts {
+		end := len(r)
+		if i+1 < len(parts) && int(parts[i+1]) < end {
+			end = int(parts[i+1])
+		}
+		for a, b := int(start), end-1; a >= 0 && a < b; a, b = a+1, b-1 {
+			r[a], r[b] = r[b], r[a]
+		}
+	}
+	return r
+}
+
+// windingSampleSize DiagnoseWinding 最多检查的多边形要素数量
+const windingSampleSize = 1000
+
+// WindingReport 多边形外环方向的诊断结果
+type WindingReport struct {
+	// Features 检查过的多边形要素数量
+	Features int
+	// Clockwise 顺时针外环（符合 Shapefile 规范）的数量
+	Clockwise int
+	// CounterClockwise 逆时针外环（方向颠倒，常见于直接导入的 GeoJSON）的数量
+	CounterClockwise int
+	// InvertedFeatures 含有逆时针外环的要素索引
+	InvertedFeatures []int
+}
+
+// Inverted 判断外环是否以逆时针为主，即多边形很可能会被渲染为洞
+func (r WindingReport) Inverted() bool {
+	return r.CounterClockwise > r.Clockwise
+}
+
+// DiagnoseWinding 抽查 Shapefile 中前 1000 个多边形要素的外环方向。外环按包含关系
+// 判定（不被同一要素中其他环包含的环），而不是按方向判定，因此方向颠倒的数据也能被识别。
+// 非多边形文件返回空的报告。
+func (g GeometryUtils) DiagnoseWinding(filename string) (WindingReport, error) {
+	var report WindingReport
+	reader, err := Open(filename)
+	if err != nil {
+		return report, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	for report.Features < windingSampleSize && reader.Next() {
+		n, shape := reader.Shape()
+		rings, polygon := shapeParts(shape)
+		if !polygon || len(rings) == 0 {
+			continue
+		}
+		report.Features++
+
+		inverted := false
+		for i, ring := range rings {
+			if signedArea(ring) == 0 || ringContainedByOther(rings, i) {
+				continue
+			}
+			if isClockwise(ring) {
+				report.Clockwise++
+			} else {
+				report.CounterClockwise++
+				inverted = true
+			}
+		}
+		if inverted {
+			report.InvertedFeatures = append(report.InvertedFeatures, n)
+		}
+	}
+	return report, reader.Err()
+}
+
+// ringContainedByOther 判断 rings[i] 是否位于其他某个环内部
+func ringContainedByOther(rings [][]Point, i int) bool {
+	for j, other := range rings {
+		if j != i && ringContainsRing(other, rings[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExteriorRings 返回只包含外环的新多边形，丢弃所有内环（洞）。
+// 外环按 groupPolygonRings 的规则判定：顺时针的环为外环，首个环总视为外环。
+func (GeometryUtils) ExteriorRings(poly *Polygon) *Polygon {
+	var rings [][]Point
+	for _, group := range groupPolygonRings(splitParts(poly.Parts, poly.Points)) {
+		rings = append(rings, append([]Point(nil), group[0]...))
+	}
+	return NewPolygon(rings)
+}
+
+// RemoveSmallParts 返回去掉了面积（绝对值）小于 minArea 的环的新多边形，用于小比例尺制图综合时
+// 剔除细小的岛屿、碎片和小洞，可与 SimplifyPolyLine 配合使用。内环比包含它的外环小，
+// 因此被去掉的外环的内环也会被去掉。所有环都被去掉时返回没有部件的多边形，poly 为 nil 时返回 nil
+func (g GeometryUtils) RemoveSmallParts(poly *Polygon, minArea float64) *Polygon {
+	if poly == nil {
+		return nil
+	}
+	var rings [][]Point
+	for _, ring := range splitParts(poly.Parts, poly.Points) {
+		if g.Area(ring) >= minArea {
+			rings = append(rings, append([]Point(nil), ring...))
+		}
+	}
+	return NewPolygon(rings)
+}
+
+// ConvexHull 计算点集的凸包 (Andrew 单调链算法)，按逆时针顺序返回凸包顶点，
+// 首个顶点不重复出现在末尾，共线的点被省略
+func (GeometryUtils) ConvexHull(points []Point) []Point {
+	sorted := append([]Point(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].X != sorted[j].X {
+			return sorted[i].X < sorted[j].X
+		}
+		return sorted[i].Y < sorted[j].Y
+	})
+	// 去除重复点
+	unique := sorted[:0]
+	for i, p := range sorted {
+		if i == 0 || p != sorted[i-1] {
+			unique = append(unique, p)
+		}
+	}
+	if len(unique) < 3 {
+		return unique
+	}
+
+	cross := func(o, a, b Point) float64 {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+	hull := make([]Point, 0, 2*len(unique))
+	// 下凸包
+	for _, p := range unique {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// 上凸包
+	lower := len(hull) + 1
+	for i := len(unique) - 2; i >= 0; i-- {
+		p := unique[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+// MinimumBoundingRectangle 计算点集面积最小的外接矩形（可旋转），对凸包使用旋转卡壳法。
+// 返回按逆时针顺序排列的四个角点，以及第一个角点到第二个角点的边与 X 轴的夹角（弧度，
+// 取值范围 [0, π/2)），可用于分析建筑轮廓等要素的朝向。
+// 所有点共线时矩形退化为线段，夹角为线段的方向，取值范围 [0, π)；没有点时返回零值
+func (g GeometryUtils) MinimumBoundingRectangle(points []Point) ([4]Point, float64) {
+	var corners [4]Point
+	hull := g.ConvexHull(points)
+	n := len(hull)
+	switch n {
+	case 0:
+		return corners, 0
+	case 1:
+		return [4]Point{hull[0], hull[0], hull[0], hull[0]}, 0
+	case 2:
+		// 凸包的点按 X 排序，线段方向落在 (-π/2, π/2]
+		a, b := hull[0], hull[1]
+		angle := math.Atan2(b.Y-a.Y, b.X-a.X)
+		if angle < 0 {
+			a, b = b, a
+			angle += math.Pi
+		}
+		return [4]Point{a, b, b, a}, angle
+	}
+
+	dot := func(a, b Point) float64 { return a.X*b.X + a.Y*b.Y }
+	sub := func(a, b Point) Point { return Point{a.X - b.X, a.Y - b.Y} }
+	next := func(i int) int { return (i + 1) % n }
+
+	// j、k、l 分别为沿边方向最远、离边最远和沿边反方向最远的顶点，随边的旋转单调前进
+	minArea := math.Inf(1)
+	var j, k, l int
+	for i := 0; i < n; i++ {
+		edge := sub(hull[next(i)], hull[i])
+		length := math.Hypot(edge.X, edge.Y)
+		u := Point{edge.X / length, edge.Y / length}
+		v := Point{-u.Y, u.X} // 凸包为逆时针，内侧在左边
+
+		if i == 0 {
+			j = next(i)
+		}
+		for dot(sub(hull[next(j)], hull[j]), u) > 0 {
+			j = next(j)
+		}
+		if i == 0 {
+			k = j
+		}
+		for dot(sub(hull[next(k)], hull[k]), v) > 0 {
+			k = next(k)
+		}
+		if i == 0 {
+			l = k
+		}
+		for dot(sub(hull[next(l)], hull[l]), u) < 0 {
+			l = next(l)
+		}
+
+		maxU := dot(sub(hull[j], hull[i]), u)
+		minU := dot(sub(hull[l], hull[i]), u)
+		maxV := dot(sub(hull[k], hull[i]), v)
+		if area := (maxU - minU) * maxV; area < minArea {
+			minArea = area
+			at := func(a, b float64) Point {
+				return Point{hull[i].X + u.X*a + v.X*b, hull[i].Y + u.Y*a + v.Y*b}
+			}
+			corners = [4]Point{at(minU, 0), at(maxU, 0), at(maxU, maxV), at(minU, maxV)}
+		}
+	}
+	return normalizeRectangle(corners)
+}
+
+// normalizeRectangle 轮换逆时针排列的矩形角点，使第一条边与 X 轴的夹角落在 [0, π/2)，并返回该夹角
+func normalizeRectangle(corners [4]Point) ([4]Point, float64) {
+	angle := math.Atan2(corners[1].Y-corners[0].Y, corners[1].X-corners[0].X)
+	// 逆时针的下一条边的夹角比上一条大 π/2
+	steps := int(math.Floor(angle / (math.Pi / 2)))
+	shift := ((-steps)%4 + 4) % 4
+	var rotated [4]Point
+	for i := range corners {
+		rotated[i] = corners[(i+shift)%4]
+	}
+	return rotated, angle - float64(steps)*math.Pi/2
+}
+
+// SharedBoundaryLength 计算两个多边形公共边界的长度。b 的边若两个端点到 a 某条边所在直线的
+// 距离都不超过 tolerance，则视为与该边重合，累加两者在该边方向上重叠部分的长度。
+// 用于根据行政区划等面数据构建邻接关系；复杂度为两者边数之积。
+func (g GeometryUtils) SharedBoundaryLength(a, b *Polygon, tolerance float64) float64 {
+	if a == nil || b == nil {
+		return 0
+	}
+	ringsB := splitParts(b.Parts, b.Points)
+	total := 0.0
+	for _, ringA := range splitParts(a.Parts, a.Points) {
+		for i := 0; i+1 < len(ringA); i++ {
+			p1, p2 := ringA[i], ringA[i+1]
+			length := g.Distance(p1, p2)
+			if length == 0 {
+				continue
+			}
+			// 边 p1-p2 的单位方向向量
+			dx, dy := (p2.X-p1.X)/length, (p2.Y-p1.Y)/length
+			for _, ringB := range ringsB {
+				for j := 0; j+1 < len(ringB); j++ {
+					q1, q2 := ringB[j], ringB[j+1]
+					if pointToLineDistance(q1, p1, p2) > tolerance || pointToLineDistance(q2, p1, p2) > tolerance {
+						continue
+					}
+					// q1、q2 在 p1-p2 方向上的投影位置
+					t1 := (q1.X-p1.X)*dx + (q1.Y-p1.Y)*dy
+					t2 := (q2.X-p1.X)*dx + (q2.Y-p1.Y)*dy
+					if t1 > t2 {
+						t1, t2 = t2, t1
+					}
+					if overlap := math.Min(t2, length) - math.Max(t1, 0); overlap > 0 {
+						total += overlap
+					}
+				}
+			}
+		}
+	}
+	return total
+}
+
 // StatisticsUtils 统计工具函数集合
 type StatisticsUtils struct{}
 
 // ShapefileStats Shapefile统计信息
 type ShapefileStats struct {
-	TotalShapes    int
-	ShapeTypes     map[ShapeType]int
-	BoundingBox    Box
-	AverageArea    float64
-	TotalArea      float64
-	LargestShape   int
-	SmallestShape  int
-	AttributeStats map[string]AttributeStats
+	TotalShapes int
+	// ShapeTypes 按记录自身的几何类型计数，Null 记录计入 ShapeTypes[NULL]
+	ShapeTypes  map[ShapeType]int
+	BoundingBox Box
+	// AverageArea 每个要素的平均面积，按非 Null 要素数计算
+	AverageArea float64
+	TotalArea   float64
+	// TotalPerimeter 所有多边形（含 PolygonZ、PolygonM）外环与内环的边界长度之和
+	TotalPerimeter float64
+	// AveragePerimeter 每个要素的平均周长，与 AverageArea 一样按非 Null 要素数计算
+	AveragePerimeter float64
+	LargestShape     int
+	SmallestShape    int
+	AttributeStats   map[string]AttributeStats
+	// VertexHistogram 按顶点数统计的要素分布
+	VertexHistogram VertexHistogram
+}
+
+// VertexHistogram 按每个要素的顶点总数统计要素数量，用于发现拖慢处理的超大要素
+type VertexHistogram struct {
+	UpTo10   int // 0–10 个顶点
+	UpTo100  int // 11–100 个顶点
+	UpTo1000 int // 101–1000 个顶点
+	Over1000 int // 1000 个以上顶点
+	// MaxVertices 单个要素的最大顶点数，MaxVerticesShape 为该要素的索引（没有要素时为 -1）
+	MaxVertices      int
+	MaxVerticesShape int
+}
+
+// add 记录索引为 index、顶点数为 n 的要素
+func (h *VertexHistogram) add(index, n int) {
+	switch {
+	case n <= 10:
+		h.UpTo10++
+	case n <= 100:
+		h.UpTo100++
+	case n <= 1000:
+		h.UpTo1000++
+	default:
+		h.Over1000++
+	}
+	if h.MaxVerticesShape < 0 || n > h.MaxVertices {
+		h.MaxVertices = n
+		h.MaxVerticesShape = index
+	}
+}
+
+// shapeVertexCount 返回形状的顶点总数，单点为 1，Null 为 0
+func shapeVertexCount(shape Shape) int {
+	switch s := shape.(type) {
+	case *Point, *PointZ, *PointM:
+		return 1
+	case *PolyLine:
+		return len(s.Points)
+	case *Polygon:
+		return len(s.Points)
+	case *MultiPoint:
+		return len(s.Points)
+	case *PolyLineZ:
+		return len(s.Points)
+	case *PolygonZ:
+		return len(s.Points)
+	case *MultiPointZ:
+		return len(s.Points)
+	case *PolyLineM:
+		return len(s.Points)
+	case *PolygonM:
+		return len(s.Points)
+	case *MultiPointM:
+		return len(s.Points)
+	case *MultiPatch:
+		return len(s.Points)
+	default:
+		return 0
+	}
 }
 
 // AttributeStats 属性统计信息
@@ -158,12 +677,20 @@ type AttributeStats struct {
 	NullValues   int
 	MinLength    int
 	MaxLength    int
-	Values       []string            // 用于唯一值统计
-	valueSet     map[string]struct{} // 内部使用的 set，加快查找速度
+	Values       []string            // 收集到的唯一值样本（最多 MaxUniqueValues 个）
+	valueSet     map[string]struct{} // 查重用的 set，统计结束后释放
+	// ValuesTruncated 表示唯一值数量超过了 MaxUniqueValues，
+	// 此时 Values 只包含前 MaxUniqueValues 个唯一值，UniqueValues 仍是精确的数量
+	ValuesTruncated bool
 }
 
 // AnalyzeShapefile 分析Shapefile并返回统计信息
-func (StatisticsUtils) AnalyzeShapefile(filename string) (*ShapefileStats, error) {
+func (StatisticsUtils) AnalyzeShapefile(filename string, opts ...AnalyzeOption) (*ShapefileStats, error) {
+	options := DefaultAnalyzeOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	reader, err := Open(filename)
 	if err != nil {
 		return nil, err
@@ -171,9 +698,10 @@ func (StatisticsUtils) AnalyzeShapefile(filename string) (*ShapefileStats, error
 	defer func() { _ = reader.Close() }()
 
 	stats := &ShapefileStats{
-		ShapeTypes:     make(map[ShapeType]int),
-		AttributeStats: make(map[string]AttributeStats),
-		BoundingBox:    reader.BBox(),
+		ShapeTypes:      make(map[ShapeType]int),
+		AttributeStats:  make(map[string]AttributeStats),
+		BoundingBox:     reader.BBox(),
+		VertexHistogram: VertexHistogram{MaxVerticesShape: -1},
 	}
 
 	s := statisticsCollector{
@@ -183,6 +711,7 @@ func (StatisticsUtils) AnalyzeShapefile(filename string) (*ShapefileStats, error
 		smallestArea:  math.Inf(1),
 		largestIndex:  -1,
 		smallestIndex: -1,
+		maxValues:     options.MaxUniqueValues,
 	}
 
 	return s.collectStatistics()
@@ -190,29 +719,28 @@ func (StatisticsUtils) AnalyzeShapefile(filename string) (*ShapefileStats, error
 
 // statisticsCollector helps collect shapefile statistics
 type statisticsCollector struct {
-	reader        *Reader
-	stats         *ShapefileStats
-	utils         GeometryUtils
-	totalArea     float64
-	largestArea   float64
-	smallestArea  float64
-	largestIndex  int
-	smallestIndex int
+	reader         *Reader
+	stats          *ShapefileStats
+	utils          GeometryUtils
+	totalArea      float64
+	totalPerimeter float64
+	largestArea    float64
+	smallestArea   float64
+	largestIndex   int
+	smallestIndex  int
+	maxValues      int
 }
 
 // collectStatistics collects all statistics for the shapefile
 func (s *statisticsCollector) collectStatistics() (*ShapefileStats, error) {
 	s.initializeAttributeStats()
 
-	index := 0
 	for s.reader.Next() {
-		_, shape := s.reader.Shape()
+		index, shape := s.reader.Shape()
 		s.stats.TotalShapes++
 
 		s.analyzeShape(shape, index)
 		s.analyzeAttributes(index)
-
-		index++
 	}
 
 	s.finalizeStatistics()
@@ -235,16 +763,15 @@ func (s *statisticsCollector) initializeAttributeStats() {
 
 // analyzeShape analyzes a single shape and updates statistics
 func (s *statisticsCollector) analyzeShape(shape Shape, index int) {
-	switch sh := shape.(type) {
-	case *Point:
-		s.stats.ShapeTypes[POINT]++
-	case *PolyLine:
-		s.stats.ShapeTypes[POLYLINE]++
-	case *Polygon:
-		s.stats.ShapeTypes[POLYGON]++
-		s.analyzePolygonArea(sh, index)
-	case *MultiPoint:
-		s.stats.ShapeTypes[MULTIPOINT]++
+	s.stats.VertexHistogram.add(index, shapeVertexCount(shape))
+	if _, polygon := shapeParts(shape); polygon {
+		s.totalPerimeter += ShapeLength(shape)
+	}
+
+	// 规范允许 Null 记录与其他类型的记录混合，每条记录按自身类型计数
+	s.stats.ShapeTypes[shapeTypeOf(shape)]++
+	if polygon, ok := shape.(*Polygon); ok {
+		s.analyzePolygonArea(polygon, index)
 	}
 }
 
@@ -299,33 +826,38 @@ func (s *statisticsCollector) updateLengthStats(fieldStats *AttributeStats, attr
 
 // updateUniqueValues updates unique values for a field
 func (s *statisticsCollector) updateUniqueValues(fieldStats *AttributeStats, attr string) {
-	// 收集唯一值（限制数量避免内存过多使用）
-	if len(fieldStats.Values) >= 1000 {
-		return
-	}
-
 	// 使用 map 快速检查是否已存在
 	if fieldStats.valueSet == nil {
 		fieldStats.valueSet = make(map[string]struct{})
 	}
+	if _, exists := fieldStats.valueSet[attr]; exists {
+		return
+	}
+	fieldStats.valueSet[attr] = struct{}{}
 
-	if _, exists := fieldStats.valueSet[attr]; !exists {
-		fieldStats.valueSet[attr] = struct{}{}
-		fieldStats.Values = append(fieldStats.Values, attr)
+	// 只限制 Values 样本的数量，唯一值计数不受限制
+	if s.maxValues > 0 && len(fieldStats.Values) >= s.maxValues {
+		fieldStats.ValuesTruncated = true
+		return
 	}
+	fieldStats.Values = append(fieldStats.Values, attr)
 }
 
 // finalizeStatistics calculates final statistics
 func (s *statisticsCollector) finalizeStatistics() {
 	// 计算唯一值数量
 	for fieldName, fieldStats := range s.stats.AttributeStats {
-		fieldStats.UniqueValues = len(fieldStats.Values)
+		fieldStats.UniqueValues = len(fieldStats.valueSet)
+		fieldStats.valueSet = nil
 		s.stats.AttributeStats[fieldName] = fieldStats
 	}
 
 	s.stats.TotalArea = s.totalArea
-	if s.stats.TotalShapes > 0 {
-		s.stats.AverageArea = s.totalArea / float64(s.stats.TotalShapes)
+	s.stats.TotalPerimeter = s.totalPerimeter
+	// Null 记录没有几何，不参与平均值
+	if shapes := s.stats.TotalShapes - s.stats.ShapeTypes[NULL]; shapes > 0 {
+		s.stats.AverageArea = s.totalArea / float64(shapes)
+		s.stats.AveragePerimeter = s.totalPerimeter / float64(shapes)
 	}
 	s.stats.LargestShape = s.largestIndex
 	s.stats.SmallestShape = s.smallestIndex
@@ -345,10 +877,22 @@ func (s *ShapefileStats) String() string {
 		sb.WriteString(fmt.Sprintf("    %s: %d\n", shapeType.String(), count))
 	}
 
+	h := s.VertexHistogram
+	sb.WriteString("  Vertices per Shape:\n")
+	sb.WriteString(fmt.Sprintf("    0-10: %d, 11-100: %d, 101-1000: %d, >1000: %d\n",
+		h.UpTo10, h.UpTo100, h.UpTo1000, h.Over1000))
+	if h.MaxVerticesShape >= 0 {
+		sb.WriteString(fmt.Sprintf("    Max: %d (shape %d)\n", h.MaxVertices, h.MaxVerticesShape))
+	}
+
 	if s.TotalArea > 0 {
 		sb.WriteString(fmt.Sprintf("  Total Area: %.6f\n", s.TotalArea))
 		sb.WriteString(fmt.Sprintf("  Average Area: %.6f\n", s.AverageArea))
 	}
+	if s.TotalPerimeter > 0 {
+		sb.WriteString(fmt.Sprintf("  Total Perimeter: %.6f\n", s.TotalPerimeter))
+		sb.WriteString(fmt.Sprintf("  Average Perimeter: %.6f\n", s.AveragePerimeter))
+	}
 
 	sb.WriteString("  Attribute Fields:\n")
 	fieldNames := make([]string, 0, len(s.AttributeStats))
@@ -398,13 +942,33 @@ func (FormatUtils) ToWKT(shape Shape) string {
 		coords := formatPointsAsWKT(s.Points)
 		return fmt.Sprintf("LINESTRING (%s)", coords)
 	case *Polygon:
-		coords := formatPointsAsWKT(s.Points)
-		return fmt.Sprintf("POLYGON ((%s))", coords)
+		return formatPolygonAsWKT(splitParts(s.Parts, s.Points))
 	default:
 		return "GEOMETRYCOLLECTION EMPTY"
 	}
 }
 
+// formatPolygonAsWKT 将多边形的环格式化为 WKT。每个环单独用括号包裹，
+// 多个外环时输出 MULTIPOLYGON。
+func formatPolygonAsWKT(rings [][]Point) string {
+	groups := groupPolygonRings(rings)
+	if len(groups) == 0 {
+		return "POLYGON EMPTY"
+	}
+	polygons := make([]string, len(groups))
+	for i, group := range groups {
+		formatted := make([]string, len(group))
+		for j, ring := range group {
+			formatted[j] = "(" + formatPointsAsWKT(ring) + ")"
+		}
+		polygons[i] = "(" + strings.Join(formatted, ", ") + ")"
+	}
+	if len(polygons) == 1 {
+		return "POLYGON " + polygons[0]
+	}
+	return "MULTIPOLYGON (" + strings.Join(polygons, ", ") + ")"
+}
+
 // formatPointsAsJSON 格式化点数组为JSON坐标格式
 func formatPointsAsJSON(points []Point) string {
 	coords := make([]string, len(points))
@@ -0,0 +1,650 @@
+package shp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToWKTPolygonWithHole(t *testing.T) {
+	outer := []Point{{0, 0}, {0, 4}, {4, 4}, {4, 0}, {0, 0}}
+	hole := []Point{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}}
+	island := []Point{{10, 10}, {10, 11}, {11, 11}, {10, 10}}
+
+	got := FormatUtils{}.ToWKT(NewPolygon([][]Point{outer, hole}))
+	want := "POLYGON ((0.000000 0.000000, 0.000000 4.000000, 4.000000 4.000000, 4.000000 0.000000, 0.000000 0.000000), " +
+		"(1.000000 1.000000, 2.000000 1.000000, 2.000000 2.000000, 1.000000 2.000000, 1.000000 1.000000))"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	got = FormatUtils{}.ToWKT(NewPolygon([][]Point{outer, hole, island}))
+	want = "MULTIPOLYGON (((0.000000 0.000000, 0.000000 4.000000, 4.000000 4.000000, 4.000000 0.000000, 0.000000 0.000000), " +
+		"(1.000000 1.000000, 2.000000 1.000000, 2.000000 2.000000, 1.000000 2.000000, 1.000000 1.000000)), " +
+		"((10.000000 10.000000, 10.000000 11.000000, 11.000000 11.000000, 10.000000 10.000000)))"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	l := &PolyLineZ{
+		NumParts:  2,
+		NumPoints: 5,
+		Parts:     []int32{0, 2},
+		Points:    []Point{{0, 0}, {1, 1}, {2, 2}, {3, 3}, {4, 4}},
+		ZArray:    []float64{0, 1, 2, 3, 4},
+		MArray:    []float64{10, 11, 12, 13, 14},
+	}
+	r := GeometryUtils{}.Reverse(l).(*PolyLineZ)
+
+	wantPoints := []Point{{1, 1}, {0, 0}, {4, 4}, {3, 3}, {2, 2}}
+	for i := range wantPoints {
+		if r.Points[i] != wantPoints[i] {
+			t.Fatalf("got points %v, want %v", r.Points, wantPoints)
+		}
+	}
+	if r.ZArray[0] != 1 || r.ZArray[2] != 4 || r.MArray[4] != 12 {
+		t.Errorf("Z/M not reversed with points: Z=%v M=%v", r.ZArray, r.MArray)
+	}
+	if l.Points[0] != (Point{0, 0}) {
+		t.Error("Reverse modified its input")
+	}
+}
+
+func TestMakeValid(t *testing.T) {
+	geom := GeometryUtils{}
+	rings := func(p *Polygon) [][]Point { return splitParts(p.Parts, p.Points) }
+
+	// bowtie: the ring crosses itself at (0.5, 0.5)
+	bowtie := NewPolygon([][]Point{{{0, 0}, {1, 1}, {1, 0}, {0, 1}, {0, 0}}})
+	got, err := geom.MakeValid(bowtie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := rings(got); len(r) != 2 {
+		t.Fatalf("bowtie: got %d rings, want 2: %v", len(r), r)
+	}
+	for _, r := range rings(got) {
+		if !isClockwise(r) || r[0] != r[len(r)-1] || geom.Area(r) != 0.25 {
+			t.Errorf("bowtie: invalid ring %v", r)
+		}
+	}
+
+	// counter-clockwise exterior with a spike and a clockwise hole
+	poly := NewPolygon([][]Point{
+		{{0, 0}, {4, 0}, {4, 2}, {6, 2}, {4, 2}, {4, 4}, {0, 4}, {0, 0}},
+		{{1, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}},
+	})
+	got, err = geom.MakeValid(poly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]Point{
+		{{0, 0}, {0, 4}, {4, 4}, {4, 2}, {4, 0}, {0, 0}},
+		{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}},
+	}
+	if r := rings(got); !reflect.DeepEqual(r, want) {
+		t.Errorf("got rings %v, want %v", r, want)
+	}
+	if got.Box != (Box{0, 0, 4, 4}) {
+		t.Errorf("got bbox %v", got.Box)
+	}
+
+	if _, err := geom.MakeValid(NewPolygon([][]Point{{{0, 0}, {1, 1}, {2, 2}, {0, 0}}})); err == nil {
+		t.Error("degenerate polygon accepted")
+	}
+}
+
+func TestClassifyRings(t *testing.T) {
+	geom := GeometryUtils{}
+	// two exteriors, a hole in the second one written before it with the
+	// wrong orientation, and an island inside a hole of the first one
+	poly := NewPolygon([][]Point{
+		{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}},
+		{{2, 2}, {8, 2}, {8, 8}, {2, 8}, {2, 2}},
+		{{21, 1}, {21, 2}, {22, 2}, {22, 1}, {21, 1}},
+		{{20, 0}, {20, 5}, {25, 5}, {25, 0}, {20, 0}},
+		{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}},
+	})
+	got, err := geom.ClassifyRings(poly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RingInfo{
+		{Part: 0, Outer: true, Parent: -1, Clockwise: true, Area: 100},
+		{Part: 1, Outer: false, Parent: 0, Clockwise: false, Area: 36},
+		{Part: 2, Outer: false, Parent: 3, Clockwise: true, Area: 1},
+		{Part: 3, Outer: true, Parent: -1, Clockwise: true, Area: 25},
+		{Part: 4, Outer: true, Parent: -1, Clockwise: true, Area: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	poly.Parts[1] = 100
+	if _, err := geom.ClassifyRings(poly); err == nil {
+		t.Error("invalid parts accepted")
+	}
+	if _, err := geom.ClassifyRings(nil); err == nil {
+		t.Error("nil polygon accepted")
+	}
+}
+
+func TestIntersectionArea(t *testing.T) {
+	geom := GeometryUtils{}
+	square := func(x0, y0, x1, y1 float64) []Point {
+		return []Point{{x0, y0}, {x0, y1}, {x1, y1}, {x1, y0}, {x0, y0}}
+	}
+	withHole := NewPolygon([][]Point{square(0, 0, 4, 4), square(1, 1, 2, 2)})
+	// L-shape covering [0,3]x[0,1] and [0,1]x[0,3]
+	lShape := NewPolygon([][]Point{{{0, 0}, {0, 3}, {1, 3}, {1, 1}, {3, 1}, {3, 0}, {0, 0}}})
+	// two exteriors, the second one counter-clockwise
+	reversed := square(5, 0, 6, 1)
+	for a, b := 0, len(reversed)-1; a < b; a, b = a+1, b-1 {
+		reversed[a], reversed[b] = reversed[b], reversed[a]
+	}
+	twoParts := NewPolygon([][]Point{square(0, 0, 1, 1), reversed})
+
+	tests := []struct {
+		name string
+		a, b *Polygon
+		want float64
+	}{
+		{"overlapping squares", NewPolygon([][]Point{square(0, 0, 2, 2)}), NewPolygon([][]Point{square(1, 1, 3, 3)}), 1},
+		{"disjoint", NewPolygon([][]Point{square(0, 0, 1, 1)}), NewPolygon([][]Point{square(2, 2, 3, 3)}), 0},
+		{"identical", lShape, lShape, 5},
+		{"hole", withHole, NewPolygon([][]Point{square(0, 0, 2, 2)}), 3},
+		{"concave", lShape, NewPolygon([][]Point{square(0.5, 0.5, 2.5, 2.5)}), 1.75},
+		{"several exteriors", twoParts, NewPolygon([][]Point{square(0.5, 0, 5.5, 1)}), 1},
+		{"contained", withHole, NewPolygon([][]Point{square(2.5, 2.5, 3, 3)}), 0.25},
+	}
+	for _, tt := range tests {
+		got, err := geom.IntersectionArea(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		if reverse, _ := geom.IntersectionArea(tt.b, tt.a); math.Abs(reverse-got) > 1e-9 {
+			t.Errorf("%s: not symmetric: %v and %v", tt.name, got, reverse)
+		}
+	}
+
+	if _, err := geom.IntersectionArea(nil, lShape); err == nil {
+		t.Error("nil polygon accepted")
+	}
+}
+
+func TestPointDensityGrid(t *testing.T) {
+	geom := GeometryUtils{}
+	bbox := Box{MinX: 0, MinY: 0, MaxX: 4, MaxY: 2}
+	points := []Point{
+		{0, 0}, {0.5, 0.5}, // first cell
+		{1, 0}, // on a cell boundary: the next column
+		{4, 2}, // on the max corner: the last cell
+		{3.5, 1.5},
+		{5, 1},                   // outside
+		{math.NaN(), math.NaN()}, // null record
+	}
+	got := geom.PointDensityGrid(points, bbox, 4, 2)
+	want := [][]int{{2, 1, 0, 0}, {0, 0, 0, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := geom.PointDensityGrid(points, bbox, 0, 2); got != nil {
+		t.Errorf("got %v for zero columns", got)
+	}
+
+	filename := t.TempDir() + "/points.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range points[:5] {
+		p := p
+		w.Write(&p)
+	}
+	w.Write(&Null{})
+	w.Close()
+	// the zero box stands for the extent of the file
+	got, err = geom.PointDensityGridFromFile(filename, Box{}, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int{{3, 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("from file: got %v, want %v", got, want)
+	}
+}
+
+func TestDistanceMatrix(t *testing.T) {
+	geom := GeometryUtils{}
+	m, err := geom.DistanceMatrix([]Point{{0, 0}, {3, 4}, {0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]float64{{0, 5, 1}, {5, 0, math.Sqrt(18)}, {1, math.Sqrt(18), 0}}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+
+	// one degree of longitude at the equator
+	m, err = geom.DistanceMatrix([]Point{{0, 0}, {1, 0}}, WithHaversine(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m[0][1]-111195) > 1 {
+		t.Errorf("got haversine distance %v, want about 111195", m[0][1])
+	}
+
+	if _, err := geom.DistanceMatrix(make([]Point, 3), WithMaxPoints(2)); !errors.Is(err, NewShapeError(ErrInvalidFormat, "", nil)) {
+		t.Errorf("expected an error above the limit, got %v", err)
+	}
+
+	filename := t.TempDir() + "/points.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{0, 0})
+	w.Write(&Point{0, 2})
+	w.Close()
+	m, err = geom.DistanceMatrixFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float64{{0, 2}, {2, 0}}; !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+	m, _ = geom.DistanceMatrix([]Point{{0, 0}, {math.NaN(), math.NaN()}})
+	if !math.IsNaN(m[0][1]) || !math.IsNaN(m[1][1]) || m[0][0] != 0 {
+		t.Errorf("got %v for a missing point", m)
+	}
+}
+
+func TestLineIntersections(t *testing.T) {
+	geom := GeometryUtils{}
+	// a zigzag crossing a horizontal line twice, touching it at an end point
+	// and running along it from (6,0) to (7,0)
+	a := NewPolyLine([][]Point{{{0, -1}, {1, 1}, {2, -1}, {3, 0}}, {{5, 1}, {6, 0}, {7, 0}}})
+	b := NewPolyLine([][]Point{{{-1, 0}, {10, 0}}})
+	got, err := geom.LineIntersections(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Point{{0.5, 0}, {1.5, 0}, {3, 0}, {6, 0}, {7, 0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	c := NewPolyLine([][]Point{{{0, 5}, {1, 5}}})
+	if got, err := geom.LineIntersections(a, c); err != nil || len(got) != 0 {
+		t.Errorf("got %v, %v for disjoint lines", got, err)
+	}
+	// collinear but not overlapping
+	d := NewPolyLine([][]Point{{{11, 0}, {12, 0}}})
+	if got, _ := geom.LineIntersections(b, d); len(got) != 0 {
+		t.Errorf("got %v for separate collinear segments", got)
+	}
+
+	polygon := NewPolygon([][]Point{{{0, 0}, {0, 1}, {1, 1}, {0, 0}}})
+	if _, err := geom.LineIntersections(a, polygon); !errors.Is(err, NewShapeError(ErrUnsupportedType, "", nil)) {
+		t.Errorf("expected unsupported type error, got %v", err)
+	}
+}
+
+func TestSharedBoundaryLength(t *testing.T) {
+	geom := GeometryUtils{}
+	a := NewPolygon([][]Point{{{0, 0}, {0, 2}, {2, 2}, {2, 0}, {0, 0}}})
+	// b shares the segment (2,1)-(2,2) with a, with slightly noisy coordinates
+	b := NewPolygon([][]Point{{{2.0001, 1}, {2, 3}, {4, 3}, {4, 1}, {2.0001, 1}}})
+	c := NewPolygon([][]Point{{{5, 5}, {5, 6}, {6, 6}, {5, 5}}})
+
+	if got := geom.SharedBoundaryLength(a, b, 0.001); math.Abs(got-1) > 1e-9 {
+		t.Errorf("got shared length %v, want 1", got)
+	}
+	if got := geom.SharedBoundaryLength(a, b, 0); got != 0 {
+		t.Errorf("got shared length %v without tolerance, want 0", got)
+	}
+	if got := geom.SharedBoundaryLength(a, c, 0.001); got != 0 {
+		t.Errorf("got shared length %v for disjoint polygons, want 0", got)
+	}
+}
+
+func TestAnalyzeShapefileMaxUniqueValues(t *testing.T) {
+	filename := t.TempDir() + "/values.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{StringField("NAME", 5)})
+	for i, name := range []string{"a", "b", "a", "c", "d", "e"} {
+		w.Write(&Point{float64(i), 0})
+		_ = w.WriteAttribute(i, 0, name)
+	}
+	w.Close()
+
+	// the unique count is exact, only the sample of values is bounded
+	tests := []struct {
+		opts          []AnalyzeOption
+		wantValues    int
+		wantTruncated bool
+	}{
+		{nil, 5, false},
+		{[]AnalyzeOption{WithMaxUniqueValues(3)}, 3, true},
+		{[]AnalyzeOption{WithMaxUniqueValues(5)}, 5, false},
+		{[]AnalyzeOption{WithMaxUniqueValues(0)}, 5, false},
+	}
+	for _, test := range tests {
+		stats, err := StatisticsUtils{}.AnalyzeShapefile(filename, test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := stats.AttributeStats["NAME"]
+		if got.UniqueValues != 5 || len(got.Values) != test.wantValues || got.ValuesTruncated != test.wantTruncated {
+			t.Errorf("got %d unique values, %d listed (truncated: %v), want 5, %d listed (truncated: %v)",
+				got.UniqueValues, len(got.Values), got.ValuesTruncated, test.wantValues, test.wantTruncated)
+		}
+		if !strings.Contains(stats.String(), "Unique Values: 5\n") {
+			t.Errorf("summary does not report 5 unique values:\n%s", stats)
+		}
+	}
+}
+
+func TestAnalyzeShapefileStreaming(t *testing.T) {
+	filename := t.TempDir() + "/streaming.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{StringField("NAME", 8), NumberField("POP", 8)})
+	const n = 5000
+	for i := 0; i < n; i++ {
+		w.Write(&Point{float64(i), 0})
+		if i%10 != 0 {
+			_ = w.WriteAttribute(i, 0, fmt.Sprintf("n%d", i%2000))
+		}
+		_ = w.WriteAttribute(i, 1, i)
+	}
+	w.Close()
+
+	stats, err := StatisticsUtils{}.AnalyzeShapefileStreaming(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalShapes != n || stats.ShapeTypes[POINT] != n || len(stats.Fields) != 2 {
+		t.Fatalf("got %+v", stats)
+	}
+	name, pop := stats.Fields[0], stats.Fields[1]
+	if name.Name != "NAME" || name.Count != 4500 || name.NullValues != 500 || name.MinLength != 2 || name.MaxLength != 5 {
+		t.Errorf("got NAME stats %+v", name)
+	}
+	if name.NumericValues != 0 {
+		t.Errorf("character field has %d numeric values", name.NumericValues)
+	}
+	// 1800 distinct names, the estimate is within a few percent
+	if math.Abs(float64(name.EstimatedUnique)-1800) > 1800*0.1 {
+		t.Errorf("estimated %d unique names, want about 1800", name.EstimatedUnique)
+	}
+	if pop.NumericValues != n || pop.Min != 0 || pop.Max != n-1 || math.Abs(pop.Mean-(n-1)/2.0) > 1e-6 {
+		t.Errorf("got POP stats %+v", pop)
+	}
+	if math.Abs(float64(pop.EstimatedUnique)-n) > n*0.1 {
+		t.Errorf("estimated %d unique populations, want about %d", pop.EstimatedUnique, n)
+	}
+}
+
+func TestSimplifyPolyLineNearDuplicates(t *testing.T) {
+	geom := GeometryUtils{}
+	tests := []struct {
+		points []Point
+		want   []Point
+	}{
+		{
+			[]Point{{0, 0}, {5, 5}, {5, 5 + 1e-14}, {10, 0}},
+			[]Point{{0, 0}, {5, 5 + 1e-14}, {10, 0}},
+		},
+		{
+			// the end point is kept, the near-duplicate before it is dropped
+			[]Point{{0, 0}, {5, 5}, {10, 1e-14}, {10, 0}},
+			[]Point{{0, 0}, {5, 5}, {10, 0}},
+		},
+		{
+			[]Point{{0, 0}, {0, 1}, {1, 1}, {1, 1}, {0, 0}},
+			[]Point{{0, 0}, {0, 1}, {1, 1}, {0, 0}},
+		},
+	}
+	for _, test := range tests {
+		if got := geom.SimplifyPolyLine(test.points, 0); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SimplifyPolyLine(%v): got %v, want %v", test.points, got, test.want)
+		}
+	}
+}
+
+func TestExteriorRings(t *testing.T) {
+	outer := []Point{{0, 0}, {0, 4}, {4, 4}, {4, 0}, {0, 0}}
+	hole := []Point{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}}
+	island := []Point{{10, 10}, {10, 11}, {11, 11}, {10, 10}}
+
+	got := GeometryUtils{}.ExteriorRings(NewPolygon([][]Point{outer, hole, island}))
+	want := NewPolygon([][]Point{outer, island})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRemoveSmallParts(t *testing.T) {
+	outer := []Point{{0, 0}, {0, 4}, {4, 4}, {4, 0}, {0, 0}}         // 16
+	hole := []Point{{1, 1}, {3, 1}, {3, 3}, {1, 3}, {1, 1}}          // 4
+	speck := []Point{{3.5, 3.5}, {3.6, 3.5}, {3.6, 3.6}, {3.5, 3.5}} // 0.005
+	island := []Point{{10, 10}, {10, 11}, {11, 11}, {10, 10}}        // 0.5
+	poly := NewPolygon([][]Point{outer, hole, speck, island})
+
+	got := GeometryUtils{}.RemoveSmallParts(poly, 1)
+	want := NewPolygon([][]Point{outer, hole})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if poly.NumParts != 4 {
+		t.Error("input polygon modified")
+	}
+	if got := (GeometryUtils{}).RemoveSmallParts(poly, 100); got.NumParts != 0 || len(got.Points) != 0 {
+		t.Errorf("got %+v, want no parts", got)
+	}
+}
+
+func TestAnalyzeShapefileVertexHistogram(t *testing.T) {
+	filename := t.TempDir() + "/lines.shp"
+	w, err := Create(filename, POLYLINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{2, 10, 11, 500, 1000, 1001, 3} {
+		points := make([]Point, n)
+		for i := range points {
+			points[i] = Point{float64(i), 0}
+		}
+		w.Write(NewPolyLine([][]Point{points}))
+	}
+	w.Close()
+
+	stats, err := StatisticsUtils{}.AnalyzeShapefile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := VertexHistogram{UpTo10: 3, UpTo100: 1, UpTo1000: 2, Over1000: 1, MaxVertices: 1001, MaxVerticesShape: 5}
+	if stats.VertexHistogram != want {
+		t.Errorf("got histogram %+v, want %+v", stats.VertexHistogram, want)
+	}
+}
+
+func TestAnalyzeShapefilePerimeter(t *testing.T) {
+	filename := t.TempDir() + "/parcels.shp"
+	w, err := Create(filename, POLYGON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a 4 x 4 square with a 1 x 1 hole and a 1 x 2 rectangle
+	w.Write(NewPolygon([][]Point{
+		{{0, 0}, {0, 4}, {4, 4}, {4, 0}, {0, 0}},
+		{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}},
+	}))
+	w.Write(NewPolygon([][]Point{{{10, 0}, {10, 2}, {11, 2}, {11, 0}, {10, 0}}}))
+	w.Close()
+
+	stats, err := StatisticsUtils{}.AnalyzeShapefile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalPerimeter != 26 || stats.AveragePerimeter != 13 {
+		t.Errorf("got perimeter total %v, average %v; want 26, 13", stats.TotalPerimeter, stats.AveragePerimeter)
+	}
+}
+
+func TestDiagnoseWinding(t *testing.T) {
+	outerCW := []Point{{0, 0}, {0, 4}, {4, 4}, {4, 0}, {0, 0}}
+	holeCCW := []Point{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}}
+	outerCCW := []Point{{10, 0}, {14, 0}, {14, 4}, {10, 4}, {10, 0}}
+	holeCW := []Point{{11, 1}, {11, 2}, {12, 2}, {12, 1}, {11, 1}}
+
+	write := func(polygons ...[][]Point) string {
+		filename := t.TempDir() + "/winding.shp"
+		w, err := Create(filename, POLYGON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range polygons {
+			w.Write(NewPolygon(p))
+		}
+		w.Close()
+		return filename
+	}
+
+	report, err := GeometryUtils{}.DiagnoseWinding(write(
+		[][]Point{outerCW, holeCCW},
+		[][]Point{outerCW},
+		[][]Point{outerCCW, holeCW},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WindingReport{Features: 3, Clockwise: 2, CounterClockwise: 1, InvertedFeatures: []int{2}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("got %+v, want %+v", report, want)
+	}
+	if report.Inverted() {
+		t.Error("mostly clockwise data reported as inverted")
+	}
+
+	report, err = GeometryUtils{}.DiagnoseWinding(write([][]Point{outerCCW, holeCW}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Inverted() {
+		t.Errorf("counter-clockwise data not reported as inverted: %+v", report)
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	points := []Point{{0, 0}, {2, 0}, {1, 1}, {2, 2}, {0, 2}, {1, 0}, {2, 2}}
+	got := GeometryUtils{}.ConvexHull(points)
+	want := []Point{{0, 0}, {2, 0}, {2, 2}, {0, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got hull %v, want %v", got, want)
+	}
+}
+
+func TestMinimumBoundingRectangle(t *testing.T) {
+	// a 4 x 1 rectangle rotated by 30 degrees, with a point inside
+	angle := math.Pi / 6
+	u := Point{math.Cos(angle), math.Sin(angle)}
+	v := Point{-u.Y, u.X}
+	at := func(a, b float64) Point { return Point{u.X*a + v.X*b, u.Y*a + v.Y*b} }
+	points := []Point{at(0, 1), at(4, 0), at(2, 0.5), at(0, 0), at(4, 1)}
+
+	corners, got := GeometryUtils{}.MinimumBoundingRectangle(points)
+	if math.Abs(got-angle) > 1e-9 {
+		t.Errorf("got angle %v, want %v", got, angle)
+	}
+	want := [4]Point{at(0, 0), at(4, 0), at(4, 1), at(0, 1)}
+	for i := range want {
+		if !corners[i].AlmostEquals(want[i], 1e-9) {
+			t.Errorf("got corners %v, want %v", corners, want)
+			break
+		}
+	}
+
+	corners, got = GeometryUtils{}.MinimumBoundingRectangle([]Point{{0, 0}, {0, 1}, {1, 1}, {1, 0}})
+	if got != 0 || corners != [4]Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+		t.Errorf("square: got %v, %v", corners, got)
+	}
+
+	// collinear points give a segment
+	corners, got = GeometryUtils{}.MinimumBoundingRectangle([]Point{{0, 2}, {1, 1}, {2, 0}})
+	if math.Abs(got-3*math.Pi/4) > 1e-9 || corners[0] != (Point{2, 0}) || corners[1] != (Point{0, 2}) {
+		t.Errorf("segment: got %v, %v", corners, got)
+	}
+}
+
+func TestFeatureHash(t *testing.T) {
+	geom := GeometryUtils{}
+	line := NewPolyLine([][]Point{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}})
+	attrs := map[string]string{"NAME": "road", "LANES": "2"}
+	hash := geom.FeatureHash(line, attrs)
+	if len(hash) != 64 {
+		t.Fatalf("got hash %q, want 64 hex digits", hash)
+	}
+
+	// the stored box and the map order don't matter, -0 equals 0
+	same := NewPolyLine([][]Point{{{math.Copysign(0, -1), 0}, {1, 1}}, {{2, 2}, {3, 3}}})
+	same.Box = Box{}
+	if got := geom.FeatureHash(same, map[string]string{"LANES": "2", "NAME": "road"}); got != hash {
+		t.Errorf("equal features hash differently: %s, %s", got, hash)
+	}
+
+	changed := map[string]Shape{
+		"moved vertex":   NewPolyLine([][]Point{{{0, 0}, {1, 1.5}}, {{2, 2}, {3, 3}}}),
+		"different part": NewPolyLine([][]Point{{{0, 0}, {1, 1}, {2, 2}}, {{3, 3}}}),
+		"polygon":        &Polygon{Box: line.Box, NumParts: 2, NumPoints: 4, Parts: line.Parts, Points: line.Points},
+		"with M":         &PolyLineM{NumParts: 2, NumPoints: 4, Parts: line.Parts, Points: line.Points, MArray: []float64{0, 0, 0, 0}},
+		"null":           nil,
+	}
+	for name, shape := range changed {
+		if geom.FeatureHash(shape, attrs) == hash {
+			t.Errorf("%s: same hash as the original", name)
+		}
+	}
+	for name, a := range map[string]map[string]string{
+		"changed value": {"NAME": "road", "LANES": "3"},
+		"missing field": {"NAME": "road"},
+		"shifted key":   {"NAMEr": "oad", "LANES": "2"},
+	} {
+		if geom.FeatureHash(line, a) == hash {
+			t.Errorf("%s: same hash as the original", name)
+		}
+	}
+
+	filename := t.TempDir() + "/roads.shp"
+	w, err := Create(filename, POLYLINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 20), NumberField("LANES", 4)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(line)
+	_ = w.WriteAttribute(0, 0, "road")
+	_ = w.WriteAttribute(0, 1, 2)
+	w.Close()
+	hashes, err := geom.FeatureHashesFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 1 || hashes[0] != hash {
+		t.Errorf("hashes from file %v, want [%s]", hashes, hash)
+	}
+}
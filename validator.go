@@ -10,8 +10,47 @@ type Validator interface {
 	Validate(shape Shape) error
 }
 
-// DefaultValidator 默认验证器
-type DefaultValidator struct{}
+// ValidationIssue 描述转换过程中被跳过的记录及原因
+type ValidationIssue struct {
+	// Row 记录索引（从 0 开始）
+	Row int
+	// Err 跳过该记录的原因
+	Err error
+}
+
+// Error 实现 error 接口
+func (i ValidationIssue) Error() string {
+	return fmt.Sprintf("record %d: %v", i.Row, i.Err)
+}
+
+// GeographicBounds 经纬度坐标的有效范围
+var GeographicBounds = Box{MinX: -180, MinY: -90, MaxX: 180, MaxY: 90}
+
+// DefaultValidator 默认验证器，零值即可使用
+type DefaultValidator struct {
+	bounds *Box
+}
+
+// ValidatorOption 定义验证器选项
+type ValidatorOption func(*DefaultValidator)
+
+// WithCoordinateBounds 设置坐标的有效范围（含边界），任一顶点超出范围的形状验证失败，
+// 错误的 Cause 为 *CoordinateBoundsError，包含第一个超出范围的顶点下标。
+// 经纬度数据可使用 GeographicBounds，投影数据使用对应坐标系的范围
+func WithCoordinateBounds(box Box) ValidatorOption {
+	return func(v *DefaultValidator) {
+		v.bounds = &box
+	}
+}
+
+// NewDefaultValidator 创建带选项的默认验证器
+func NewDefaultValidator(opts ...ValidatorOption) *DefaultValidator {
+	v := &DefaultValidator{}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
 
 // Validate 实现默认验证逻辑
 func (v *DefaultValidator) Validate(shape Shape) error {
@@ -24,7 +63,59 @@ func (v *DefaultValidator) Validate(shape Shape) error {
 		return err
 	}
 
-	return v.validateShapeType(shape)
+	if err := v.validateShapeType(shape); err != nil {
+		return err
+	}
+	return v.validateBounds(shape)
+}
+
+// validateBounds 检查所有顶点是否在设置的坐标范围内
+func (v *DefaultValidator) validateBounds(shape Shape) error {
+	if v.bounds == nil {
+		return nil
+	}
+	b := *v.bounds
+	for i, p := range shapeVertices(shape) {
+		if p.X < b.MinX || p.X > b.MaxX || p.Y < b.MinY || p.Y > b.MaxY {
+			return NewShapeError(ErrInvalidFormat, "coordinate out of bounds",
+				&CoordinateBoundsError{Vertex: i, Point: p, Bounds: b})
+		}
+	}
+	return nil
+}
+
+// shapeVertices 返回形状的所有顶点，空形状返回 nil
+func shapeVertices(shape Shape) []Point {
+	switch s := shape.(type) {
+	case *Point:
+		return []Point{*s}
+	case *PointZ:
+		return []Point{{X: s.X, Y: s.Y}}
+	case *PointM:
+		return []Point{{X: s.X, Y: s.Y}}
+	case *PolyLine:
+		return s.Points
+	case *Polygon:
+		return s.Points
+	case *MultiPoint:
+		return s.Points
+	case *PolyLineZ:
+		return s.Points
+	case *PolygonZ:
+		return s.Points
+	case *MultiPointZ:
+		return s.Points
+	case *PolyLineM:
+		return s.Points
+	case *PolygonM:
+		return s.Points
+	case *MultiPointM:
+		return s.Points
+	case *MultiPatch:
+		return s.Points
+	default:
+		return nil
+	}
 }
 
 // validateShapeType validates a specific shape type
@@ -136,7 +227,7 @@ func (v *DefaultValidator) validatePolyLine(pl *PolyLine) error {
 		}
 	}
 
-	return nil
+	return v.validatePartSizes(pl.Parts, pl.Points, minLinePoints, false)
 }
 
 // validateMultiPartGeometry 验证多部分几何的基本结构
@@ -166,10 +257,30 @@ func (v *DefaultValidator) validateArrayLengths(expectedLen int, actualLens []in
 	return nil
 }
 
+// validatePartSizes 验证每个部分的点数（线至少 2 个点，环至少 4 个点且闭合）
+func (v *DefaultValidator) validatePartSizes(parts []int32, points []Point, minPoints int, closed bool) error {
+	for i, start := range parts {
+		end := int32(len(points))
+		if i+1 < len(parts) {
+			end = parts[i+1]
+		}
+		if start < 0 || start > end || end > int32(len(points)) {
+			return NewShapeError(ErrInvalidFormat, fmt.Sprintf("invalid part offset at index %d", i), nil)
+		}
+		if err := checkPart(points[start:end], minPoints, closed); err != nil {
+			return NewShapeError(ErrInvalidFormat, fmt.Sprintf("invalid part %d", i), err)
+		}
+	}
+	return nil
+}
+
 // validatePolygon 验证多边形
 func (v *DefaultValidator) validatePolygon(pg *Polygon) error {
 	pl := (*PolyLine)(pg)
-	return v.validatePolyLine(pl)
+	if err := v.validatePolyLine(pl); err != nil {
+		return err
+	}
+	return v.validatePartSizes(pg.Parts, pg.Points, minRingPoints, true)
 }
 
 // validateMultiPoint 验证多点
@@ -191,9 +302,12 @@ func (v *DefaultValidator) validateMultiPoint(mp *MultiPoint) error {
 	return nil
 }
 
-// validatePointZ 验证Z点
+// validatePointZ 验证Z点，Z 为 NaN 表示高程未知（NoData），是合法的
 func (v *DefaultValidator) validatePointZ(p *PointZ) error {
-	return v.validatePointValues([]float64{p.X, p.Y, p.Z, p.M}, "pointZ")
+	if math.IsInf(p.Z, 0) {
+		return NewShapeError(ErrInvalidFormat, "pointZ contains infinite values", nil)
+	}
+	return v.validatePointValues([]float64{p.X, p.Y, p.M}, "pointZ")
 }
 
 // validatePolyLineZ 验证Z多线
@@ -201,13 +315,19 @@ func (v *DefaultValidator) validatePolyLineZ(plz *PolyLineZ) error {
 	if err := v.validateMultiPartGeometry(plz.NumParts, plz.NumPoints, len(plz.Parts), len(plz.Points)); err != nil {
 		return err
 	}
+	if err := v.validatePartSizes(plz.Parts, plz.Points, minLinePoints, false); err != nil {
+		return err
+	}
 	return v.validateArrayLengths(int(plz.NumPoints), []int{len(plz.ZArray), len(plz.MArray)}, []string{"Z", "M"})
 }
 
 // validatePolygonZ 验证Z多边形
 func (v *DefaultValidator) validatePolygonZ(pgz *PolygonZ) error {
 	plz := (*PolyLineZ)(pgz)
-	return v.validatePolyLineZ(plz)
+	if err := v.validatePolyLineZ(plz); err != nil {
+		return err
+	}
+	return v.validatePartSizes(pgz.Parts, pgz.Points, minRingPoints, true)
 }
 
 // validateMultiPointZ 验证Z多点
@@ -231,13 +351,19 @@ func (v *DefaultValidator) validatePolyLineM(plm *PolyLineM) error {
 	if err := v.validateMultiPartGeometry(plm.NumParts, plm.NumPoints, len(plm.Parts), len(plm.Points)); err != nil {
 		return err
 	}
+	if err := v.validatePartSizes(plm.Parts, plm.Points, minLinePoints, false); err != nil {
+		return err
+	}
 	return v.validateArrayLengths(int(plm.NumPoints), []int{len(plm.MArray)}, []string{"M"})
 }
 
 // validatePolygonM 验证M多边形
 func (v *DefaultValidator) validatePolygonM(pgm *PolygonM) error {
 	plm := (*PolyLineM)(pgm)
-	return v.validatePolyLineM(plm)
+	if err := v.validatePolyLineM(plm); err != nil {
+		return err
+	}
+	return v.validatePartSizes(pgm.Parts, pgm.Points, minRingPoints, true)
 }
 
 // validateMultiPointM 验证M多点
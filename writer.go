@@ -1,6 +1,7 @@
 package shp
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -10,6 +11,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // Writer is the type that is used to write a new shapefile.
@@ -20,11 +23,23 @@ type Writer struct {
 	GeometryType ShapeType
 	num          int32
 	bbox         Box
+	// hasExtent reports whether bbox holds the extent of a non-Null shape
+	hasExtent bool
 
 	dbf             writeSeekCloser
 	dbfFields       []Field
 	dbfHeaderLength int16
 	dbfRecordLength int16
+	dbfCodePage     byte
+	// dbfReserved holds the reserved header bytes 12-31 of an appended DBF,
+	// which are written back unchanged apart from the language driver ID
+	dbfReserved [dbfHeaderPaddingLen]byte
+	// dbfFieldIndex maps upper-case field names to their index in dbfFields,
+	// built on first use by WriteAttributeByName
+	dbfFieldIndex map[string]int
+
+	// Configuration
+	config *WriterConfig
 }
 
 type writeSeekCloser interface {
@@ -40,16 +55,31 @@ type writeSeekCloser interface {
 // and DBF).
 // If filename does not end on ".shp" already, it will be treated as the basename
 // for the file and the ".shp" extension will be appended to that name.
-func Create(filename string, t ShapeType) (*Writer, error) {
+func Create(filename string, t ShapeType, opts ...WriterOption) (*Writer, error) {
+	config := DefaultWriterConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
 	if strings.HasSuffix(strings.ToLower(filename), ".shp") {
 		filename = filename[0 : len(filename)-4]
 	}
-	shp, err := os.Create(filename + ".shp")
+	if !config.Overwrite {
+		// the DBF is only created by SetFields, so check it up front
+		if _, err := os.Stat(filename + ".dbf"); err == nil {
+			return nil, fmt.Errorf("%s.dbf: %w", filename, os.ErrExist)
+		}
+	}
+	shp, err := createFile(filename+".shp", config.Overwrite)
 	if err != nil {
 		return nil, err
 	}
-	shx, err := os.Create(filename + ".shx")
+	shx, err := createFile(filename+".shx", config.Overwrite)
 	if err != nil {
+		_ = shp.Close()
+		if !config.Overwrite {
+			_ = os.Remove(filename + ".shp")
+		}
 		return nil, err
 	}
 	_, _ = shp.Seek(100, io.SeekStart)
@@ -59,32 +89,91 @@ func Create(filename string, t ShapeType) (*Writer, error) {
 		shp:          shp,
 		shx:          shx,
 		GeometryType: t,
+		config:       config,
 	}
 	return w, nil
 }
 
+// CreateExclusive is like Create, but fails with an error wrapping
+// os.ErrExist instead of truncating if any of the SHP, SHX or DBF files
+// already exists. It is equivalent to Create with WithOverwrite(false).
+func CreateExclusive(filename string, t ShapeType, opts ...WriterOption) (*Writer, error) {
+	return Create(filename, t, append(opts, WithOverwrite(false))...)
+}
+
+// createFile creates the file path for writing. An existing file is truncated
+// if overwrite is true, otherwise an error wrapping os.ErrExist is returned.
+func createFile(path string, overwrite bool) (*os.File, error) {
+	flag := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flag = os.O_RDWR | os.O_CREATE | os.O_EXCL
+	}
+	return os.OpenFile(path, flag, 0o666)
+}
+
 // Append returns a Writer pointer that will append to the given shapefile and
 // the first error that was encountered during creation of that Writer. The
-// shapefile must have a valid index file.
-func Append(filename string) (*Writer, error) {
+// shapefile must have a valid index file. The reserved bytes of the DBF header
+// are kept as they are.
+func Append(filename string, opts ...WriterOption) (*Writer, error) {
 	// open shp/shx and init writer
 	w, shp, basename, err := openAndInitWriter(filename)
 	if err != nil {
 		return nil, err
 	}
+	w.config = DefaultWriterConfig()
+	for _, opt := range opts {
+		opt(w.config)
+	}
 	// load shx and position cursors
 	shx, err := openAndPositionIndex(shp, basename, &w.num)
 	if err != nil {
 		return nil, err
 	}
 	w.shx = shx
+	// a zero box is what files with only Null shapes have
+	w.hasExtent = w.num > 0 && w.bbox != (Box{})
 	// try to open dbf (optional)
 	if err := openAndInitDbf(basename, w); err != nil {
 		return nil, err
 	}
+	if dbf, ok := w.dbf.(*os.File); ok {
+		if err := trimDbfEndOfFile(dbf, w.dbfHeaderLength, w.dbfRecordLength); err != nil {
+			return nil, err
+		}
+	}
 	return w, nil
 }
 
+// trimDbfEndOfFile removes the end-of-file marker right after the last record
+// of dbf, if there is one, so that appended records follow the last record.
+// The marker is written again by Close.
+func trimDbfEndOfFile(dbf *os.File, headerLength, recordLength int16) error {
+	size, err := dbf.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 4)
+	if _, err := dbf.ReadAt(buf, dbfOffsetNumRecords); err != nil {
+		return fmt.Errorf("cannot read number of records from DBF: %v", err)
+	}
+	end := int64(headerLength) + int64(binary.LittleEndian.Uint32(buf))*int64(recordLength)
+	if size != end+1 {
+		return nil
+	}
+	if _, err := dbf.ReadAt(buf[:1], end); err != nil {
+		return fmt.Errorf("cannot read DBF end: %v", err)
+	}
+	if buf[0] != dbfEndOfFile {
+		return nil
+	}
+	if err := dbf.Truncate(size - 1); err != nil {
+		return fmt.Errorf("cannot remove DBF end-of-file marker: %v", err)
+	}
+	_, err = dbf.Seek(0, io.SeekEnd)
+	return err
+}
+
 // openAndInitWriter opens the shp file and reads geometry type and bbox
 func openAndInitWriter(filename string) (*Writer, *os.File, string, error) {
 	shp, err := os.OpenFile(filename, os.O_RDWR, 0o666)
@@ -145,9 +234,11 @@ func openAndPositionIndex(shp *os.File, basename string, num *int32) (*os.File,
 	return shx, nil
 }
 
-// openAndInitDbf opens the DBF (optional) and initializes writer fields
+// openAndInitDbf opens the DBF (optional) and initializes writer fields. The
+// DBF is opened for reading and writing so that the attributes of the
+// appended records can be written.
 func openAndInitDbf(basename string, w *Writer) error {
-	dbf, err := os.Open(basename + ".dbf")
+	dbf, err := os.OpenFile(basename+".dbf", os.O_RDWR, 0o666)
 	if os.IsNotExist(err) {
 		return nil // it's okay if the DBF does not exist
 	}
@@ -166,9 +257,13 @@ func openAndInitDbf(basename string, w *Writer) error {
 	if er.e != nil {
 		return fmt.Errorf("cannot read record length from DBF: %v", err)
 	}
-	if _, err = dbf.Seek(dbfHeaderPaddingLen, io.SeekCurrent); err != nil { // skip padding
-		return fmt.Errorf("cannot seek in DBF: %v", err)
+	padding := make([]byte, dbfHeaderPaddingLen)
+	readLE(er, padding)
+	if er.e != nil {
+		return fmt.Errorf("cannot read DBF header: %v", er.e)
 	}
+	w.dbfCodePage = padding[dbfOffsetCodePage-dbfOffsetPadding]
+	copy(w.dbfReserved[:], padding)
 	numFields := calcNumFields(w.dbfHeaderLength)
 	if w.dbfFields, err = readDbfFields(dbf, numFields); err != nil {
 		return fmt.Errorf("cannot read number of fields from DBF: %v", err)
@@ -183,11 +278,58 @@ func openAndInitDbf(basename string, w *Writer) error {
 // Write shape to the Shapefile. This also creates
 // a record in the SHX file and DBF file (if it is
 // initialized). Returns the index of the written object
-// which can be used in WriteAttribute.
+// which can be used in WriteAttribute. If the record does
+// not fit the 32-bit lengths of the format nothing is
+// written and -1 is returned; use WriteChecked to get the
+// error.
 func (w *Writer) Write(shape Shape) int32 {
-	// increate bbox
-	if w.num == 0 {
+	n, _ := w.WriteChecked(shape)
+	return n
+}
+
+// WriteChecked is like Write, but returns an error instead of writing the
+// record if the record or the resulting file would be longer than the
+// maximum length of 2^31-1 16-bit words that the record and file headers
+// can store, as can happen for a single geometry with many millions of
+// vertices.
+func (w *Writer) WriteChecked(shape Shape) (int32, error) {
+	row, err := w.writeShape(shape)
+	if err != nil {
+		return -1, err
+	}
+	// write empty record to dbf
+	if w.dbf != nil {
+		w.writeEmptyRecord()
+	}
+	return row, nil
+}
+
+// writeShape writes shape to the SHP and SHX files and returns its row
+// without adding a record to the DBF.
+func (w *Writer) writeShape(shape Shape) (int32, error) {
+	if w.config != nil && w.config.CoordinateDecimals >= 0 {
+		shape = roundShape(shape, w.config.CoordinateDecimals)
+	}
+	if w.config != nil && w.config.EnforcePolygonWinding {
+		shape = enforceWinding(shape)
+	}
+
+	offset, err := w.shp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1, NewShapeError(ErrIO, "failed to get current position", err)
+	}
+	if err := checkRecordLength(offset, shapeContentLength(shape)); err != nil {
+		return -1, err
+	}
+
+	// increate bbox; Null shapes have no extent and are written with the
+	// Null shape type, whatever the type of the file
+	recordType := w.GeometryType
+	if _, isNull := shape.(*Null); isNull {
+		recordType = NULL
+	} else if !w.hasExtent {
 		w.bbox = shape.BBox()
+		w.hasExtent = true
 	} else {
 		w.bbox.Extend(shape.BBox())
 	}
@@ -197,10 +339,10 @@ func (w *Writer) Write(shape Shape) int32 {
 	writeBE(ewShp, w.num)
 	_, _ = w.shp.Seek(4, io.SeekCurrent)
 	start, _ := w.shp.Seek(0, io.SeekCurrent)
-	writeLE(ewShp, w.GeometryType)
+	writeLE(ewShp, recordType)
 	shape.write(w.shp)
 	finish, _ := w.shp.Seek(0, io.SeekCurrent)
-	length := int32(math.Floor((float64(finish) - float64(start)) / 2.0))
+	length := int32((finish - start) / 2)
 	_, _ = w.shp.Seek(start-4, io.SeekStart)
 	writeBE(ewShp, length)
 	_, _ = w.shp.Seek(finish, io.SeekStart)
@@ -210,12 +352,7 @@ func (w *Writer) Write(shape Shape) int32 {
 	writeBE(ewShx, int32((start-8)/2))
 	writeBE(ewShx, length)
 
-	// write empty record to dbf
-	if w.dbf != nil {
-		w.writeEmptyRecord()
-	}
-
-	return w.num - 1
+	return w.num - 1, nil
 }
 
 // Close closes the Writer. This must be used at the end of
@@ -230,11 +367,17 @@ func (w *Writer) Close() {
 	if w.dbf == nil {
 		_ = w.SetFields([]Field{})
 	}
+	// end the records with the end-of-file marker, which Append removes again
+	if _, err := w.dbf.Seek(0, io.SeekEnd); err == nil {
+		_, _ = w.dbf.Write([]byte{dbfEndOfFile})
+	}
 	w.writeDbfHeader(w.dbf)
 	_ = w.dbf.Close()
 }
 
-// writeHeader wrires SHP/SHX headers to ws.
+// writeHeader wrires SHP/SHX headers to ws. A file without records or with
+// only Null shapes has no extent; its bounding box is written as all zeros,
+// like shapelib and GDAL do, which Reader.BBoxEmpty recognizes.
 func (w *Writer) writeHeader(ws io.WriteSeeker) {
 	filelength, _ := ws.Seek(0, io.SeekEnd)
 	if filelength == 0 {
@@ -258,14 +401,21 @@ func (w *Writer) writeHeader(ws io.WriteSeeker) {
 func (w *Writer) writeDbfHeader(ws io.WriteSeeker) {
 	_, _ = ws.Seek(0, 0)
 	ew := &errWriter{Writer: ws}
-	// version, year (YEAR-1990), month, day
-	writeLE(ew, []byte{3, 24, 5, 3})
+	// version, year (YEAR-1900), month, day of the last update
+	date := time.Now()
+	if w.config != nil && !w.config.LastUpdate.IsZero() {
+		date = w.config.LastUpdate
+	}
+	writeLE(ew, []byte{3, byte(date.Year() - 1900), byte(date.Month()), byte(date.Day())})
 	// number of records
 	writeLE(ew, w.num)
 	// header length, record length
 	writeLE(ew, []int16{w.dbfHeaderLength, w.dbfRecordLength})
-	// padding
-	writeLE(ew, make([]byte, 20))
+	// reserved bytes, kept from the original file when appending, with the
+	// language driver ID
+	padding := w.dbfReserved
+	padding[dbfOffsetCodePage-dbfOffsetPadding] = w.dbfCodePage
+	writeLE(ew, padding[:])
 
 	for _, field := range w.dbfFields {
 		writeLE(ew, field)
@@ -275,6 +425,23 @@ func (w *Writer) writeDbfHeader(ws io.WriteSeeker) {
 	_, _ = ws.Write([]byte("\r"))
 }
 
+// SetCodePage sets the language driver ID written to the DBF header, which
+// tells readers the code page of the text in the file. If the Writer has no
+// string encoding configured and cp is a known code page, a matching .cpg
+// file is written as well.
+func (w *Writer) SetCodePage(cp byte) error {
+	w.dbfCodePage = cp
+	if w.config != nil && w.config.StringEncoding != "" {
+		return nil
+	}
+	if name, ok := dbfCodePageNames[cp]; ok {
+		if err := os.WriteFile(w.filename+".cpg", []byte(name), 0o666); err != nil {
+			return fmt.Errorf("failed to write %s.cpg: %v", w.filename, err)
+		}
+	}
+	return nil
+}
+
 // SetFields sets field values in the DBF. This initializes the DBF file and
 // should be used prior to writing any attributes.
 func (w *Writer) SetFields(fields []Field) error {
@@ -283,12 +450,21 @@ func (w *Writer) SetFields(fields []Field) error {
 	}
 
 	var err error
-	w.dbf, err = os.Create(w.filename + ".dbf")
+	w.dbf, err = createFile(w.filename+".dbf", w.config == nil || w.config.Overwrite)
 	if err != nil {
-		return fmt.Errorf("failed to open %s.dbf: %v", w.filename, err)
+		return fmt.Errorf("failed to open %s.dbf: %w", w.filename, err)
 	}
 	w.dbfFields = fields
 
+	if w.config != nil && w.config.StringEncoding != "" {
+		if err := os.WriteFile(w.filename+".cpg", []byte(w.config.StringEncoding), 0o666); err != nil {
+			return fmt.Errorf("failed to write %s.cpg: %v", w.filename, err)
+		}
+		if w.dbfCodePage == 0 {
+			w.dbfCodePage = dbfCodePageFromEncoding(w.config.StringEncoding)
+		}
+	}
+
 	// calculate record length
 	w.dbfRecordLength = int16(1)
 	for _, field := range w.dbfFields {
@@ -312,12 +488,18 @@ func (w *Writer) SetFields(fields []Field) error {
 
 // Writes an empty record to the end of the DBF. This
 // works by seeking to the end of the file and writing
-// dbfRecordLength number of bytes. The first byte is a
-// space that indicates a new record.
+// dbfRecordLength number of spaces. The first byte is a
+// space that indicates a new record, the remaining ones
+// are the blank field values. As in files written by
+// dBase, values shorter than their field and fields that
+// are never written are padded with blanks rather than
+// NUL bytes, which other tools show as part of the value.
+// Binary fields are the exception and are set to zero
+// bytes, see blankDbfRecord.
 func (w *Writer) writeEmptyRecord() {
 	_, _ = w.dbf.Seek(0, io.SeekEnd)
 	buf := make([]byte, w.dbfRecordLength)
-	buf[0] = ' '
+	blankDbfRecord(buf, w.dbfFields)
 	ew := &errWriter{Writer: w.dbf}
 	writeLE(ew, buf)
 }
@@ -327,26 +509,57 @@ func (w *Writer) writeEmptyRecord() {
 // Shapefile. The field value corresponds to the field in the slice used in
 // SetFields.
 func (w *Writer) WriteAttribute(row int, field int, value interface{}) error {
+	if w.dbf == nil {
+		return errors.New("initialize DBF by using SetFields first")
+	}
+	buf, err := w.encodeAttribute(field, value)
+	if err != nil || buf == nil {
+		return err
+	}
+	return w.writeFieldBytes(row, field, buf)
+}
+
+// encodeAttribute returns the bytes stored in the DBF for value in field. A
+// nil slice without an error means that the attribute is left blank.
+func (w *Writer) encodeAttribute(field int, value interface{}) ([]byte, error) {
+	if isBinaryFieldType(w.dbfFields[field].Fieldtype) {
+		buf, err := encodeBinaryField(w.dbfFields[field], value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write field %v: %v", field, err)
+		}
+		return buf, nil
+	}
+
 	var buf []byte
 	switch v := value.(type) {
 	case int:
 		buf = []byte(strconv.Itoa(v))
 	case float64:
-		precision := w.dbfFields[field].Precision
-		buf = []byte(strconv.FormatFloat(v, 'f', int(precision), 64))
+		var err error
+		if buf, err = w.formatFloat(v, field); err != nil {
+			return nil, fmt.Errorf("unable to write field %v: %v", field, err)
+		}
 	case string:
-		buf = []byte(v)
+		var err error
+		if buf, err = w.encodeString(v); err != nil {
+			return nil, fmt.Errorf("unable to write field %v: %v", field, err)
+		}
 	default:
-		return fmt.Errorf("unsupported value type: %T", v)
+		return nil, fmt.Errorf("unsupported value type: %T", v)
 	}
 
-	if w.dbf == nil {
-		return errors.New("initialize DBF by using SetFields first")
-	}
 	if sz := int(w.dbfFields[field].Size); len(buf) > sz {
-		return fmt.Errorf("unable to write field %v: %q exceeds field length %v", field, buf, sz)
+		if s, ok := value.(string); ok && utf8.RuneCountInString(s) != len(buf) {
+			return nil, fmt.Errorf("unable to write field %v: %q needs %d bytes for %d characters, exceeds field length %v",
+				field, s, len(buf), utf8.RuneCountInString(s), sz)
+		}
+		return nil, fmt.Errorf("unable to write field %v: %q exceeds field length %v", field, buf, sz)
 	}
+	return buf, nil
+}
 
+// writeFieldBytes writes the encoded value buf of field into row of the DBF.
+func (w *Writer) writeFieldBytes(row int, field int, buf []byte) error {
 	seekTo := dbfFieldOffset(w.dbfHeaderLength, w.dbfRecordLength, row, w.dbfFields, field)
 	_, _ = w.dbf.Seek(seekTo, io.SeekStart)
 	ew := &errWriter{Writer: w.dbf}
@@ -354,7 +567,353 @@ func (w *Writer) WriteAttribute(row int, field int, value interface{}) error {
 	return ew.e
 }
 
-// BBox returns the bounding box of the Writer.
+// ShapeWithAttrs is a feature sent to WriteFrom: a shape and the values of its
+// attributes in the order of the fields passed to SetFields. Nil values and
+// missing trailing values leave the attribute blank.
+type ShapeWithAttrs struct {
+	Shape Shape
+	Attrs []interface{}
+}
+
+// WriteFrom writes every feature received from ch until ch is closed, so that
+// the shapes can be produced concurrently by another goroutine. It returns the
+// first error encountered without receiving any further features; the
+// producer has to be stopped by the caller in that case, e.g. by cancelling a
+// context, or it blocks forever on sending.
+func (w *Writer) WriteFrom(ch <-chan ShapeWithAttrs) error {
+	for feature := range ch {
+		if len(feature.Attrs) > len(w.dbfFields) {
+			return NewShapeError(ErrInvalidField,
+				fmt.Sprintf("%d attribute values for %d fields", len(feature.Attrs), len(w.dbfFields)), nil)
+		}
+		row, err := w.WriteChecked(feature.Shape)
+		if err != nil {
+			return err
+		}
+		for i, v := range feature.Attrs {
+			if v == nil {
+				continue
+			}
+			if err := w.WriteAttribute(int(row), i, v); err != nil {
+				return fmt.Errorf("record %d: %w", row, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteBatch writes features like WriteFrom, but builds the DBF records of
+// the whole batch in memory and appends them with a single write instead of
+// one write per record and attribute, which is considerably faster for large
+// numbers of small shapes. If a feature cannot be written, the records of the
+// features written before it, including its own shape if that was written,
+// are still stored and the error is returned.
+func (w *Writer) WriteBatch(features []ShapeWithAttrs) error {
+	for _, feature := range features {
+		if len(feature.Attrs) > len(w.dbfFields) {
+			return NewShapeError(ErrInvalidField,
+				fmt.Sprintf("%d attribute values for %d fields", len(feature.Attrs), len(w.dbfFields)), nil)
+		}
+	}
+
+	recordLength := int(w.dbfRecordLength)
+	var records []byte
+	starts := make([]int, len(w.dbfFields))
+	if w.dbf != nil {
+		records = make([]byte, len(features)*recordLength)
+		for i := range features {
+			blankDbfRecord(records[i*recordLength:(i+1)*recordLength], w.dbfFields)
+		}
+		for i := range starts {
+			starts[i] = dbfFieldStartByte(w.dbfFields, i)
+		}
+	}
+	written := 0
+	flush := func(err error) error {
+		if w.dbf == nil || written == 0 {
+			return err
+		}
+		_, _ = w.dbf.Seek(0, io.SeekEnd)
+		ew := &errWriter{Writer: w.dbf}
+		writeLE(ew, records[:written*recordLength])
+		if err == nil {
+			err = ew.e
+		}
+		return err
+	}
+
+	for i, feature := range features {
+		row, err := w.writeShape(feature.Shape)
+		if err != nil {
+			return flush(err)
+		}
+		written++
+		if w.dbf == nil {
+			continue
+		}
+		record := records[i*recordLength : (i+1)*recordLength]
+		for field, v := range feature.Attrs {
+			if v == nil {
+				continue
+			}
+			buf, err := w.encodeAttribute(field, v)
+			if err != nil {
+				return flush(fmt.Errorf("record %d: %w", row, err))
+			}
+			copy(record[starts[field]:], buf)
+		}
+	}
+	return flush(nil)
+}
+
+// WriteAttributeByName is WriteAttribute for the field named fieldName, so
+// that callers don't depend on the order of the fields passed to SetFields.
+// Field names are matched ignoring case, as DBF field names are.
+func (w *Writer) WriteAttributeByName(row int, fieldName string, value interface{}) error {
+	if w.dbf == nil {
+		return errors.New("initialize DBF by using SetFields first")
+	}
+	if w.dbfFieldIndex == nil {
+		w.dbfFieldIndex = make(map[string]int, len(w.dbfFields))
+		for i, f := range w.dbfFields {
+			name := strings.ToUpper(f.String())
+			if _, ok := w.dbfFieldIndex[name]; !ok {
+				w.dbfFieldIndex[name] = i
+			}
+		}
+	}
+	field, ok := w.dbfFieldIndex[strings.ToUpper(fieldName)]
+	if !ok {
+		return NewShapeError(ErrInvalidField, fmt.Sprintf("unknown field %q", fieldName), nil)
+	}
+	return w.WriteAttribute(row, field, value)
+}
+
+// BBox returns the bounding box of the Writer, which is all zeros while only
+// Null shapes have been written.
 func (w *Writer) BBox() Box {
 	return w.bbox
 }
+
+// formatFloat formats v for the given field. Non-finite values are rejected
+// unless the writer is configured to store them as empty (null) values.
+func (w *Writer) formatFloat(v float64, field int) ([]byte, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		if w.config != nil && w.config.NonFiniteAsNull {
+			return bytes.Repeat([]byte{' '}, int(w.dbfFields[field].Size)), nil
+		}
+		return nil, NewShapeError(ErrInvalidField, fmt.Sprintf("non-finite value %v cannot be stored in DBF", v), nil)
+	}
+	precision := int(w.dbfFields[field].Precision)
+	if w.config != nil && w.config.FloatPrecision >= 0 {
+		precision = w.config.FloatPrecision
+	}
+	return []byte(strconv.FormatFloat(v, 'f', precision, 64)), nil
+}
+
+// encodeString converts s into the bytes stored in the DBF according to the
+// configured string encoding. Without an encoding the bytes are written as-is.
+func (w *Writer) encodeString(s string) ([]byte, error) {
+	if w.config == nil || w.config.StringEncoding == "" {
+		return []byte(s), nil
+	}
+	sanitize := w.config.SanitizeStrings
+	switch strings.ToUpper(strings.ReplaceAll(w.config.StringEncoding, "_", "-")) {
+	case "UTF-8", "UTF8":
+		if utf8.ValidString(s) {
+			return []byte(s), nil
+		}
+		if sanitize {
+			return []byte(strings.ToValidUTF8(s, "?")), nil
+		}
+		return nil, NewShapeError(ErrInvalidField, fmt.Sprintf("invalid UTF-8 in %q", s), nil)
+	case "ISO-8859-1", "LATIN1", "LATIN-1", "88591":
+		buf := make([]byte, 0, len(s))
+		for i, r := range s {
+			switch {
+			case r == utf8.RuneError && !sanitize:
+				if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+					return nil, NewShapeError(ErrInvalidField, fmt.Sprintf("invalid UTF-8 in %q", s), nil)
+				}
+				fallthrough
+			case r > 0xff:
+				if !sanitize {
+					return nil, NewShapeError(ErrInvalidField,
+						fmt.Sprintf("character %q cannot be encoded as %s", r, w.config.StringEncoding), nil)
+				}
+				buf = append(buf, '?')
+			default:
+				buf = append(buf, byte(r))
+			}
+		}
+		return buf, nil
+	default:
+		return nil, NewShapeError(ErrUnsupportedType,
+			fmt.Sprintf("unsupported string encoding: %s", w.config.StringEncoding), nil)
+	}
+}
+
+// enforceWinding returns shape with the exterior rings of a polygon shape
+// clockwise and its holes counter-clockwise, telling them apart by
+// containment. The Z and M values are reversed with their points. A copy is
+// returned if any ring is reversed; other shapes are returned as-is.
+func enforceWinding(shape Shape) Shape {
+	var parts []int32
+	var points []Point
+	switch s := shape.(type) {
+	case *Polygon:
+		parts, points = s.Parts, s.Points
+	case *PolygonZ:
+		parts, points = s.Parts, s.Points
+	case *PolygonM:
+		parts, points = s.Parts, s.Points
+	default:
+		return shape
+	}
+
+	rings := splitParts(parts, points)
+	if len(rings) != len(parts) {
+		return shape // invalid parts, leave it to the validator
+	}
+	var reversed []int
+	for i, info := range classifyRings(rings) {
+		if info.Area != 0 && info.Clockwise != info.Outer {
+			reversed = append(reversed, i)
+		}
+	}
+	if len(reversed) == 0 {
+		return shape
+	}
+
+	reverse := func(values []float64) []float64 {
+		if len(values) != len(points) {
+			return values
+		}
+		values = append([]float64(nil), values...)
+		for _, i := range reversed {
+			start, end := partRange(parts, len(points), i)
+			reverseFloats(values[start:end])
+		}
+		return values
+	}
+	newPoints := append([]Point(nil), points...)
+	for _, i := range reversed {
+		start, end := partRange(parts, len(points), i)
+		ring := newPoints[start:end]
+		for a, b := 0, len(ring)-1; a < b; a, b = a+1, b-1 {
+			ring[a], ring[b] = ring[b], ring[a]
+		}
+	}
+
+	switch s := shape.(type) {
+	case *Polygon:
+		p := *s
+		p.Points = newPoints
+		return &p
+	case *PolygonZ:
+		p := *s
+		p.Points, p.ZArray, p.MArray = newPoints, reverse(s.ZArray), reverse(s.MArray)
+		return &p
+	default:
+		p := *shape.(*PolygonM)
+		p.Points, p.MArray = newPoints, reverse(p.MArray)
+		return &p
+	}
+}
+
+// partRange returns the range of the points of part i.
+func partRange(parts []int32, numPoints, i int) (int, int) {
+	end := numPoints
+	if i+1 < len(parts) {
+		end = int(parts[i+1])
+	}
+	return int(parts[i]), end
+}
+
+func reverseFloats(values []float64) {
+	for a, b := 0, len(values)-1; a < b; a, b = a+1, b-1 {
+		values[a], values[b] = values[b], values[a]
+	}
+}
+
+// roundShape returns a copy of shape with all coordinates, including Z and M
+// values, rounded to the given number of decimals and the bounding box
+// recomputed from the rounded points. Unknown shape types are returned as-is.
+func roundShape(shape Shape, decimals int) Shape {
+	scale := math.Pow(10, float64(decimals))
+	round := func(v float64) float64 {
+		return math.Round(v*scale) / scale
+	}
+	roundValues := func(values []float64) []float64 {
+		rounded := make([]float64, len(values))
+		for i, v := range values {
+			rounded[i] = round(v)
+		}
+		return rounded
+	}
+	roundPoints := func(points []Point) []Point {
+		rounded := make([]Point, len(points))
+		for i, p := range points {
+			rounded[i] = Point{round(p.X), round(p.Y)}
+		}
+		return rounded
+	}
+	roundRange := func(r [2]float64) [2]float64 {
+		return [2]float64{round(r[0]), round(r[1])}
+	}
+
+	switch s := shape.(type) {
+	case *Point:
+		return &Point{round(s.X), round(s.Y)}
+	case *PointZ:
+		return &PointZ{round(s.X), round(s.Y), round(s.Z), round(s.M)}
+	case *PointM:
+		return &PointM{round(s.X), round(s.Y), round(s.M)}
+	case *PolyLine:
+		r := *s
+		r.Points = roundPoints(s.Points)
+		shape = &r
+	case *Polygon:
+		r := *s
+		r.Points = roundPoints(s.Points)
+		shape = &r
+	case *MultiPoint:
+		r := *s
+		r.Points = roundPoints(s.Points)
+		shape = &r
+	case *PolyLineZ:
+		r := *s
+		r.Points, r.ZArray, r.MArray = roundPoints(s.Points), roundValues(s.ZArray), roundValues(s.MArray)
+		r.ZRange, r.MRange = roundRange(s.ZRange), roundRange(s.MRange)
+		shape = &r
+	case *PolygonZ:
+		r := *s
+		r.Points, r.ZArray, r.MArray = roundPoints(s.Points), roundValues(s.ZArray), roundValues(s.MArray)
+		r.ZRange, r.MRange = roundRange(s.ZRange), roundRange(s.MRange)
+		shape = &r
+	case *MultiPointZ:
+		r := *s
+		r.Points, r.ZArray, r.MArray = roundPoints(s.Points), roundValues(s.ZArray), roundValues(s.MArray)
+		r.ZRange, r.MRange = roundRange(s.ZRange), roundRange(s.MRange)
+		shape = &r
+	case *PolyLineM:
+		r := *s
+		r.Points, r.MArray, r.MRange = roundPoints(s.Points), roundValues(s.MArray), roundRange(s.MRange)
+		shape = &r
+	case *PolygonM:
+		r := *s
+		r.Points, r.MArray, r.MRange = roundPoints(s.Points), roundValues(s.MArray), roundRange(s.MRange)
+		shape = &r
+	case *MultiPointM:
+		r := *s
+		r.Points, r.MArray, r.MRange = roundPoints(s.Points), roundValues(s.MArray), roundRange(s.MRange)
+		shape = &r
+	case *MultiPatch:
+		r := *s
+		r.Points, r.ZArray, r.MArray = roundPoints(s.Points), roundValues(s.ZArray), roundValues(s.MArray)
+		r.ZRange, r.MRange = roundRange(s.ZRange), roundRange(s.MRange)
+		shape = &r
+	}
+	RecomputeBBox(shape)
+	return shape
+}
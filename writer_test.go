@@ -2,10 +2,18 @@ package shp
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 var filenamePrefix = "test_files/write_"
@@ -39,8 +47,14 @@ func TestAppend(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	for _, p := range points {
+	if err := shape.SetFields([]Field{NumberField("ID", 4)}); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range points {
 		shape.Write(&Point{p[0], p[1]})
+		if err := shape.WriteAttribute(i, 0, i); err != nil {
+			t.Fatal(err)
+		}
 	}
 	wantNum := shape.num
 	shape.Close()
@@ -62,8 +76,14 @@ func TestAppend(t *testing.T) {
 	}
 
 	for _, p := range newPoints {
-		shape.Write(&Point{p[0], p[1]})
+		n := shape.Write(&Point{p[0], p[1]})
+		// the DBF used to be opened read-only, which failed with "bad file
+		// descriptor" and dropped the appended attributes
+		if err := shape.WriteAttribute(int(n), 0, int(n)); err != nil {
+			t.Fatalf("cannot write attribute of appended record %d: %v", n, err)
+		}
 	}
+	shape.Close()
 
 	points = append(points, newPoints...)
 
@@ -72,6 +92,88 @@ func TestAppend(t *testing.T) {
 		t.Error("Number of shapes read was wrong")
 	}
 	testPoint(t, points, shapes)
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got := r.AttributeCount(); got != len(points) {
+		t.Fatalf("got %d attribute records, want %d", got, len(points))
+	}
+	for i := range points {
+		if got := r.ReadAttribute(i, 0); got != strconv.Itoa(i) {
+			t.Errorf("record %d: got ID %q, want %d", i, got, i)
+		}
+	}
+}
+
+func TestDbfHeaderReservedPreserved(t *testing.T) {
+	filename := t.TempDir() + "/points.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{NumberField("ID", 4)})
+	for i := 0; i < 3; i++ {
+		w.Write(&Point{float64(i), 0})
+		_ = w.WriteAttribute(i, 0, i)
+	}
+	_ = w.SetCodePage(0x57)
+	w.Close()
+
+	// bytes some tools keep in the reserved area, including work area ID 7
+	reserved := []byte{1, 2, 0, 0, 0x11, 0x12, 0x13, 0x14, 7, 0, 0, 0, 0, 0, 0, 0, 1, 0x57, 0, 9}
+	dbfPath := shapefileBase(filename) + ".dbf"
+	dbf, err := os.OpenFile(dbfPath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbf.WriteAt(reserved, dbfOffsetPadding); err != nil {
+		t.Fatal(err)
+	}
+	_ = dbf.Close()
+
+	check := func(step string) {
+		t.Helper()
+		r, err := Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		if got := r.DbfHeaderReserved(); !bytes.Equal(got, reserved) {
+			t.Errorf("%s: got reserved bytes %v, want %v", step, got, reserved)
+		}
+		if got := r.WorkAreaID(); got != 7 {
+			t.Errorf("%s: got work area ID %d, want 7", step, got)
+		}
+	}
+	check("modified")
+
+	w, err = Append(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := w.Write(&Point{3, 0})
+	_ = w.WriteAttribute(int(row), 0, 3)
+	w.Close()
+	check("append")
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, v := r.AttributeCount(), r.ReadAttribute(3, 0); n != 4 || v != "3" {
+		t.Errorf("after append: got %d rows, appended value %q", n, v)
+	}
+	r.Close()
+
+	if err := DeleteRecord(filename, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Pack(filename); err != nil {
+		t.Fatal(err)
+	}
+	check("pack")
 }
 
 func TestWritePoint(t *testing.T) {
@@ -183,6 +285,8 @@ func TestWriteAttribute(t *testing.T) {
 		{"float-0", 0, 1, 123.44, 7, "123.4400"},
 		{"float-0-overflow-1", 0, 1, 1234.0, 0, ""},
 		{"float-0-overflow-n", 0, 1, 123456789.0, 0, ""},
+		{"float-0-nan", 0, 1, math.NaN(), 0, ""},
+		{"float-0-inf", 0, 1, math.Inf(1), 0, ""},
 		{"int-0", 0, 2, 4242, 15, "4242"},
 		{"int-0-overflow-1", 0, 2, 42424, 0, ""},
 		{"int-0-overflow-n", 0, 2, 42424343, 0, ""},
@@ -207,3 +311,858 @@ func TestWriteAttribute(t *testing.T) {
 		})
 	}
 }
+
+func TestEmptyRecordsArePaddedWithSpaces(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Create(dir+"/padding.shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 6), NumberField("POP", 4)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{0, 0})
+	_ = w.WriteAttribute(0, 0, "ab")
+	w.Write(&Point{1, 1})
+	w.Close()
+
+	dbf, err := os.ReadFile(dir + "/padding.dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerLength := int(dbf[8]) | int(dbf[9])<<8
+	recordLength := int(dbf[10]) | int(dbf[11])<<8
+	records := dbf[headerLength : headerLength+2*recordLength]
+	// deletion flag, NAME and POP of both records
+	if want := " ab        " + "           "; string(records) != want {
+		t.Errorf("got records %q, want %q", records, want)
+	}
+}
+
+func TestWriteAttributeStringEncoding(t *testing.T) {
+	buf := new(bytes.Buffer)
+	s := &seekTracker{Writer: buf}
+	fields := []Field{StringField("NAME", 6)}
+
+	tests := []struct {
+		name     string
+		opts     []WriterOption
+		data     string
+		wantData string
+		wantErr  bool
+	}{
+		{"raw", nil, "abc", "abc", false},
+		{"utf8-valid", []WriterOption{WithStringEncoding("UTF-8")}, "äöü", "äöü", false},
+		{"utf8-multibyte-overflow", []WriterOption{WithStringEncoding("UTF-8")}, "äöüß", "", true},
+		{"utf8-invalid", []WriterOption{WithStringEncoding("UTF-8")}, "a\xffb", "", true},
+		{"utf8-sanitize", []WriterOption{WithStringEncoding("UTF-8"), WithSanitizeStrings(true)}, "a\xffb", "a?b", false},
+		{"latin1-transcode", []WriterOption{WithStringEncoding("ISO-8859-1")}, "äöüß", "\xe4\xf6\xfc\xdf", false},
+		{"latin1-unencodable", []WriterOption{WithStringEncoding("ISO-8859-1")}, "中", "", true},
+		{"latin1-sanitize", []WriterOption{WithStringEncoding("ISO-8859-1"), WithSanitizeStrings(true)}, "a中", "a?", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf.Reset()
+			config := DefaultWriterConfig()
+			for _, opt := range test.opts {
+				opt(config)
+			}
+			w := Writer{dbf: s, dbfFields: fields, dbfRecordLength: 7, config: config}
+
+			err := w.WriteAttribute(0, 0, test.data)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got err %v, want error: %v", err, test.wantErr)
+			}
+			if buf.String() != test.wantData {
+				t.Errorf("got data: %q, want: %q", buf.String(), test.wantData)
+			}
+		})
+	}
+}
+
+func TestWriteAttributeFloatOptions(t *testing.T) {
+	buf := new(bytes.Buffer)
+	s := &seekTracker{Writer: buf}
+	config := DefaultWriterConfig()
+	WithNonFiniteAsNull(true)(config)
+	WithFloatPrecision(1)(config)
+	w := Writer{dbf: s, dbfFields: []Field{FloatField("A_FLOAT", 8, 4)}, dbfRecordLength: 9, config: config}
+
+	if err := w.WriteAttribute(0, 0, math.NaN()); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "        " {
+		t.Errorf("got data %q for NaN, want blank field", got)
+	}
+
+	buf.Reset()
+	if err := w.WriteAttribute(0, 0, 12.345); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "12.3" {
+		t.Errorf("got data %q, want %q", got, "12.3")
+	}
+}
+
+func TestMultiTypeWriter(t *testing.T) {
+	base := t.TempDir() + "/mixed"
+	m := NewMultiTypeWriter(base + ".shp")
+	if err := m.SetFields([]Field{StringField("NAME", 10)}); err != nil {
+		t.Fatal(err)
+	}
+
+	shapes := []struct {
+		shape Shape
+		name  string
+	}{
+		{&Point{1, 1}, "a"},
+		{NewPolyLine([][]Point{{{0, 0}, {1, 1}}}), "b"},
+		{&Point{2, 2}, "c"},
+	}
+	for _, s := range shapes {
+		row, err := m.Write(s.shape)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.WriteAttribute(shapeTypeOf(s.shape), int(row), 0, s.name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.SetFields(nil); err == nil {
+		t.Error("SetFields after Write succeeded")
+	}
+	files := m.Close()
+
+	want := map[ShapeType]string{
+		POINT:    base + "_point.shp",
+		POLYLINE: base + "_polyline.shp",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("got files %v, want %v", files, want)
+	}
+
+	r, err := Open(files[POINT])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var names []string
+	for r.Next() {
+		n, _ := r.Shape()
+		names = append(names, r.ReadAttribute(n, 0))
+	}
+	if !reflect.DeepEqual(names, []string{"a", "c"}) {
+		t.Errorf("got point names %v", names)
+	}
+}
+
+func TestWriteCodePage(t *testing.T) {
+	dir := t.TempDir()
+	codePage := func(filename string) byte {
+		data, err := os.ReadFile(filename + ".dbf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data[dbfOffsetCodePage]
+	}
+
+	filename := dir + "/gbk"
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetCodePage(0x4d); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{StringField("NAME", 10)})
+	w.Write(&Point{1, 1})
+	w.Close()
+	if cp := codePage(filename); cp != 0x4d {
+		t.Errorf("got code page %#x, want 0x4d", cp)
+	}
+	if cpg, _ := os.ReadFile(filename + ".cpg"); string(cpg) != "936" {
+		t.Errorf("got .cpg %q, want %q", cpg, "936")
+	}
+
+	// appending keeps the code page
+	w, err = Append(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{2, 2})
+	w.Close()
+	if cp := codePage(filename); cp != 0x4d {
+		t.Errorf("got code page %#x after append, want 0x4d", cp)
+	}
+
+	// derived from the string encoding
+	filename = dir + "/latin1"
+	w, err = Create(filename+".shp", POINT, WithStringEncoding("ISO-8859-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{StringField("NAME", 10)})
+	w.Close()
+	if cp := codePage(filename); cp != 0x57 {
+		t.Errorf("got code page %#x, want 0x57", cp)
+	}
+}
+
+func TestCreateExclusive(t *testing.T) {
+	filename := t.TempDir() + "/exclusive"
+	w, err := CreateExclusive(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.SetFields([]Field{StringField("NAME", 5)})
+	w.Write(&Point{1, 2})
+	w.Close()
+	stat, err := os.Stat(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CreateExclusive(filename+".shp", POINT); !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected os.ErrExist, got %v", err)
+	}
+	if _, err := Create(filename+".shp", POINT, WithOverwrite(false)); !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected os.ErrExist with WithOverwrite(false), got %v", err)
+	}
+	if after, err := os.Stat(filename + ".shp"); err != nil || after.Size() != stat.Size() {
+		t.Errorf("existing shapefile was modified")
+	}
+
+	// a leftover DBF alone is enough to refuse, without creating the SHP
+	other := t.TempDir() + "/other"
+	if err := os.WriteFile(other+".dbf", nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateExclusive(other, POINT); !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected os.ErrExist for existing DBF, got %v", err)
+	}
+	if _, err := os.Stat(other + ".shp"); !os.IsNotExist(err) {
+		t.Errorf("SHP file was created despite existing DBF")
+	}
+}
+
+func TestWriteCoordinateRounding(t *testing.T) {
+	filename := t.TempDir() + "/rounded.shp"
+	w, err := Create(filename, POLYLINEZ, WithCoordinateRounding(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := &PolyLineZ{
+		NumParts:  1,
+		NumPoints: 2,
+		Parts:     []int32{0},
+		Points:    []Point{{1.23456, 2.34567}, {-3.45678, 4.005001}},
+		ZArray:    []float64{10.019, 20.011},
+		ZRange:    [2]float64{10.019, 20.011},
+		MArray:    []float64{0.5555, 0.4444},
+		MRange:    [2]float64{0.4444, 0.5555},
+	}
+	RecomputeBBox(line)
+	w.Write(line)
+	w.Close()
+
+	if line.Points[0].X != 1.23456 {
+		t.Errorf("Write modified the shape passed to it")
+	}
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if want := (Box{-3.46, 2.35, 1.23, 4.01}); r.BBox() != want {
+		t.Errorf("got file bbox %v, want %v", r.BBox(), want)
+	}
+	if !r.Next() {
+		t.Fatal("no shapes in file")
+	}
+	_, shape := r.Shape()
+	got := shape.(*PolyLineZ)
+	if want := []Point{{1.23, 2.35}, {-3.46, 4.01}}; !reflect.DeepEqual(got.Points, want) {
+		t.Errorf("got points %v, want %v", got.Points, want)
+	}
+	if want := []float64{10.02, 20.01}; !reflect.DeepEqual(got.ZArray, want) {
+		t.Errorf("got Z values %v, want %v", got.ZArray, want)
+	}
+	if want := []float64{0.56, 0.44}; !reflect.DeepEqual(got.MArray, want) {
+		t.Errorf("got M values %v, want %v", got.MArray, want)
+	}
+	if want := (Box{-3.46, 2.35, 1.23, 4.01}); got.Box != want {
+		t.Errorf("got shape bbox %v, want %v", got.Box, want)
+	}
+}
+
+func TestShapeContentLength(t *testing.T) {
+	points := []Point{{0, 0}, {1, 1}, {2, 0}}
+	values := []float64{1, 2, 3}
+	shapes := []Shape{
+		&Null{},
+		&Point{1, 2},
+		&PointZ{1, 2, 3, 4},
+		&PointM{1, 2, 3},
+		&PolyLine{NumParts: 1, NumPoints: 3, Parts: []int32{0}, Points: points},
+		&Polygon{NumParts: 1, NumPoints: 3, Parts: []int32{0}, Points: points},
+		&MultiPoint{NumPoints: 3, Points: points},
+		&PolyLineZ{NumParts: 1, NumPoints: 3, Parts: []int32{0}, Points: points, ZArray: values, MArray: values},
+		&PolygonZ{NumParts: 1, NumPoints: 3, Parts: []int32{0}, Points: points, ZArray: values},
+		&MultiPointZ{NumPoints: 3, Points: points, ZArray: values, MArray: values},
+		&PolyLineM{NumParts: 1, NumPoints: 3, Parts: []int32{0}, Points: points, MArray: values},
+		&PolygonM{NumParts: 1, NumPoints: 3, Parts: []int32{0}, Points: points, MArray: values},
+		&MultiPointM{NumPoints: 3, Points: points, MArray: values},
+		&MultiPatch{NumParts: 1, NumPoints: 3, Parts: []int32{0}, PartTypes: []int32{0}, Points: points, ZArray: values, MArray: values},
+	}
+	for _, shape := range shapes {
+		var buf bytes.Buffer
+		shape.write(&buf)
+		if got := shapeContentLength(shape); got != int64(buf.Len()) {
+			t.Errorf("%T: got content length %d, want %d", shape, got, buf.Len())
+		}
+	}
+}
+
+func TestCheckRecordLength(t *testing.T) {
+	maxBytes := int64(maxFileWords) * 2
+	tests := []struct {
+		offset, content int64
+		ok              bool
+	}{
+		{100, 16, true},
+		{100, maxBytes - 4 - 108, true},
+		{100, maxBytes - 4 - 100, false}, // file too long
+		{100, maxBytes, false},           // record too long
+		{maxBytes - 10, 16, false},
+	}
+	for _, tt := range tests {
+		err := checkRecordLength(tt.offset, tt.content)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkRecordLength(%d, %d) = %v, want ok %v", tt.offset, tt.content, err, tt.ok)
+		}
+	}
+}
+
+func TestGeoJSONToShapefileRecordTooLong(t *testing.T) {
+	// room for the header and two lines of two points only
+	defer func(words int64) { maxFileWords = words }(maxFileWords)
+	maxFileWords = 150
+
+	line := func(n int) string {
+		coords := make([]string, n)
+		for i := range coords {
+			coords[i] = fmt.Sprintf("[%d, 0]", i)
+		}
+		return `{"type": "Feature", "properties": {"ID": 1}, "geometry": {"type": "LineString", "coordinates": [` +
+			strings.Join(coords, ", ") + `]}}`
+	}
+	input := `{"type": "FeatureCollection", "features": [` + line(2) + `, ` +
+		`{"type": "Feature", "properties": {"ID": 2}, "geometry": null}, ` + line(10) + `, ` + line(2) + `]}`
+	var geoJSON GeoJSON
+	if err := json.Unmarshal([]byte(input), &geoJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	c := GeoJSONConverter{}
+	for name, convert := range map[string]func(string) error{
+		"GeoJSONToShapefile": func(filename string) error {
+			return c.GeoJSONToShapefile(&geoJSON, filename)
+		},
+		"GeoJSONStreamToShapefile": func(filename string) error {
+			return c.GeoJSONStreamToShapefile(strings.NewReader(input), filename)
+		},
+	} {
+		filename := dir + "/" + name + ".shp"
+		err := convert(filename)
+		if !errors.Is(err, NewShapeError(ErrInvalidFormat, "", nil)) || !strings.HasPrefix(err.Error(), "feature 2: ") {
+			t.Errorf("%s: got error %v, want a record length error for feature 2", name, err)
+		}
+		r, err := Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for r.Next() {
+			n++
+		}
+		r.Close()
+		if n != 2 {
+			t.Errorf("%s: got %d records, want the 2 before the long one", name, n)
+		}
+	}
+}
+
+func TestWriteAttributeByName(t *testing.T) {
+	filename := t.TempDir() + "/byname.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 10), NumberField("POP", 8)}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	if err := w.WriteAttributeByName(0, "pop", 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteAttributeByName(0, "NAME", "town"); err != nil {
+		t.Fatal(err)
+	}
+	err = w.WriteAttributeByName(0, "AREA", 1.5)
+	if !errors.Is(err, NewShapeError(ErrInvalidField, "", nil)) {
+		t.Errorf("unknown field: expected invalid field error, got %v", err)
+	}
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if name, pop := r.ReadAttribute(0, 0), r.ReadAttribute(0, 1); name != "town" || pop != "42" {
+		t.Errorf("got %q, %q; want town, 42", name, pop)
+	}
+}
+
+func TestWriteFrom(t *testing.T) {
+	filename := t.TempDir() + "/stream.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 10), NumberField("N", 4)}); err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan ShapeWithAttrs)
+	go func() {
+		defer close(ch)
+		for i := 0; i < 3; i++ {
+			ch <- ShapeWithAttrs{Shape: &Point{float64(i), 0}, Attrs: []interface{}{"p", i}}
+		}
+		ch <- ShapeWithAttrs{Shape: &Point{3, 0}, Attrs: []interface{}{nil, 3}}
+	}()
+	if err := w.WriteFrom(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := make(chan ShapeWithAttrs, 1)
+	bad <- ShapeWithAttrs{Shape: &Point{4, 0}, Attrs: []interface{}{"p", 4, "extra"}}
+	close(bad)
+	if err := w.WriteFrom(bad); !errors.Is(err, NewShapeError(ErrInvalidField, "", nil)) {
+		t.Errorf("expected invalid field error for extra values, got %v", err)
+	}
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var got []string
+	for r.Next() {
+		n, shape := r.Shape()
+		got = append(got, FormatUtils{}.ToWKT(shape)+" "+r.ReadAttribute(n, 0)+" "+r.ReadAttribute(n, 1))
+	}
+	if len(got) != 4 || got[2] != "POINT (2.000000 0.000000) p 2" || got[3] != "POINT (3.000000 0.000000)  3" {
+		t.Errorf("got records %q", got)
+	}
+}
+
+func TestWriteBatch(t *testing.T) {
+	filename := t.TempDir() + "/batch.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 4), NumberField("N", 4), IntegerField("I")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var batch []ShapeWithAttrs
+	for i := 0; i < 3; i++ {
+		batch = append(batch, ShapeWithAttrs{Shape: &Point{float64(i), 0}, Attrs: []interface{}{"p", i, -i}})
+	}
+	batch = append(batch, ShapeWithAttrs{Shape: &Point{3, 0}, Attrs: []interface{}{nil, 3}})
+	if err := w.WriteBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{4, 0})
+	if err := w.WriteAttribute(4, 0, "q"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the record with the value that doesn't fit is kept, the rest of the
+	// batch isn't written
+	bad := []ShapeWithAttrs{
+		{Shape: &Point{5, 0}, Attrs: []interface{}{"r"}},
+		{Shape: &Point{6, 0}, Attrs: []interface{}{"too long"}},
+		{Shape: &Point{7, 0}},
+	}
+	if err := w.WriteBatch(bad); err == nil {
+		t.Error("expected an error for a value exceeding the field length")
+	}
+	if err := w.WriteBatch([]ShapeWithAttrs{{Shape: &Point{8, 0}, Attrs: []interface{}{"s", 1, 2, 3}}}); !errors.Is(err, NewShapeError(ErrInvalidField, "", nil)) {
+		t.Errorf("expected invalid field error for extra values, got %v", err)
+	}
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var got []string
+	for r.Next() {
+		n, shape := r.Shape()
+		got = append(got, FormatUtils{}.ToWKT(shape)+" "+r.ReadAttribute(n, 0)+" "+r.ReadAttribute(n, 1)+" "+r.ReadAttribute(n, 2))
+	}
+	want := []string{
+		"POINT (0.000000 0.000000) p 0 0",
+		"POINT (1.000000 0.000000) p 1 -1",
+		"POINT (2.000000 0.000000) p 2 -2",
+		// unset binary integers are 0
+		"POINT (3.000000 0.000000)  3 0",
+		"POINT (4.000000 0.000000) q  0",
+		"POINT (5.000000 0.000000) r  0",
+		"POINT (6.000000 0.000000)   0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got records %q, want %q", got, want)
+	}
+	if err := r.CheckRecordCount(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEnforcePolygonWinding(t *testing.T) {
+	// GeoJSON winding: counter-clockwise exterior, clockwise hole
+	outer := []Point{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}}
+	hole := []Point{{1, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}}
+	pg := NewPolygon([][]Point{outer, hole})
+	z := []float64{0, 1, 2, 3, 0, 10, 11, 12, 13, 10}
+	shape := &PolygonZ{Box: pg.Box, NumParts: pg.NumParts, NumPoints: pg.NumPoints,
+		Parts: pg.Parts, Points: pg.Points, ZRange: [2]float64{0, 13}, ZArray: z}
+
+	write := func(opts ...WriterOption) *PolygonZ {
+		filename := t.TempDir() + "/winding.shp"
+		w, err := Create(filename, POLYGONZ, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(shape)
+		w.Close()
+		r, err := Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		if !r.Next() {
+			t.Fatal(r.Err())
+		}
+		_, got := r.Shape()
+		return got.(*PolygonZ)
+	}
+
+	got := write(WithEnforcePolygonWinding(true))
+	rings := splitParts(got.Parts, got.Points)
+	if !isClockwise(rings[0]) || isClockwise(rings[1]) {
+		t.Errorf("got rings %v", rings)
+	}
+	if want := []float64{0, 3, 2, 1, 0, 10, 13, 12, 11, 10}; !reflect.DeepEqual(got.ZArray, want) {
+		t.Errorf("got Z %v, want %v", got.ZArray, want)
+	}
+	if shape.Points[1] != (Point{4, 0}) || z[1] != 1 {
+		t.Error("the written shape was modified")
+	}
+
+	got = write()
+	if !reflect.DeepEqual(got.Points, shape.Points) {
+		t.Errorf("winding changed without the option: %v", got.Points)
+	}
+}
+
+func TestDbfLastUpdate(t *testing.T) {
+	lastUpdate := func(filename string, opts ...WriterOption) []byte {
+		w, err := Create(filename+".shp", POINT, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.SetFields([]Field{StringField("NAME", 10)}); err != nil {
+			t.Fatal(err)
+		}
+		w.Write(&Point{1, 1})
+		w.Close()
+		header, err := os.ReadFile(filename + ".dbf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return header[1:4]
+	}
+
+	dir := t.TempDir()
+	now := time.Now()
+	got := lastUpdate(dir + "/now")
+	if want := []byte{byte(now.Year() - 1900), byte(now.Month()), byte(now.Day())}; !bytes.Equal(got, want) {
+		t.Errorf("got last update % x, want % x", got, want)
+	}
+
+	got = lastUpdate(dir+"/fixed", WithLastUpdate(time.Date(2001, time.February, 3, 0, 0, 0, 0, time.UTC)))
+	if want := []byte{101, 2, 3}; !bytes.Equal(got, want) {
+		t.Errorf("got last update % x, want % x", got, want)
+	}
+}
+
+func TestWriteZNoData(t *testing.T) {
+	filename := t.TempDir() + "/nodata.shp"
+	w, err := Create(filename, POLYLINEZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := &PolyLineZ{
+		NumParts:  1,
+		NumPoints: 3,
+		Parts:     []int32{0},
+		Points:    []Point{{0, 0}, {1, 1}, {2, 2}},
+		ZArray:    []float64{0, math.NaN(), 5},
+		MArray:    []float64{0, 0, 0},
+	}
+	line.Box = line.BBox()
+	line.ZRange = valueRange(line.ZArray)
+	if _, err := w.WriteChecked(line); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if !math.IsNaN(line.ZArray[1]) {
+		t.Error("writing modified the Z values of the shape")
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// record header, box, counts, part, points and Z range precede the Z values
+	z := shpHeaderLen + 8 + 4 + 32 + 8 + 4 + 3*16 + 16 + 8
+	if got := math.Float64frombits(binary.LittleEndian.Uint64(data[z:])); got != zNoData {
+		t.Errorf("got %v in the file for the unknown Z value, want %v", got, zNoData)
+	}
+
+	shapes := getShapesFromFile(shapefileBase(filename), t)
+	got := shapes[0].(*PolyLineZ)
+	if got.ZArray[0] != 0 || !math.IsNaN(got.ZArray[1]) || got.ZArray[2] != 5 {
+		t.Errorf("got Z values %v, want [0 NaN 5]", got.ZArray)
+	}
+	if got.ZRange != [2]float64{0, 5} {
+		t.Errorf("got Z range %v, want [0 5]", got.ZRange)
+	}
+}
+
+func TestTimestampFields(t *testing.T) {
+	filename := t.TempDir() + "/observations.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{TimestampField("OBSERVED"), DateTimeField("LOGGED")}); err != nil {
+		t.Fatal(err)
+	}
+	observed := time.Date(2024, 3, 15, 13, 45, 30, 123e6, time.UTC)
+	// before 1970 and in another zone, stored in UTC
+	logged := time.Date(1969, 12, 31, 23, 0, 0, 0, time.FixedZone("", -2*3600))
+	values := [][2]interface{}{
+		{observed, logged},
+		{"2024-03-15T13:45:30.123Z", ""}, // the second left unset
+	}
+	for i, v := range values {
+		w.Write(&Point{float64(i), 0})
+		for j, value := range v {
+			if err := w.WriteAttribute(i, j, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.WriteAttribute(0, 0, "yesterday"); err == nil {
+		t.Error("expected error for an invalid timestamp")
+	}
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got := r.ReadAttribute(0, 0); got != "2024-03-15T13:45:30.123Z" {
+		t.Errorf("got %q", got)
+	}
+	if got := r.ReadAttribute(0, 1); got != "1970-01-01T01:00:00.000Z" {
+		t.Errorf("got %q", got)
+	}
+	for row, want := range [][2]interface{}{{observed, logged.UTC()}, {observed, nil}} {
+		for field := range want {
+			got := r.ReadAttributeTyped(row, field)
+			if gotTime, ok := got.(time.Time); ok && want[field] != nil {
+				if !gotTime.Equal(want[field].(time.Time)) {
+					t.Errorf("row %d field %d: got %v, want %v", row, field, got, want[field])
+				}
+			} else if got != want[field] {
+				t.Errorf("row %d field %d: got %v (%T), want %v", row, field, got, got, want[field])
+			}
+		}
+	}
+
+	// Julian day number and milliseconds since midnight
+	data, err := os.ReadFile(filename[:len(filename)-4] + ".dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerLength := int(binary.LittleEndian.Uint16(data[8:]))
+	raw := data[headerLength+1:]
+	if day, ms := binary.LittleEndian.Uint32(raw), binary.LittleEndian.Uint32(raw[4:]); day != 2460385 || ms != 49530123 {
+		t.Errorf("stored day %d ms %d, want 2460385 49530123", day, ms)
+	}
+}
+
+func TestBinaryNumericFields(t *testing.T) {
+	filename := t.TempDir() + "/binary.shp"
+	w, err := Create(filename, POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{IntegerField("COUNT"), DoubleField("RATIO")}); err != nil {
+		t.Fatal(err)
+	}
+	values := []struct {
+		count, ratio interface{}
+	}{
+		{-538976288, 0.25},
+		{2147483647, "-1.5e300"},
+		{"", ""}, // left unset
+		// all bytes are spaces
+		{538976288, math.Float64frombits(0x2020202020202020)},
+	}
+	for i, v := range values {
+		w.Write(&Point{float64(i), 0})
+		if err := w.WriteAttribute(i, 0, v.count); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteAttribute(i, 1, v.ratio); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteAttribute(0, 0, 1.5); err == nil {
+		t.Error("expected error for a fraction in an integer field")
+	}
+	if err := w.WriteAttribute(0, 0, 1<<40); err == nil {
+		t.Error("expected error for an integer that needs more than 32 bits")
+	}
+	w.Close()
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	want := [][2]interface{}{
+		{int64(-538976288), 0.25},
+		{int64(2147483647), -1.5e300},
+		{int64(0), 0.0},
+		{int64(538976288), math.Float64frombits(0x2020202020202020)},
+	}
+	for i, v := range want {
+		if got := [2]interface{}{r.ReadAttributeTyped(i, 0), r.ReadAttributeTyped(i, 1)}; got != v {
+			t.Errorf("row %d: got %v, want %v", i, got, v)
+		}
+	}
+	if got := r.ReadAttribute(1, 1); got != "-1.5e+300" {
+		t.Errorf("got %q, want -1.5e+300", got)
+	}
+
+	shp, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbf, err := os.Open(shapefileBase(filename) + ".dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := SequentialReaderFromExt(shp, dbf)
+	defer sr.Close()
+	if !sr.Next() {
+		t.Fatal(sr.Err())
+	}
+	if got := RecordTyped(sr); got["COUNT"] != want[0][0] || got["RATIO"] != want[0][1] {
+		t.Errorf("sequential reader: got %v", got)
+	}
+}
+
+func TestNullShapesHaveNoExtent(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, shapes ...Shape) *Reader {
+		filename := dir + "/" + name + ".shp"
+		w, err := Create(filename, POLYGON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.SetFields([]Field{NumberField("ID", 4)}); err != nil {
+			t.Fatal(err)
+		}
+		for i, shape := range shapes {
+			w.Write(shape)
+			_ = w.WriteAttribute(i, 0, i+1)
+		}
+		w.Close()
+		r, err := Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { r.Close() })
+		return r
+	}
+	square := NewPolygon([][]Point{{{10, 10}, {10, 20}, {20, 20}, {20, 10}, {10, 10}}})
+
+	// attribute-only file
+	r := write("nulls", &Null{}, &Null{})
+	if r.BBox() != (Box{}) || !r.BBoxEmpty() {
+		t.Errorf("all-null file: box %v, empty %v", r.BBox(), r.BBoxEmpty())
+	}
+	n := 0
+	for r.Next() {
+		row, shape := r.Shape()
+		if _, ok := shape.(*Null); !ok {
+			t.Errorf("record %d read back as %T", row, shape)
+		}
+		if got := r.ReadAttribute(row, 0); got != fmt.Sprint(row+1) {
+			t.Errorf("record %d: ID %q", row, got)
+		}
+		n++
+	}
+	if n != 2 || r.Err() != nil {
+		t.Errorf("read %d records, err %v", n, r.Err())
+	}
+
+	// a leading Null shape doesn't pull the box to the origin
+	r = write("mixed", &Null{}, square)
+	if want := (Box{10, 10, 20, 20}); r.BBox() != want || r.BBoxEmpty() {
+		t.Errorf("mixed file: box %v, want %v, empty %v", r.BBox(), want, r.BBoxEmpty())
+	}
+	var types []ShapeType
+	for r.Next() {
+		_, shape := r.Shape()
+		types = append(types, shapeTypeOf(shape))
+	}
+	if !reflect.DeepEqual(types, []ShapeType{NULL, POLYGON}) {
+		t.Errorf("read back %v", types)
+	}
+
+	if r := write("none"); !r.BBoxEmpty() {
+		t.Error("file without records not reported empty")
+	}
+	// a real shape at the origin has a zero box too
+	origin := NewPolygon([][]Point{{{0, 0}, {0, 0}, {0, 0}, {0, 0}}})
+	if r := write("origin", origin); r.BBoxEmpty() {
+		t.Error("shape at the origin reported as empty")
+	}
+}
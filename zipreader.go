@@ -144,6 +144,18 @@ func (zr *ZipReader) Fields() []Field {
 	return zr.sr.Fields()
 }
 
+// ZRange returns the range of Z values, Zmin and Zmax, stored in the SHP
+// header.
+func (zr *ZipReader) ZRange() [2]float64 {
+	return zr.sr.ZRange()
+}
+
+// MRange returns the range of measures, Mmin and Mmax, stored in the SHP
+// header.
+func (zr *ZipReader) MRange() [2]float64 {
+	return zr.sr.MRange()
+}
+
 // Err returns the last non-EOF error that was encountered by this ZipReader.
 func (zr *ZipReader) Err() error {
 	return zr.sr.Err()